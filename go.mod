@@ -3,26 +3,30 @@ module github.com/vmikk/seqhasher
 go 1.23.4
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/cespare/xxhash/v2 v2.3.0
 	github.com/dsnet/compress v0.0.1
 	github.com/fatih/color v1.18.0
 	github.com/go-faster/city v1.0.1
 	github.com/klauspost/compress v1.17.11
 	github.com/klauspost/pgzip v1.2.6
+	github.com/mattn/go-isatty v0.0.20
+	github.com/pierrec/lz4/v4 v4.1.21
 	github.com/shenwei356/bio v0.13.6
 	github.com/shenwei356/xopen v0.3.2
 	github.com/spaolacci/murmur3 v1.1.0
 	github.com/ulikunitz/xz v0.5.12
 	github.com/will-rowe/nthash v0.4.0
 	github.com/zeebo/blake3 v0.2.4
+	go.etcd.io/bbolt v1.3.11
 	golang.org/x/crypto v0.31.0
+	google.golang.org/protobuf v1.36.11
 )
 
 require (
 	github.com/elliotwutingfeng/asciiset v0.0.0-20240214025120-24af97c84155 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.9 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/rogpeppe/go-internal v1.12.0 // indirect
 	github.com/shenwei356/util v0.5.3 // indirect
 	golang.org/x/sys v0.28.0 // indirect
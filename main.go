@@ -0,0 +1,21 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+// SeqHasher computes hashes of DNA sequences; the processing is done by package seqhash
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/vmikk/seqhasher/seqhash"
+)
+
+func main() {
+	if err := seqhash.Run(os.Stdout); err != nil {
+		log.Print(err)
+		os.Exit(seqhash.ExitStatus(err))
+	}
+}
@@ -0,0 +1,11 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+// Package recordpb holds the messages of the protobuf output (--out-format protobuf),
+// generated from record.proto. Other programs can read the output with
+// protodelim.UnmarshalFrom into a Record.
+package recordpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative record.proto
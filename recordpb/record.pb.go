@@ -0,0 +1,225 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+// Records of the protobuf output (--out-format protobuf).
+// The output is a stream of length-delimited messages: each Record is preceded
+// by its size in bytes as a varint (as written by protodelim.MarshalTo in Go,
+// or writeDelimitedTo in Java and C++).
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: record.proto
+
+package recordpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Record struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`             // Sequence ID (after --synthesize-ids)
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"` // File label (omitted with --nofilename)
+	Hashes        []*Hash                `protobuf:"bytes,3,rep,name=hashes,proto3" json:"hashes,omitempty"`     // In the order of --hash
+	Sequence      []byte                 `protobuf:"bytes,4,opt,name=sequence,proto3" json:"sequence,omitempty"` // As written to FASTA output (omitted with --headersonly)
+	Quality       []byte                 `protobuf:"bytes,5,opt,name=quality,proto3" json:"quality,omitempty"`   // Quality scores of FASTQ records
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Record) Reset() {
+	*x = Record{}
+	mi := &file_record_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Record) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Record) ProtoMessage() {}
+
+func (x *Record) ProtoReflect() protoreflect.Message {
+	mi := &file_record_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Record.ProtoReflect.Descriptor instead.
+func (*Record) Descriptor() ([]byte, []int) {
+	return file_record_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Record) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Record) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Record) GetHashes() []*Hash {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+func (x *Record) GetSequence() []byte {
+	if x != nil {
+		return x.Sequence
+	}
+	return nil
+}
+
+func (x *Record) GetQuality() []byte {
+	if x != nil {
+		return x.Quality
+	}
+	return nil
+}
+
+type Hash struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // Hash type (e.g., sha1)
+	Digest        string                 `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Hash) Reset() {
+	*x = Hash{}
+	mi := &file_record_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hash) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hash) ProtoMessage() {}
+
+func (x *Hash) ProtoReflect() protoreflect.Message {
+	mi := &file_record_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hash.ProtoReflect.Descriptor instead.
+func (*Hash) Descriptor() ([]byte, []int) {
+	return file_record_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Hash) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Hash) GetDigest() string {
+	if x != nil {
+		return x.Digest
+	}
+	return ""
+}
+
+var File_record_proto protoreflect.FileDescriptor
+
+const file_record_proto_rawDesc = "" +
+	"\n" +
+	"\frecord.proto\x12\tseqhasher\"\x93\x01\n" +
+	"\x06Record\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12'\n" +
+	"\x06hashes\x18\x03 \x03(\v2\x0f.seqhasher.HashR\x06hashes\x12\x1a\n" +
+	"\bsequence\x18\x04 \x01(\fR\bsequence\x12\x18\n" +
+	"\aquality\x18\x05 \x01(\fR\aquality\"2\n" +
+	"\x04Hash\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
+	"\x06digest\x18\x02 \x01(\tR\x06digestB%Z#github.com/vmikk/seqhasher/recordpbb\x06proto3"
+
+var (
+	file_record_proto_rawDescOnce sync.Once
+	file_record_proto_rawDescData []byte
+)
+
+func file_record_proto_rawDescGZIP() []byte {
+	file_record_proto_rawDescOnce.Do(func() {
+		file_record_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_record_proto_rawDesc), len(file_record_proto_rawDesc)))
+	})
+	return file_record_proto_rawDescData
+}
+
+var file_record_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_record_proto_goTypes = []any{
+	(*Record)(nil), // 0: seqhasher.Record
+	(*Hash)(nil),   // 1: seqhasher.Hash
+}
+var file_record_proto_depIdxs = []int32{
+	1, // 0: seqhasher.Record.hashes:type_name -> seqhasher.Hash
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_record_proto_init() }
+func file_record_proto_init() {
+	if File_record_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_record_proto_rawDesc), len(file_record_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_record_proto_goTypes,
+		DependencyIndexes: file_record_proto_depIdxs,
+		MessageInfos:      file_record_proto_msgTypes,
+	}.Build()
+	File_record_proto = out.File
+	file_record_proto_goTypes = nil
+	file_record_proto_depIdxs = nil
+}
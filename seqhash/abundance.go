@@ -0,0 +1,65 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Abundance annotation of USEARCH/VSEARCH (e.g., ">seq1;size=12")
+const defaultSizePattern = `;size=(\d+)`
+
+// abundanceParser extracts abundances from headers (--sizein, --size-regexp)
+type abundanceParser struct {
+	re *regexp.Regexp // nil for the size annotation (defaultSizePattern)
+}
+
+// newAbundanceParser compiles the pattern (the size annotation is parsed if empty);
+// its first capture group must match the abundance
+func newAbundanceParser(pattern string) (*abundanceParser, error) {
+	if pattern == "" {
+		return &abundanceParser{}, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid size pattern: %v", err)
+	}
+	if re.NumSubexp() < 1 {
+		return nil, fmt.Errorf("Invalid size pattern: %s. It must contain a capture group for the abundance", pattern)
+	}
+	return &abundanceParser{re: re}, nil
+}
+
+// abundance returns the abundance annotated in the header, or 1 if there is none
+func (p *abundanceParser) abundance(name []byte) (int64, error) {
+	if p.re == nil {
+		if _, notes := splitAnnotations(name); notes.hasSize {
+			return notes.size, nil
+		}
+		return 1, nil
+	}
+	match := p.re.FindSubmatch(name)
+	if match == nil || match[1] == nil {
+		return 1, nil
+	}
+	size, err := strconv.ParseInt(string(match[1]), 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("Invalid abundance %q in header %q", match[1], name)
+	}
+	return size, nil
+}
+
+// strip removes the abundance matched by the pattern from the header, as it is replaced
+// by the size annotation with --sizeout (which replaces the size annotation itself)
+func (p *abundanceParser) strip(name []byte) []byte {
+	if p.re == nil {
+		return name
+	}
+	return bytes.TrimRight(p.re.ReplaceAll(name, nil), ";")
+}
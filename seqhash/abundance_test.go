@@ -0,0 +1,59 @@
+package seqhash
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAbundance(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		header   string
+		expected int64
+		wantErr  bool
+	}{
+		{"Default pattern", "", "seq1;size=12;", 12, false},
+		{"Count annotation", `;count=(\d+)`, "seq1;count=5", 5, false},
+		{"Underscore suffix", `_(\d+)$`, "otu1_42", 42, false},
+		{"No annotation", `;count=(\d+)`, "seq1;size=12", 1, false},
+		{"Optional group not matched", `;count=(\d+)?`, "seq1;count=", 1, false},
+		{"Not a number", `;count=(\w+)`, "seq1;count=many", 0, true},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			p, err := newAbundanceParser(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.abundance([]byte(tt.header))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("abundance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("abundance() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSizeRegexpClusters(t *testing.T) {
+	clusters := filepath.Join(t.TempDir(), "clusters.tsv")
+	input := ">a;count=5\nACTG\n>b;count=2\nactg\n>c\nTTTT\n"
+
+	cfg := Config{HashTypes: []string{"sha1"}, ClustersFile: clusters, SizeIn: true, SizeRegexp: `;count=(\d+)`}
+	if err := processSequences(strings.NewReader(input), &strings.Builder{}, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+
+	expected := [][]string{
+		{"sha1", "size", "representative"},
+		{"65c89f59d38cdbf90dfaf0b0a6884829df8396b0", "7", "a;count=5"},
+		{getHashFunc("sha1")([]byte("TTTT")), "1", "c"},
+	}
+	if got := readTSV(t, clusters); !equalRows(got, expected) {
+		t.Errorf("Unexpected clusters:\n%v\nWant:\n%v", got, expected)
+	}
+}
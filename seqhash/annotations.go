@@ -0,0 +1,123 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bytes"
+	"slices"
+	"strconv"
+)
+
+// annotations are the trailing ";key=value" pairs of a header, as written by USEARCH, VSEARCH,
+// and other tools (e.g., "seq1;size=12;sample=A"). They may end the ID or the comment,
+// and they are written back in a canonical form after the fields of the rewritten header
+// (before the comment): other pairs verbatim and in their order, followed by sample and size
+type annotations struct {
+	size      int64 // Abundance (";size=N")
+	hasSize   bool
+	sample    string // Sample name (";sample=A")
+	hasSample bool
+	other     [][]byte // Other pairs ("key=value")
+}
+
+// splitAnnotations removes the trailing annotations of the ID and of the comment from a header
+func splitAnnotations(name []byte) ([]byte, annotations) {
+	var a annotations
+	id, tail := splitComment(name)
+	id = a.trim(id)
+	if len(tail) > 0 {
+		tail = a.trim(tail)
+		if len(bytes.TrimSpace(tail)) == 0 {
+			tail = nil
+		}
+	}
+	if len(tail) == 0 {
+		return id, a
+	}
+	return append(append(make([]byte, 0, len(id)+len(tail)), id...), tail...), a
+}
+
+// trim parses the run of annotations at the end of b, returning the rest of b.
+// The text before the first ';' is never an annotation, and empty pairs at the end
+// (e.g., "seq1;size=12;" written by USEARCH) are dropped.
+func (a *annotations) trim(b []byte) []byte {
+	end := len(bytes.TrimRight(b, ";"))
+	start := end
+	for {
+		i := bytes.LastIndexByte(b[:start], ';')
+		if i < 0 || !isAnnotation(b[i+1:start]) {
+			break
+		}
+		start = i
+	}
+	if start == end {
+		return b
+	}
+	for _, pair := range bytes.Split(b[start+1:end], []byte(";")) {
+		a.add(pair)
+	}
+	return b[:start]
+}
+
+// isAnnotation reports whether a pair looks like "key=value" (the value may be empty)
+func isAnnotation(pair []byte) bool {
+	key, value, found := bytes.Cut(pair, []byte("="))
+	if !found || len(key) == 0 || bytes.ContainsAny(value, " \t") {
+		return false
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// add records a pair; recognized keys with a valid value are parsed (the last one wins)
+func (a *annotations) add(pair []byte) {
+	key, value, _ := bytes.Cut(pair, []byte("="))
+	switch string(key) {
+	case "size":
+		if size, err := strconv.ParseInt(string(value), 10, 64); err == nil && size >= 0 {
+			a.setSize(size)
+			return
+		}
+	case "sample":
+		if len(value) > 0 {
+			a.sample, a.hasSample = string(value), true
+			return
+		}
+	}
+	a.other = append(a.other, pair)
+}
+
+// replace sets a pair, removing the other pairs with the same key
+func (a *annotations) replace(key, value string) {
+	a.other = slices.DeleteFunc(a.other, func(pair []byte) bool { return bytes.HasPrefix(pair, []byte(key+"=")) })
+	a.other = append(a.other, []byte(key+"="+value))
+}
+
+// setSize replaces the abundance (e.g., with the total of --sizeout)
+func (a *annotations) setSize(size int64) {
+	a.size, a.hasSize = size, true
+}
+
+// appendTo writes the annotations in the canonical form (";key=value" each),
+// skipping the keys that are already written by the caller
+func (a *annotations) appendTo(dst []byte, skip ...string) []byte {
+	for _, pair := range a.other {
+		if key, _, _ := bytes.Cut(pair, []byte("=")); !slices.Contains(skip, string(key)) {
+			dst = append(append(dst, ';'), pair...)
+		}
+	}
+	if a.hasSample && !slices.Contains(skip, "sample") {
+		dst = append(append(dst, ";sample="...), a.sample...)
+	}
+	if a.hasSize && !slices.Contains(skip, "size") {
+		dst = strconv.AppendInt(append(dst, ";size="...), a.size, 10)
+	}
+	return dst
+}
@@ -0,0 +1,131 @@
+package seqhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitAnnotations(t *testing.T) {
+	tests := []struct {
+		header   string
+		bare     string
+		expected string // Canonical form
+	}{
+		{"seq1;size=12;sample=A;foo=bar", "seq1", ";foo=bar;sample=A;size=12"},
+		{"seq1;size=12;", "seq1", ";size=12"},
+		{"seq1 sample A;size=3", "seq1 sample A", ";size=3"},
+		{"seq1;win=1-4 sample A", "seq1 sample A", ";win=1-4"},
+		{"seq1;x=1 desc;y=2", "seq1 desc", ";x=1;y=2"},
+		{"seq1;size=2;size=5", "seq1", ";size=5"},
+		{"seq1;size=many;ok=", "seq1", ";size=many;ok="},
+		{"seq1;note;size=3", "seq1;note", ";size=3"},
+		{"size=12", "size=12", ""},
+		{"seq1 a=b", "seq1 a=b", ""},
+		{"seq1 ;size=3", "seq1", ";size=3"},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.header, func(t *testing.T) {
+			bare, notes := splitAnnotations([]byte(tt.header))
+			if string(bare) != tt.bare {
+				t.Errorf("Got header %q, want %q", bare, tt.bare)
+			}
+			if got := string(notes.appendTo(nil)); got != tt.expected {
+				t.Errorf("Got annotations %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+const annotatedSequences = ">seq1;foo=bar;size=12;sample=A first read\nACGT\n" +
+	">seq2;size=3\nTTTT\n" +
+	">seq3 plain\nGG\n"
+
+func TestAnnotationsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected string // Headers after the first pass (and, unless grown, the second one)
+		grows    bool   // Hashes are prepended on each pass
+	}{
+		{"Template", Config{HeaderFormat: "{id}"},
+			"seq1;foo=bar;sample=A;size=12 first read\nseq2;size=3\nseq3 plain\n", false},
+		{"seqkit-compatible", Config{SeqkitCompat: true},
+			"seq1;xxhash=f40a8ecfa26af897;foo=bar;sample=A;size=12; first read\n" +
+				"seq2;xxhash=fce1cde36bb8af7b;size=3;\n" +
+				"seq3;xxhash=c990ef291373497e; plain\n", false},
+		{"Default header", Config{},
+			"f40a8ecfa26af897;seq1;foo=bar;sample=A;size=12 first read\n" +
+				"fce1cde36bb8af7b;seq2;size=3\n" +
+				"c990ef291373497e;seq3 plain\n", true},
+		{"Strip annotations", Config{StripAnnotations: true, HeaderFormat: "{id}"},
+			"seq1 first read\nseq2\nseq3 plain\n", false},
+	}
+
+	hash := func(t *testing.T, cfg Config, input string) string {
+		cfg.HashTypes = []string{"xxhash"}
+		cfg.NoFileName = true
+		output := &bytes.Buffer{}
+		if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		return output.String()
+	}
+	headers := func(fasta string) string {
+		var b strings.Builder
+		for _, line := range strings.Split(fasta, "\n") {
+			if strings.HasPrefix(line, ">") {
+				b.WriteString(line[1:] + "\n")
+			}
+		}
+		return b.String()
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			once := hash(t, tt.cfg, annotatedSequences)
+			if got := headers(once); got != tt.expected {
+				t.Fatalf("First pass:\n%s\nwant:\n%s", got, tt.expected)
+			}
+
+			twice := headers(hash(t, tt.cfg, once))
+			if !tt.grows && twice != tt.expected {
+				t.Errorf("Second pass changed the headers:\n%s\nwant:\n%s", twice, tt.expected)
+			}
+			// Annotations are neither duplicated nor reordered
+			for _, header := range strings.Split(strings.TrimSpace(twice), "\n") {
+				if strings.Count(header, ";size=") > 1 || strings.Count(header, ";foo=") > 1 ||
+					strings.Count(header, "xxhash=") > 1 {
+					t.Errorf("Duplicated annotations in %q", header)
+				}
+			}
+			if tt.grows && !strings.HasPrefix(twice, "f40a8ecfa26af897;f40a8ecfa26af897;seq1;foo=bar;sample=A;size=12 first read\n") {
+				t.Errorf("Unexpected second pass:\n%s", twice)
+			}
+		})
+	}
+}
+
+func TestSizeinSizeoutReplacesSize(t *testing.T) {
+	input := ">a;size=5;sample=A\nACGT\n>b;size=2\nacgt\n>c\nACGT\n>d;size=4\nGG\n"
+	cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, HeadersOnly: true, HeaderFormat: "{id}",
+		Dedup: true, SizeIn: true, SizeOut: true}
+
+	output := &bytes.Buffer{}
+	if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	expected := "a;sample=A;size=8\nd;size=4\n"
+	if output.String() != expected {
+		t.Fatalf("Got:\n%s\nwant:\n%s", output, expected)
+	}
+
+	// Hashing the unique records again keeps their totals
+	again := &bytes.Buffer{}
+	if err := processSequences(strings.NewReader(">a;sample=A;size=8\nACGT\n>d;size=4\nGG\n"), again, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	if again.String() != expected {
+		t.Errorf("Second pass:\n%s\nwant:\n%s", again, expected)
+	}
+}
@@ -0,0 +1,204 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// Environment variable used when --audit-log is not given
+const auditLogEnv = "SEQHASHER_AUDIT_LOG"
+
+// Placeholder for values that must never end up in the audit log
+const redactedValue = "REDACTED"
+
+// Flags whose values are secrets
+var secretFlags = map[string]bool{
+	"hash-key": true,
+}
+
+// Input or output file of an audited run
+type auditFile struct {
+	Path   string    `json:"path"`
+	SHA256 string    `json:"sha256,omitempty"`
+	hash   hash.Hash // Checksum of the raw (e.g., compressed) bytes streamed in or out, for stdin and stdout
+}
+
+// tappedInput is an input file (or stdin) whose raw bytes are copied as they are read (--audit-log)
+type tappedInput struct {
+	io.Reader
+	io.Closer
+}
+
+// One line of the audit log
+type auditRecord struct {
+	Timestamp  string            `json:"timestamp"`
+	User       string            `json:"user"`
+	Host       string            `json:"host"`
+	Version    string            `json:"version"`
+	Command    []string          `json:"command"`
+	Options    map[string]string `json:"options"`
+	Input      *auditFile        `json:"input"`
+	Output     *auditFile        `json:"output"`
+	ExitStatus int               `json:"exit_status"`
+	Error      string            `json:"error,omitempty"`
+	Records    int64             `json:"records"`
+	Bases      int64             `json:"bases"`
+}
+
+// newAuditRecord starts the record of a run from the options it was parsed from
+// (each command of --stdin-commands has its own)
+func newAuditRecord(cfg Config) *auditRecord {
+	host, _ := os.Hostname()
+
+	outputPath := cfg.OutputFileName
+	if outputPath == "" {
+		outputPath = "-"
+	}
+
+	return &auditRecord{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		User:      currentUser(),
+		Host:      host,
+		Version:   version,
+		Command:   redactArgs(append([]string{"seqhasher"}, cfg.args...)),
+		Options:   cfg.effective,
+		Input:     &auditFile{Path: cfg.InputFileName, hash: sha256.New()},
+		Output:    &auditFile{Path: outputPath, hash: sha256.New()},
+	}
+}
+
+// write finalizes the record with the run outcome and appends it to the log
+// as a single line. The file is opened with O_APPEND and the line is written
+// with one write call, so concurrent runs never interleave partial lines.
+func (a *auditRecord) write(fileName string, stats Stats, runErr error) error {
+	a.Records = stats.Records
+	a.Bases = stats.Bases
+	if runErr != nil {
+		a.ExitStatus = ExitStatus(runErr)
+		a.Error = runErr.Error()
+	} else {
+		// Checksums are only meaningful when the whole stream was processed.
+		// Both are of the bytes as stored (e.g., of in.fa.gz, not of its decompressed records),
+		// so they can be checked against the files: regular files are read back,
+		// and stdin and stdout are hashed as they are streamed
+		for _, f := range []*auditFile{a.Input, a.Output} {
+			f.SHA256 = hex.EncodeToString(f.hash.Sum(nil))
+			if sum, err := fileSHA256(f.Path); err == nil {
+				f.SHA256 = sum
+			}
+		}
+	}
+
+	line, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fileSHA256 returns the checksum of a regular file
+func fileSHA256(fileName string) (string, error) {
+	return fileDigest(fileName, sha256.New())
+}
+
+// fileDigest returns the hex-encoded digest of a regular file
+func fileDigest(fileName string, h hash.Hash) (string, error) {
+	if info, err := os.Stat(fileName); err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", fileName)
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	// user.Current may fail in minimal containers without /etc/passwd
+	return os.Getenv("USER")
+}
+
+// effectiveOptions returns the value of every long-form flag
+// (shorthand aliases share their values, so they are skipped)
+func effectiveOptions(fs *flag.FlagSet) map[string]string {
+	options := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) == 1 {
+			return
+		}
+		options[f.Name] = flagValue(f)
+	})
+	return options
+}
+
+// explicitOptions returns the value of every flag given on the command line
+func explicitOptions(fs *flag.FlagSet) map[string]string {
+	options := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		options[f.Name] = flagValue(f)
+	})
+	return options
+}
+
+// flagValue returns the value of a flag, redacted for secret flags
+func flagValue(f *flag.Flag) string {
+	value := f.Value.String()
+	if secretFlags[f.Name] && value != "" {
+		return redactedValue
+	}
+	return value
+}
+
+// redactArgs returns a copy of the command line with secret flag values replaced
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+
+	for i := 0; i < len(redacted); i++ {
+		name := strings.TrimLeft(redacted[i], "-")
+		if name == redacted[i] {
+			continue // not a flag
+		}
+		if eq := strings.Index(name, "="); eq >= 0 {
+			if secretFlags[name[:eq]] {
+				redacted[i] = redacted[i][:len(redacted[i])-len(name)+eq+1] + redactedValue
+			}
+		} else if secretFlags[name] && i+1 < len(redacted) {
+			redacted[i+1] = redactedValue
+			i++
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,285 @@
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runWithArgs calls run() with the given command line and a fresh flag set
+func runWithArgs(args []string) (string, error) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	flag.CommandLine = flag.NewFlagSet(args[0], flag.ExitOnError)
+	os.Args = args
+
+	var buf bytes.Buffer
+	err := Run(&buf)
+	return buf.String(), err
+}
+
+func TestAuditLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "audit.jsonl")
+	outPath := filepath.Join(tmpDir, "out.fasta")
+
+	invocations := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"Stdout output", []string{"seqhasher", "--audit-log", logPath, testFastaPath}, false},
+		{"File output", []string{"seqhasher", "--audit-log", logPath, "--hash", "md5", testFastaPath, outPath}, false},
+		{"Missing input", []string{"seqhasher", "--audit-log", logPath, "nonexistent.fasta"}, true},
+	}
+
+	for _, inv := range invocations {
+		runTest(t, inv.name, func(t *testing.T) {
+			_, err := runWithArgs(inv.args)
+			if (err != nil) != inv.wantErr {
+				t.Errorf("run() error = %v, wantErr %v", err, inv.wantErr)
+			}
+		})
+	}
+
+	runTest(t, "Log lines", func(t *testing.T) {
+		f, err := os.Open(logPath)
+		if err != nil {
+			t.Fatalf("Failed to open audit log: %v", err)
+		}
+		defer f.Close()
+
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatalf("Audit line is not valid JSON: %v\n%s", err, scanner.Text())
+			}
+			lines = append(lines, entry)
+		}
+
+		if len(lines) != len(invocations) {
+			t.Fatalf("Expected %d audit lines, got %d", len(invocations), len(lines))
+		}
+
+		fields := []string{"timestamp", "user", "host", "version", "command", "options", "input", "output", "exit_status", "records", "bases"}
+		for i, entry := range lines {
+			for _, field := range fields {
+				if _, ok := entry[field]; !ok {
+					t.Errorf("Audit line %d is missing field %q", i+1, field)
+				}
+			}
+		}
+
+		if got := lines[0]["records"].(float64); got != 3 {
+			t.Errorf("Expected 3 records in the first run, got %v", got)
+		}
+		if got := lines[1]["options"].(map[string]interface{})["hash"]; got != "md5" {
+			t.Errorf("Expected hash option md5, got %v", got)
+		}
+		if got := lines[1]["output"].(map[string]interface{})["sha256"]; got == nil || got == "" {
+			t.Errorf("Expected output checksum in the second run, got %v", got)
+		}
+		if got := lines[2]["exit_status"].(float64); got != 1 {
+			t.Errorf("Expected exit status 1 for the failed run, got %v", got)
+		}
+	})
+}
+
+// readAuditLog returns the lines of an audit log
+func readAuditLog(t *testing.T, logPath string) []auditRecord {
+	t.Helper()
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	var records []auditRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record auditRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Audit line is not valid JSON: %v\n%s", err, line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestAuditLogCompressedFiles(t *testing.T) {
+	// The checksums are of the files as stored, so they can be checked against them
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "audit.jsonl")
+	outPath := filepath.Join(tmpDir, "out.fasta.gz")
+	if _, err := runWithArgs([]string{"seqhasher", "--audit-log", logPath, "../test/test.fasta.gz", outPath}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	input, err := os.ReadFile("../test/test.fasta.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := readAuditLog(t, logPath)[0]
+	if record.Input.SHA256 != sha256Hex(input) {
+		t.Errorf("Got input checksum %s, want the checksum of the compressed file %s", record.Input.SHA256, sha256Hex(input))
+	}
+	if record.Output.SHA256 != sha256Hex(output) {
+		t.Errorf("Got output checksum %s, want the checksum of the compressed file %s", record.Output.SHA256, sha256Hex(output))
+	}
+}
+
+func TestAuditLogCompressedStdin(t *testing.T) {
+	// Stdin and stdout are hashed as streamed, before decompression and after compression
+	input, err := os.ReadFile("../test/test.fasta.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	if _, err := stdin.Write(input); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdin.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	oldStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = oldStdin }()
+
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	output, err := runWithArgs([]string{"seqhasher", "--audit-log", logPath, "--compress", "xz", "-"})
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	record := readAuditLog(t, logPath)[0]
+	if record.Input.SHA256 != sha256Hex(input) {
+		t.Errorf("Got input checksum %s, want the checksum of the compressed stream %s", record.Input.SHA256, sha256Hex(input))
+	}
+	if record.Output.SHA256 != sha256Hex([]byte(output)) {
+		t.Errorf("Got output checksum %s, want the checksum of the compressed stream %s", record.Output.SHA256, sha256Hex([]byte(output)))
+	}
+}
+
+func TestAuditLogCommands(t *testing.T) {
+	// Each command of --stdin-commands is logged with its own options
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "audit.jsonl")
+	t.Setenv(auditLogEnv, logPath)
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session := `{"op":"hash","input":"` + input + `","output":"` + filepath.Join(tmpDir, "out1.fasta") + `","options":{"hash":"md5"}}` + "\n" +
+		`{"op":"hash","input":"` + input + `","output":"` + filepath.Join(tmpDir, "out2.fasta") + `","options":{"hash":"xxhash","hash-key":"secret"}}` + "\n"
+	if err := runCommands(strings.NewReader(session), &bytes.Buffer{}); err != nil {
+		t.Fatalf("runCommands() error = %v", err)
+	}
+
+	records := readAuditLog(t, logPath)
+	if len(records) != 2 {
+		t.Fatalf("Got %d audit lines, want 2", len(records))
+	}
+	for i, want := range []string{"md5", "xxhash"} {
+		if got := records[i].Options["hash"]; got != want {
+			t.Errorf("Command %d: got hash option %q, want %q", i+1, got, want)
+		}
+		if command := strings.Join(records[i].Command, " "); !strings.Contains(command, "--hash="+want) {
+			t.Errorf("Command %d: got command line %q, want --hash=%s", i+1, command, want)
+		}
+	}
+	if got := records[1].Options["hash-key"]; got != redactedValue {
+		t.Errorf("Got hash-key option %q, want it redacted", got)
+	}
+	if strings.Contains(strings.Join(records[1].Command, " "), "secret") {
+		t.Errorf("Secret in the logged command line %q", records[1].Command)
+	}
+}
+
+func TestAuditLogExitStatus(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit := &auditRecord{Input: &auditFile{hash: sha256.New()}, Output: &auditFile{hash: sha256.New()}}
+	if err := audit.write(logPath, Stats{}, &exitError{exitWarnings, os.ErrInvalid}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAuditLog(t, logPath)[0].ExitStatus; got != exitWarnings {
+		t.Errorf("Got exit status %d, want %d", got, exitWarnings)
+	}
+}
+
+func TestAuditLogFromEnv(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Setenv(auditLogEnv, logPath)
+
+	if _, err := runWithArgs([]string{"seqhasher", testFastaPath}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(logPath); err != nil {
+		t.Errorf("Expected audit log at %s: %v", logPath, err)
+	}
+}
+
+func TestAuditLogWriteFailure(t *testing.T) {
+	logPath := "/nonexistent/directory/audit.jsonl"
+
+	runTest(t, "Warning", func(t *testing.T) {
+		if _, err := runWithArgs([]string{"seqhasher", "--audit-log", logPath, testFastaPath}); err != nil {
+			t.Errorf("Expected only a warning, got error: %v", err)
+		}
+	})
+
+	runTest(t, "Strict", func(t *testing.T) {
+		_, err := runWithArgs([]string{"seqhasher", "--strict", "--audit-log", logPath, testFastaPath})
+		if err == nil || !strings.Contains(err.Error(), "Error writing audit log") {
+			t.Errorf("Expected audit log error under --strict, got %v", err)
+		}
+	})
+}
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			[]string{"seqhasher", "--hash-key", "secret", "in.fa"},
+			[]string{"seqhasher", "--hash-key", redactedValue, "in.fa"},
+		},
+		{
+			[]string{"seqhasher", "-hash-key=secret", "in.fa"},
+			[]string{"seqhasher", "-hash-key=" + redactedValue, "in.fa"},
+		},
+		{
+			[]string{"seqhasher", "--name", "secret", "in.fa"},
+			[]string{"seqhasher", "--name", "secret", "in.fa"},
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, strings.Join(tt.args, " "), func(t *testing.T) {
+			if got := redactArgs(tt.args); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("redactArgs(%q) = %q, want %q", tt.args, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+// With --auto-hash, the hash type is chosen per record by the length of the hashed sequence:
+// a cryptographic hash for short sequences, where hashing is cheap anyway,
+// and a fast non-cryptographic one for sequences longer than the threshold.
+// The chosen type is written as the hash-type annotation of the record.
+const (
+	autoHashShort            = "sha1"
+	autoHashLong             = "xxhash"
+	defaultAutoHashThreshold = 1000 // Bases
+)
+
+// autoHashType returns the hash type of a sequence of the given length (--auto-hash)
+func autoHashType(length, threshold int) string {
+	if length > threshold {
+		return autoHashLong
+	}
+	return autoHashShort
+}
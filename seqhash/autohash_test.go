@@ -0,0 +1,38 @@
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAutoHash(t *testing.T) {
+	tests := []struct {
+		name      string
+		length    int
+		threshold int
+		hashType  string
+	}{
+		{"Short sequence", 10, defaultAutoHashThreshold, "sha1"},
+		{"At the threshold", defaultAutoHashThreshold, defaultAutoHashThreshold, "sha1"},
+		{"Above the threshold", defaultAutoHashThreshold + 1, defaultAutoHashThreshold, "xxhash"},
+		{"Custom threshold", 5, 4, "xxhash"},
+		{"Zero threshold", 1, 0, "xxhash"},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			seq := strings.Repeat("ACGT", tt.length/4+1)[:tt.length]
+			cfg := Config{HashTypes: []string{autoHashShort}, NoFileName: true, AutoHash: true, AutoHashThreshold: tt.threshold}
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(">seq1\n"+seq+"\n"), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			expected := fmt.Sprintf("%s;seq1;hash-type=%s", getHashFunc(tt.hashType)([]byte(seq)), tt.hashType)
+			if ids := fastaIDs(t, output.Bytes()); len(ids) != 1 || ids[0] != expected {
+				t.Errorf("Expected header %q, got %v", expected, ids)
+			}
+		})
+	}
+}
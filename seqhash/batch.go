@@ -0,0 +1,237 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// Outcomes of the inputs of a multi-input run (--output-dir)
+const (
+	outcomeOK       = "ok"       // Processed without warnings
+	outcomeWarnings = "warnings" // Processed, but warnings were reported (e.g., records skipped with --on-error skip)
+	outcomeFailed   = "failed"   // Processing failed; the output is removed (or kept as partial with --keep-partial)
+	outcomeSkipped  = "skipped"  // Not processed: no records, or not reached after a failure with --fail-fast
+)
+
+// Exit statuses of multi-input runs; the most severe outcome wins
+const (
+	exitWarnings = 3 // Some inputs had warnings or were skipped
+	exitFailed   = 1 // Some inputs failed
+)
+
+// exitError is returned by runs that end with a specific exit status
+type exitError struct {
+	status int
+	err    error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// ExitStatus returns the exit status of the program for an error returned by Run
+func ExitStatus(err error) int {
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.status
+	}
+	return 1
+}
+
+// Outcome of an input (a row of the summary table, or an item of the --run-report)
+type inputResult struct {
+	File     string `json:"file"`
+	Output   string `json:"output,omitempty"`
+	Outcome  string `json:"outcome"`
+	Records  int64  `json:"records"`
+	Bases    int64  `json:"bases"`
+	Warnings int    `json:"warnings"`
+	Error    string `json:"error,omitempty"` // The error, or the first warning
+}
+
+// JSON report of a multi-input run (--run-report)
+type runReport struct {
+	Version    string         `json:"version"`
+	Inputs     []inputResult  `json:"inputs"`
+	Outcomes   map[string]int `json:"outcomes"`
+	ExitStatus int            `json:"exit_status"`
+}
+
+// batchInputs sets up a multi-input run: all arguments are inputs,
+// and each output is named after its input in the output directory
+func batchInputs(cfg *Config, args []string) error {
+	if cfg.Compare {
+		return fmt.Errorf("--output-dir can't be used with --compare")
+	}
+	for _, side := range []struct{ flag, file string }{
+		{"--index", cfg.IndexFileName},
+		{"--clusters", cfg.ClustersFile},
+		{"--top", cfg.TopFile},
+		{"--dedup-report", cfg.DedupReport},
+		{"--group-report", cfg.GroupReport},
+		{"--rejects", cfg.RejectsFileName},
+	} {
+		if side.file != "" {
+			return fmt.Errorf("%s can't be used with --output-dir (it would be overwritten by each input)", side.flag)
+		}
+	}
+
+	outputs := make(map[string]string, len(args))
+	for _, input := range args {
+		if input == "-" {
+			return fmt.Errorf("--output-dir does not support stdin input")
+		}
+		output := batchOutputName(cfg.OutputDir, input)
+		if previous, ok := outputs[output]; ok {
+			return fmt.Errorf("Inputs %s and %s would be written to the same output file %s", previous, input, output)
+		}
+		outputs[output] = input
+	}
+	cfg.Inputs = args
+	cfg.OutputFileName = ""
+	return nil
+}
+
+func batchOutputName(outputDir, input string) string {
+	return filepath.Join(outputDir, filepath.Base(input))
+}
+
+// runBatch processes the inputs one by one. Failures don't stop the run unless
+// --fail-fast is given; the outcome of each input is printed to stderr as a table
+// (and written to the --run-report), and the exit status reflects the worst outcome.
+func runBatch(cfg Config) error {
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		return fmt.Errorf("Error creating output directory: %v", err)
+	}
+
+	results := make([]inputResult, 0, len(cfg.Inputs))
+	stop := ""
+	for _, input := range cfg.Inputs {
+		if stop != "" {
+			results = append(results, inputResult{File: input, Outcome: outcomeSkipped, Error: stop})
+			continue
+		}
+		result := processInput(cfg, input)
+		results = append(results, result)
+
+		if interrupted.Load() {
+			stop = "not processed (interrupted)"
+		} else if result.Outcome == outcomeFailed && cfg.FailFast {
+			stop = "not processed (--fail-fast)"
+		}
+	}
+
+	report := runReport{Version: version, Inputs: results, Outcomes: make(map[string]int)}
+	for _, r := range results {
+		report.Outcomes[r.Outcome]++
+	}
+	switch {
+	case report.Outcomes[outcomeFailed] > 0:
+		report.ExitStatus = exitFailed
+	case report.Outcomes[outcomeWarnings] > 0 || report.Outcomes[outcomeSkipped] > 0:
+		report.ExitStatus = exitWarnings
+	}
+
+	if err := printRunSummary(os.Stderr, results); err != nil {
+		return err
+	}
+	if cfg.RunReport != "" {
+		if err := writeRunReport(cfg.RunReport, report); err != nil {
+			return fmt.Errorf("Error writing run report: %v", err)
+		}
+	}
+
+	switch report.ExitStatus {
+	case exitFailed:
+		return &exitError{exitFailed, fmt.Errorf("%d of %d inputs failed", report.Outcomes[outcomeFailed], len(results))}
+	case exitWarnings:
+		return &exitError{exitWarnings, fmt.Errorf("%d of %d inputs had warnings or were skipped",
+			report.Outcomes[outcomeWarnings]+report.Outcomes[outcomeSkipped], len(results))}
+	}
+	return nil
+}
+
+// processInput processes an input of a multi-input run and classifies the outcome
+func processInput(cfg Config, input string) inputResult {
+	cfg.InputFileName = input
+	cfg.OutputFileName = batchOutputName(cfg.OutputDir, input)
+	result := inputResult{File: input, Output: cfg.OutputFileName}
+
+	// Warnings are logged as they occur, so they are counted on their way to the log
+	warnings := &warningCounter{w: log.Writer()}
+	log.SetOutput(warnings)
+	stats, err := processFile(io.Discard, cfg)
+	log.SetOutput(warnings.w)
+
+	result.Records, result.Bases, result.Warnings = stats.Records, stats.Bases, warnings.count
+	switch {
+	case err != nil:
+		result.Outcome = outcomeFailed
+		result.Error = err.Error()
+	case warnings.count > 0:
+		result.Outcome = outcomeWarnings
+		result.Error = warnings.first
+	case stats.Records == 0:
+		// Nothing to output for empty inputs
+		result.Outcome = outcomeSkipped
+		result.Error = "no records"
+		result.Output = ""
+		os.Remove(cfg.OutputFileName)
+	default:
+		result.Outcome = outcomeOK
+	}
+	if err != nil && !cfg.KeepPartial {
+		result.Output = "" // Removed by processFile
+	}
+	return result
+}
+
+// warningCounter passes log messages through, counting warnings and errors
+type warningCounter struct {
+	w     io.Writer
+	count int
+	first string
+}
+
+func (wc *warningCounter) Write(p []byte) (int, error) {
+	message := string(p)
+	for _, level := range []string{"Warning", "Error"} {
+		if i := strings.Index(message, level); i >= 0 {
+			wc.count++
+			if wc.first == "" {
+				wc.first = strings.TrimSpace(message[i:])
+			}
+			break
+		}
+	}
+	return wc.w.Write(p)
+}
+
+func printRunSummary(w io.Writer, results []inputResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tOUTCOME\tRECORDS\tWARNINGS\tERROR")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", r.File, strings.ToUpper(r.Outcome), r.Records, r.Warnings, r.Error)
+	}
+	return tw.Flush()
+}
+
+func writeRunReport(fileName string, report runReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, append(data, '\n'), 0644)
+}
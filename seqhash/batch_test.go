@@ -0,0 +1,153 @@
+package seqhash
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBatchOutcomes(t *testing.T) {
+	inputDir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(inputDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	good := write("good.fasta", ">seq1\nACTG\n>seq2\nTGCA\n")
+	warned := write("warned.fasta", ">seq1\nACTG\n>quote\nAC“TG\n")
+	broken := write("broken.fastq", "@seq1\nACTG\n+\nII\n") // Quality shorter than the sequence
+	empty := write("empty.fasta", "")
+
+	tests := []struct {
+		name     string
+		args     []string
+		inputs   []string
+		outcomes []string
+		records  []int64
+		errors   []string // Substrings of the errors (or first warnings)
+		status   int
+	}{
+		{
+			name:     "Failure does not stop the run",
+			inputs:   []string{good, broken, empty},
+			outcomes: []string{outcomeOK, outcomeFailed, outcomeSkipped},
+			records:  []int64{2, 0, 0},
+			errors:   []string{"", "Error reading record", "no records"},
+			status:   exitFailed,
+		},
+		{
+			name:     "Fail fast",
+			args:     []string{"--on-error", "skip", "--fail-fast"},
+			inputs:   []string{warned, broken, good},
+			outcomes: []string{outcomeWarnings, outcomeFailed, outcomeSkipped},
+			records:  []int64{1, 0, 0},
+			errors:   []string{`Warning: skipping record: Invalid byte in the sequence of record "quote"`, "Error reading record", "not processed (--fail-fast)"},
+			status:   exitFailed,
+		},
+		{
+			name:     "Warnings only",
+			args:     []string{"--on-error", "skip"},
+			inputs:   []string{good, warned, empty},
+			outcomes: []string{outcomeOK, outcomeWarnings, outcomeSkipped},
+			records:  []int64{2, 1, 0},
+			errors:   []string{"", "Warning: skipping record", "no records"},
+			status:   exitWarnings,
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "out")
+			reportPath := filepath.Join(t.TempDir(), "report.json")
+			args := append([]string{"seqhasher", "--output-dir", outputDir, "--run-report", reportPath}, tt.args...)
+			_, err := runWithArgs(append(args, tt.inputs...))
+
+			var exit *exitError
+			if !errors.As(err, &exit) || exit.status != tt.status {
+				t.Fatalf("run() error = %v, want exit status %d", err, tt.status)
+			}
+
+			data, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatalf("Failed to read run report: %v", err)
+			}
+			var report runReport
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatalf("Invalid run report: %v\n%s", err, data)
+			}
+			if report.ExitStatus != tt.status || len(report.Inputs) != len(tt.inputs) {
+				t.Fatalf("Unexpected run report:\n%s", data)
+			}
+			for i, r := range report.Inputs {
+				if r.File != tt.inputs[i] || r.Outcome != tt.outcomes[i] || r.Records != tt.records[i] || !strings.Contains(r.Error, tt.errors[i]) {
+					t.Errorf("Input %d: got %+v, want outcome %s, %d records, error containing %q",
+						i, r, tt.outcomes[i], tt.records[i], tt.errors[i])
+				}
+
+				// Only inputs that were processed have outputs
+				_, statErr := os.Stat(filepath.Join(outputDir, filepath.Base(r.File)))
+				processed := r.Outcome == outcomeOK || r.Outcome == outcomeWarnings
+				if processed != (statErr == nil) || processed != (r.Output != "") {
+					t.Errorf("Input %d (%s): output %q, stat error %v", i, r.Outcome, r.Output, statErr)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchKeepPartial(t *testing.T) {
+	inputDir := t.TempDir()
+	broken := filepath.Join(inputDir, "broken.fastq")
+	if err := os.WriteFile(broken, []byte("@seq1\nACTG\n+\nIIII\n@seq2\nACTG\n+\nII\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(t.TempDir(), "out")
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+
+	_, err := runWithArgs([]string{"seqhasher", "--output-dir", outputDir, "--run-report", reportPath,
+		"--out-format", "json", "--keep-partial", broken})
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.status != exitFailed {
+		t.Fatalf("run() error = %v, want exit status %d", err, exitFailed)
+	}
+
+	// The partial output is kept, marked as incomplete, and listed in the report
+	output, err := os.ReadFile(filepath.Join(outputDir, "broken.fastq"))
+	if err != nil {
+		t.Fatalf("Partial output was not kept: %v", err)
+	}
+	if !strings.Contains(string(output), "// seqhasher: incomplete output") {
+		t.Errorf("Partial output is not marked as incomplete:\n%s", output)
+	}
+	data, _ := os.ReadFile(reportPath)
+	var report runReport
+	if err := json.Unmarshal(data, &report); err != nil || report.Inputs[0].Output == "" {
+		t.Errorf("Run report does not list the partial output:\n%s", data)
+	}
+}
+
+func TestBatchInputsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"Stdin", []string{"--output-dir", "out", "-"}, "--output-dir does not support stdin input"},
+		{"Same output", []string{"--output-dir", "out", "a/x.fasta", "b/x.fasta"}, "Inputs a/x.fasta and b/x.fasta would be written to the same output file out/x.fasta"},
+		{"Side file", []string{"--output-dir", "out", "--index", "index.tsv", "x.fasta"}, "--index can't be used with --output-dir"},
+		{"Report without output directory", []string{"--run-report", "report.json", "x.fasta"}, "--fail-fast and --run-report require --output-dir"},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			_, err := runWithArgs(append([]string{"seqhasher"}, tt.args...))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("run() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
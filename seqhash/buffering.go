@@ -0,0 +1,106 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// recordBuffer keeps the records that can only be written at the end of the input.
+// With --sizeout or --stratified-sample, the unique records are kept until their abundances are known,
+// and with --reverse-output or --group-by-length, all records are kept until their output order is known.
+type recordBuffer struct {
+	cfg        Config
+	abundances bool // Unique records wait for the abundances of their duplicates
+	ordered    bool // All records wait for their position in the output (also the passed-through ones)
+	dedup      *deduplicator
+	sampler    *stratifiedSampler
+	sizes      *abundanceParser
+	records    []keptRecord
+}
+
+// newRecordBuffer returns nil if the records are written as they are hashed
+func newRecordBuffer(cfg Config, dedup *deduplicator, sampler *stratifiedSampler, sizes *abundanceParser) *recordBuffer {
+	b := &recordBuffer{
+		cfg:        cfg,
+		abundances: cfg.SizeOut || sampler != nil,
+		ordered:    cfg.ReverseOutput || cfg.LengthBin > 0,
+		dedup:      dedup,
+		sampler:    sampler,
+		sizes:      sizes,
+	}
+	if !b.abundances && !b.ordered {
+		return nil
+	}
+	return b
+}
+
+// add keeps a copy of the record (the reader reuses its records)
+func (b *recordBuffer) add(k keptRecord) {
+	k.record, k.hashes = k.record.Clone(), slices.Clone(k.hashes)
+	b.records = append(b.records, k)
+}
+
+// flush writes the kept records in output order
+func (b *recordBuffer) flush(ctx context.Context, e *recordEmitter, stats *Stats) error {
+	cfg := b.cfg
+	kept := b.records
+
+	// Strata are assigned by the abundances of all duplicates, and the records of a stratum
+	// are sampled by their first digests, so equal sequences are sampled alike across datasets
+	if b.sampler != nil {
+		kept = slices.DeleteFunc(kept, func(k keptRecord) bool {
+			digest := ""
+			if len(k.hashes) > 0 {
+				digest = k.hashes[0]
+			}
+			return !b.sampler.keep(b.dedup.size(k.unique), digest)
+		})
+		stats.strata = b.sampler.summary
+	}
+
+	// Unique records in first-seen order, with the abundances of all their duplicates
+	// (or the last-seen first, with --reverse-output)
+	if cfg.ReverseOutput {
+		slices.Reverse(kept)
+	}
+	// Length bins in ascending order, keeping the order of the records within a bin
+	bin := func(k keptRecord) int { return len(k.record.Seq.Seq) / cfg.LengthBin }
+	if cfg.LengthBin > 0 {
+		slices.SortStableFunc(kept, func(a, b keptRecord) int { return bin(a) - bin(b) })
+	}
+	for i, k := range kept {
+		if interrupted.Load() {
+			return errInterrupted
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if cfg.LengthBin > 0 && (i == 0 || bin(k) != bin(kept[i-1])) {
+			from := bin(k) * cfg.LengthBin
+			if err := e.section(fmt.Sprintf("length-bin: %d-%d", from, from+cfg.LengthBin-1)); err != nil {
+				return err
+			}
+		}
+		if k.excluded {
+			if err := e.write(k.record, nil, k.comments, false); err != nil {
+				return err
+			}
+			continue
+		}
+		size := int64(-1)
+		if k.unique >= 0 && cfg.SizeOut {
+			k.record.Name = b.sizes.strip(k.record.Name)
+			size = b.dedup.size(k.unique)
+		}
+		if err := e.emit(k, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package seqhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRecordBufferPassthrough(t *testing.T) {
+	// Passed-through records keep their position among the hashed ones, also in reverse order
+	input := ">a\nACGTACGT\n>short\nAC\n>b\nTTTTTTTT\n"
+	for _, reverse := range []bool{false, true} {
+		cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, MinLen: 5, PassthroughExcluded: true, ReverseOutput: reverse}
+		output := &bytes.Buffer{}
+		if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		var ids []string
+		for _, line := range strings.Split(output.String(), "\n") {
+			if header, ok := strings.CutPrefix(line, ">"); ok {
+				ids = append(ids, header[strings.LastIndex(header, ";")+1:])
+			}
+		}
+		want := "a,short,b"
+		if reverse {
+			want = "b,short,a"
+		}
+		if got := strings.Join(ids, ","); got != want {
+			t.Errorf("reverse=%v: got records %s, want %s:\n%s", reverse, got, want, output.String())
+		}
+	}
+}
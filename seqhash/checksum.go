@@ -0,0 +1,102 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sidecar extensions looked up with --verify-input-checksum, in order of preference
+var checksumSidecars = []string{".sha256", ".md5"}
+
+// verifyInputChecksum compares the checksum of the raw input file (before decompression)
+// with the one in the checksum file (--verify-input, or a sidecar with --verify-input-checksum)
+func verifyInputChecksum(cfg Config) error {
+	checksumFile := cfg.VerifyInput
+	if checksumFile == "" {
+		candidates := make([]string, len(checksumSidecars))
+		for i, ext := range checksumSidecars {
+			candidates[i] = cfg.InputFileName + ext
+			if _, err := os.Stat(candidates[i]); err == nil && checksumFile == "" {
+				checksumFile = candidates[i]
+			}
+		}
+		if checksumFile == "" {
+			return fmt.Errorf("No checksum file found for %s (looked for %s)", cfg.InputFileName, strings.Join(candidates, ", "))
+		}
+	}
+
+	expected, err := readChecksum(checksumFile, cfg.InputFileName)
+	if err != nil {
+		return fmt.Errorf("Error reading checksum file: %v", err)
+	}
+
+	var h hash.Hash
+	algorithm := "md5"
+	switch len(expected) {
+	case 2 * md5.Size:
+		h = md5.New()
+	case 2 * sha256.Size:
+		h, algorithm = sha256.New(), "sha256"
+	default:
+		return fmt.Errorf("Invalid checksum in %s: %s. Supported checksums are MD5 and SHA-256", checksumFile, expected)
+	}
+	actual, err := fileDigest(cfg.InputFileName, h)
+	if err != nil {
+		return fmt.Errorf("Error checking input: %v", err)
+	}
+	if actual != expected {
+		return fmt.Errorf("Input checksum mismatch for %s: expected %s %s (from %s), got %s",
+			cfg.InputFileName, algorithm, expected, checksumFile, actual)
+	}
+	return nil
+}
+
+// readChecksum returns the checksum of the input file from a checksum file,
+// either in the format of md5sum and sha256sum ("<checksum>  <file name>" lines,
+// matched by the base name of the input), or holding a single bare checksum
+func readChecksum(checksumFile, inputFileName string) (string, error) {
+	f, err := os.Open(checksumFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var checksums []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		checksum, name, found := strings.Cut(line, " ")
+		checksum = strings.ToLower(checksum)
+		if !found {
+			checksums = append(checksums, checksum)
+			continue
+		}
+		// Binary mode of md5sum marks the name with '*'
+		name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+		if filepath.Base(name) == filepath.Base(inputFileName) {
+			return checksum, nil
+		}
+		checksums = append(checksums, checksum)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if len(checksums) != 1 {
+		return "", fmt.Errorf("no checksum for %s in %s", filepath.Base(inputFileName), checksumFile)
+	}
+	return checksums[0], nil
+}
@@ -0,0 +1,81 @@
+package seqhash
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyInputChecksum(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+	md5Sum := md5.Sum([]byte(testSequences))
+	sha256Sum := sha256.Sum256([]byte(testSequences))
+	goodMD5 := hex.EncodeToString(md5Sum[:])
+	goodSHA256 := hex.EncodeToString(sha256Sum[:])
+	badMD5 := strings.Repeat("0", 32)
+
+	writeChecksum := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"Correct md5sum file", []string{"--verify-input", writeChecksum("good.md5", goodMD5+"  input.fasta\n")}, ""},
+		{"Correct bare SHA-256", []string{"--verify-input", writeChecksum("good.sha256", strings.ToUpper(goodSHA256)+"\n")}, ""},
+		{"Entry of several files", []string{"--verify-input", writeChecksum("all.md5",
+			badMD5+"  other.fasta\n"+goodMD5+" *data/input.fasta\n")}, ""},
+		{"Incorrect checksum", []string{"--verify-input", writeChecksum("bad.md5", badMD5+"  input.fasta\n")},
+			"Input checksum mismatch for " + input + ": expected md5 " + badMD5},
+		{"No entry for the input", []string{"--verify-input", writeChecksum("others.md5",
+			badMD5+"  a.fasta\n"+badMD5+"  b.fasta\n")}, "no checksum for input.fasta"},
+		{"Invalid checksum", []string{"--verify-input", writeChecksum("short.md5", "abc123\n")}, "Invalid checksum"},
+		{"Missing sidecar", []string{"--verify-input-checksum"}, "No checksum file found for " + input},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			output, err := runWithArgs(append(append([]string{"seqhasher"}, tt.args...), input))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if strings.Count(output, ">") != 3 {
+					t.Errorf("Expected all records in the output, got:\n%s", output)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if output != "" {
+				t.Errorf("Expected no output after a failed check, got:\n%s", output)
+			}
+		})
+	}
+
+	runTest(t, "Sidecar", func(t *testing.T) {
+		writeChecksum("input.fasta.sha256", goodSHA256+"  input.fasta\n")
+		if _, err := runWithArgs([]string{"seqhasher", "--verify-input-checksum", input}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		writeChecksum("input.fasta.sha256", strings.Repeat("0", 64)+"  input.fasta\n")
+		if _, err := runWithArgs([]string{"seqhasher", "--verify-input-checksum", input}); err == nil {
+			t.Fatal("Expected a checksum mismatch")
+		}
+	})
+}
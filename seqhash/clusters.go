@@ -0,0 +1,128 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultTopN = 10 // Number of groups in the --top report
+
+// Marker appended to sequences truncated by --seq-limit
+const truncationMarker = "…"
+
+// Group of records with identical sequences (the same digest of the first hash type)
+type seqGroup struct {
+	digest         string
+	representative string // ID of the first record
+	size           int64
+	seq            []byte // Hashed bytes of the representative (only with --with-sequences)
+	first          int    // Order of appearance
+}
+
+// groupCollector gathers the groups for the --clusters and --top reports
+type groupCollector struct {
+	groups   map[string]*seqGroup
+	order    []*seqGroup
+	withSeqs bool
+}
+
+// newGroupCollector returns nil if no report was requested
+func newGroupCollector(cfg Config) *groupCollector {
+	if cfg.ClustersFile == "" && cfg.TopFile == "" {
+		return nil
+	}
+	return &groupCollector{groups: make(map[string]*seqGroup), withSeqs: cfg.WithSequences}
+}
+
+// add counts a record with its abundance (1 without --sizein);
+// seq must be the exact bytes that were hashed
+func (c *groupCollector) add(digest string, id, seq []byte, size int64) {
+	if digest == "" {
+		return // Empty sequence or hash failure
+	}
+	if g, ok := c.groups[digest]; ok {
+		g.size += size
+		return
+	}
+	g := &seqGroup{digest: digest, representative: string(id), size: size, first: len(c.order)}
+	if c.withSeqs {
+		g.seq = append([]byte{}, seq...)
+	}
+	c.groups[digest] = g
+	c.order = append(c.order, g)
+}
+
+// writeReports writes the requested reports after all records were processed
+func (c *groupCollector) writeReports(cfg Config) error {
+	if cfg.ClustersFile != "" {
+		if err := c.write(cfg.ClustersFile, c.order, false, cfg); err != nil {
+			return fmt.Errorf("Error writing clusters: %v", err)
+		}
+	}
+	if cfg.TopFile != "" {
+		top := append([]*seqGroup{}, c.order...)
+		sort.SliceStable(top, func(i, j int) bool { return top[i].size > top[j].size })
+		if len(top) > cfg.TopN {
+			top = top[:cfg.TopN]
+		}
+		if err := c.write(cfg.TopFile, top, true, cfg); err != nil {
+			return fmt.Errorf("Error writing top report: %v", err)
+		}
+	}
+	return nil
+}
+
+// write outputs the groups as TSV. With --with-sequences, the length of the
+// representative's sequence and the sequence itself are the last two columns.
+func (c *groupCollector) write(fileName string, groups []*seqGroup, ranked bool, cfg Config) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	var columns []string
+	if ranked {
+		columns = append(columns, "rank")
+	}
+	columns = append(columns, cfg.HashTypes[0], "size", "representative")
+	if c.withSeqs {
+		columns = append(columns, "length", "sequence")
+	}
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+
+	for i, g := range groups {
+		var row []string
+		if ranked {
+			row = append(row, strconv.Itoa(i+1))
+		}
+		row = append(row, g.digest, strconv.FormatInt(g.size, 10), g.representative)
+		if c.withSeqs {
+			row = append(row, strconv.Itoa(len(g.seq)), truncateSequence(g.seq, cfg.SeqLimit))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// truncateSequence shortens sequences longer than limit (0 means no limit), marking the cut
+func truncateSequence(seq []byte, limit int) string {
+	if limit <= 0 || len(seq) <= limit {
+		return string(seq)
+	}
+	return string(seq[:limit]) + truncationMarker
+}
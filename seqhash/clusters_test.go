@@ -0,0 +1,120 @@
+package seqhash
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// readTSV returns the rows of a TSV file, including the column names
+func readTSV(t *testing.T, fileName string) [][]string {
+	t.Helper()
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+	return rows
+}
+
+func TestClustersAndTop(t *testing.T) {
+	tmpDir := t.TempDir()
+	clusters := filepath.Join(tmpDir, "clusters.tsv")
+	top := filepath.Join(tmpDir, "top.tsv")
+	input := ">a\nACTG\n>b\nGGGG\n>c\nactg\n>d\nNNGGGGN\n>e\nACTG\n>f\nTTTT\n"
+
+	cfg := Config{HashTypes: []string{"sha1"}, ClustersFile: clusters, TopFile: top, TopN: 2}
+	if err := processSequences(strings.NewReader(input), &strings.Builder{}, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+
+	expected := [][]string{
+		{"sha1", "size", "representative"},
+		{"65c89f59d38cdbf90dfaf0b0a6884829df8396b0", "3", "a"},
+		{getHashFunc("sha1")([]byte("GGGG")), "1", "b"},
+		{getHashFunc("sha1")([]byte("NNGGGGN")), "1", "d"},
+		{getHashFunc("sha1")([]byte("TTTT")), "1", "f"},
+	}
+	if got := readTSV(t, clusters); !equalRows(got, expected) {
+		t.Errorf("Unexpected clusters:\n%v\nWant:\n%v", got, expected)
+	}
+
+	rows := readTSV(t, top)
+	if len(rows) != 3 || strings.Join(rows[0], ",") != "rank,sha1,size,representative" ||
+		strings.Join(rows[1], ",") != "1,65c89f59d38cdbf90dfaf0b0a6884829df8396b0,3,a" || rows[2][3] != "b" {
+		t.Errorf("Unexpected top report:\n%v", rows)
+	}
+}
+
+// The emitted sequences must be the hashed bytes, so that digests can be re-verified
+func TestReportSequencesMatchDigests(t *testing.T) {
+	long := strings.Repeat("ACGT", 30)
+	input := ">a\nNNacgtN\n>b\n" + long + "\n>c\n" + long + "\n>d\nAC GT\nTT\n"
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"Default normalization", Config{}},
+		{"Case-sensitive", Config{CaseSensitive: true}},
+		{"Trimmed Ns", Config{TrimNs: true}},
+		{"Sequence limit", Config{SeqLimit: 10}},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"md5", "sha1"}
+			cfg.ClustersFile = filepath.Join(tmpDir, "clusters.tsv")
+			cfg.TopFile = filepath.Join(tmpDir, "top.tsv")
+			cfg.TopN = defaultTopN
+			cfg.WithSequences = true
+			if err := processSequences(strings.NewReader(input), &strings.Builder{}, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+
+			for _, report := range []string{cfg.ClustersFile, cfg.TopFile} {
+				rows := readTSV(t, report)
+				columns := rows[0]
+				if columns[len(columns)-2] != "length" || columns[len(columns)-1] != "sequence" {
+					t.Fatalf("Expected length and sequence as the last columns, got %v", columns)
+				}
+				digestColumn := len(columns) - 5
+				for _, row := range rows[1:] {
+					sequence := row[len(row)-1]
+					length, _ := strconv.Atoi(row[len(row)-2])
+					if cfg.SeqLimit > 0 && length > cfg.SeqLimit {
+						if !strings.HasSuffix(sequence, truncationMarker) || len(strings.TrimSuffix(sequence, truncationMarker)) != cfg.SeqLimit {
+							t.Errorf("Expected a truncated sequence with a marker, got %q", sequence)
+						}
+						continue
+					}
+					if len(sequence) != length {
+						t.Errorf("Length column %d does not match the sequence %q", length, sequence)
+					}
+					if digest := getHashFunc("md5")([]byte(sequence)); digest != row[digestColumn] {
+						t.Errorf("Sequence %q hashes to %s, but the group digest is %s", sequence, digest, row[digestColumn])
+					}
+				}
+			}
+		})
+	}
+}
+
+func equalRows(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if strings.Join(a[i], "\t") != strings.Join(b[i], "\t") {
+			return false
+		}
+	}
+	return true
+}
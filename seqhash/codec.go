@@ -0,0 +1,216 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/zstd"
+	gzip "github.com/klauspost/pgzip"
+	"github.com/ulikunitz/xz"
+)
+
+// codec is a compression format of inputs and outputs. Inputs are recognized
+// by their magic bytes (as in the xopen package used by fastx), or by their extension
+// for formats without magic bytes; outputs are compressed according to their extension
+// or --compress.
+type codec struct {
+	name       string
+	magic      []byte   // Leading bytes of compressed data (nil if the format has none)
+	extensions []string // File name extensions, e.g., ".xz"
+	tag        string   // Build tag of an optional codec ("" if always compiled in)
+
+	// Constructors (nil for optional codecs that were not compiled in);
+	// codecs without newWriter can only be read
+	newReader func(r io.Reader) (io.ReadCloser, error)
+	newWriter func(w io.Writer, level int) (io.WriteCloser, error)
+}
+
+// Registered codecs. Optional codecs are listed without constructors,
+// so that their files are reported with the build tag that enables them;
+// their files (codec_<tag>.go) replace the entries when compiled in.
+var codecs = []*codec{
+	{
+		name: "gzip", magic: []byte{0x1f, 0x8b}, extensions: []string{".gz"},
+		newReader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	},
+	{
+		name: "zstd", magic: []byte{0x28, 0xb5, 0x2f, 0xfd}, extensions: []string{".zst"},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			zr, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return zr.IOReadCloser(), nil
+		},
+	},
+	{
+		name: "xz", magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, extensions: []string{".xz"},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			xr, err := xz.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return io.NopCloser(xr), nil
+		},
+		newWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level < 0 || level >= len(xzDictSizes) {
+				return nil, fmt.Errorf("Invalid xz compression level: %d", level)
+			}
+			return xz.WriterConfig{DictCap: xzDictSizes[level]}.NewWriter(w)
+		},
+	},
+	{
+		name: "bzip2", magic: []byte{'B', 'Z', 'h'}, extensions: []string{".bz2"},
+		newReader: func(r io.Reader) (io.ReadCloser, error) { return bzip2.NewReader(r, nil) },
+		newWriter: func(w io.Writer, _ int) (io.WriteCloser, error) {
+			// The standard library only decompresses bzip2; dsnet/compress provides
+			// the encoder (the same package is used by fastx to read bzip2 input)
+			return bzip2.NewWriter(w, &bzip2.WriterConfig{Level: bzip2.DefaultCompression})
+		},
+	},
+	{name: "lz4", magic: []byte{0x04, 0x22, 0x4d, 0x18}, extensions: []string{".lz4"}, tag: "lz4"},
+	{name: "brotli", extensions: []string{".br"}, tag: "brotli"},
+}
+
+// registerCodec replaces the entry of an optional codec with its implementation
+func registerCodec(c *codec) {
+	for i, existing := range codecs {
+		if existing.name == c.name {
+			codecs[i] = c
+			return
+		}
+	}
+	codecs = append(codecs, c)
+}
+
+func findCodec(name string) *codec {
+	for _, c := range codecs {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (c *codec) compiledIn() bool { return c.newReader != nil }
+
+// errNotCompiledIn reports an optional codec that is missing from the build
+func (c *codec) errNotCompiledIn() error {
+	return fmt.Errorf("%s codec not compiled in (rebuild with -tags %s)", c.name, c.tag)
+}
+
+// codecNames returns the names of the compiled-in codecs that can read (or also write) files
+func codecNames(writable bool) []string {
+	var names []string
+	for _, c := range codecs {
+		if c.compiledIn() && (!writable || c.newWriter != nil) {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// Number of leading bytes needed to recognize any codec
+const codecMagicLen = 6
+
+// Sequence formats reported by detectFormat
+const (
+	formatFASTA = "FASTA"
+	formatFASTQ = "FASTQ"
+	formatEmpty = "empty"
+	formatOther = "other"
+)
+
+// detectCodec returns the compression codec of data starting with header ("none" if not compressed)
+func detectCodec(header []byte) string {
+	for _, c := range codecs {
+		if c.magic != nil && bytes.HasPrefix(header, c.magic) {
+			return c.name
+		}
+	}
+	return "none"
+}
+
+// codecFromName returns the codec of a format without magic bytes implied by the file extension
+func codecFromName(fileName string) string {
+	for _, c := range codecs {
+		if c.magic != nil {
+			continue
+		}
+		for _, ext := range c.extensions {
+			if strings.HasSuffix(fileName, ext) {
+				return c.name
+			}
+		}
+	}
+	return "none"
+}
+
+// detectFormat returns the sequence format of decompressed data starting with header
+func detectFormat(header []byte) string {
+	header = bytes.TrimPrefix(header, []byte("\uFEFF"))
+	header = bytes.TrimLeft(header, " \t\r\n")
+	if len(header) == 0 {
+		return formatEmpty
+	}
+	switch header[0] {
+	case '>':
+		return formatFASTA
+	case '@':
+		return formatFASTQ
+	}
+	return formatOther
+}
+
+// decodedInput is the decompressed stream of an input
+type decodedInput struct {
+	io.Reader
+	codec   string
+	closers []io.Closer // closed in reverse order
+}
+
+func (d *decodedInput) Close() error {
+	var err error
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		if cerr := d.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// decodeInput detects the compression of src by its magic bytes (or, for formats without them,
+// by the extension of fileName) and returns the decompressed stream. Closing the result also closes src.
+func decodeInput(src io.ReadCloser, fileName string) (*decodedInput, error) {
+	buffered := bufio.NewReader(src)
+	header, _ := buffered.Peek(codecMagicLen) // short inputs are not compressed
+
+	d := &decodedInput{codec: detectCodec(header), closers: []io.Closer{src}}
+	if d.codec == "none" {
+		d.codec = codecFromName(fileName)
+	}
+	c := findCodec(d.codec)
+	if c == nil {
+		d.Reader = buffered
+		return d, nil
+	}
+	if !c.compiledIn() {
+		return nil, fmt.Errorf("Error reading %s stream: %v", c.name, c.errNotCompiledIn())
+	}
+	r, err := c.newReader(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s stream: %v", c.name, err)
+	}
+	d.Reader = r
+	d.closers = append(d.closers, r)
+	return d, nil
+}
@@ -0,0 +1,30 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+//go:build brotli
+
+package seqhash
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Brotli, enabled with -tags brotli. Brotli streams have no magic bytes,
+// so inputs are recognized by the ".br" extension (stdin can't be detected).
+func init() {
+	registerCodec(&codec{
+		name:       "brotli",
+		extensions: []string{".br"},
+		tag:        "brotli",
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(brotli.NewReader(r)), nil
+		},
+		newWriter: func(w io.Writer, _ int) (io.WriteCloser, error) {
+			return brotli.NewWriterLevel(w, brotli.DefaultCompression), nil
+		},
+	})
+}
@@ -0,0 +1,9 @@
+//go:build brotli
+
+package seqhash
+
+import "testing"
+
+func TestBrotliRoundTrip(t *testing.T) {
+	roundTripCodec(t, "out.fasta.br", "brotli")
+}
@@ -0,0 +1,30 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+//go:build lz4
+
+package seqhash
+
+import (
+	"io"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+// LZ4 frame format (as written by the lz4 tool), enabled with -tags lz4
+func init() {
+	registerCodec(&codec{
+		name:       "lz4",
+		magic:      []byte{0x04, 0x22, 0x4d, 0x18},
+		extensions: []string{".lz4"},
+		tag:        "lz4",
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(lz4.NewReader(r)), nil
+		},
+		newWriter: func(w io.Writer, _ int) (io.WriteCloser, error) {
+			return lz4.NewWriter(w), nil
+		},
+	})
+}
@@ -0,0 +1,9 @@
+//go:build lz4
+
+package seqhash
+
+import "testing"
+
+func TestLZ4RoundTrip(t *testing.T) {
+	roundTripCodec(t, "out.fasta.lz4", "lz4")
+}
@@ -0,0 +1,50 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+	"math"
+)
+
+// Default probability above which collision risks are reported (--collision-warn-threshold)
+const defaultCollisionThreshold = 1e-6
+
+// collisionProbability estimates the probability that any two of n digests of the given bit width
+// are equal (birthday bound, assuming uniformly distributed digests): 1 - exp(-n(n-1) / 2^(bits+1))
+func collisionProbability(n int64, bits int) float64 {
+	if n < 2 {
+		return 0
+	}
+	pairs := float64(n) * float64(n-1) / 2
+	return -math.Expm1(-pairs / math.Exp2(float64(bits)))
+}
+
+// collisionWarnings reports the hash types whose collision probability for n digests
+// exceeds the threshold (--warn-on-short-hash-collision-risk). All digests are counted,
+// so identical sequences make the estimate an upper bound.
+func collisionWarnings(cfg Config, n int64) []string {
+	var warnings []string
+	for i, hashType := range cfg.HashTypes {
+		if i == 0 && cfg.MinimalUniquePrefix {
+			continue // Prefixes are unique within the input by construction
+		}
+		if cfg.AutoHash {
+			hashType = autoHashLong // The shorter digests
+		}
+		algorithm, ok := hashAlgorithms[hashType]
+		if !ok {
+			continue
+		}
+		bits := algorithm.width * 4 // Hex digits
+		if p := collisionProbability(n, bits); p > cfg.CollisionThreshold {
+			warnings = append(warnings, fmt.Sprintf(
+				"Warning: estimated probability of a %s collision among %d digests is %.3g (%d-bit hash, above the threshold of %g); consider a longer hash, e.g., sha1 or blake3",
+				hashType, n, p, bits, cfg.CollisionThreshold))
+		}
+	}
+	return warnings
+}
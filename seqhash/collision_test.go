@@ -0,0 +1,75 @@
+package seqhash
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCollisionProbability(t *testing.T) {
+	tests := []struct {
+		n        int64
+		bits     int
+		expected float64
+	}{
+		{0, 32, 0},
+		{1, 32, 0},
+		{77163, 32, 0.5},      // Classic birthday bound for 32-bit hashes
+		{1 << 16, 32, 0.3935}, // 1 - exp(-1/2)
+		{5_000_000_000, 64, 0.4920},
+	}
+	for _, tt := range tests {
+		if got := collisionProbability(tt.n, tt.bits); math.Abs(got-tt.expected) > 1e-3 {
+			t.Errorf("collisionProbability(%d, %d) = %.4f, want %.4f", tt.n, tt.bits, got, tt.expected)
+		}
+	}
+	// Tiny probabilities keep their precision
+	if got := collisionProbability(1000, 160); got <= 0 || got > 1e-40 {
+		t.Errorf("Expected a tiny positive probability for sha1, got %g", got)
+	}
+}
+
+func TestCollisionWarnings(t *testing.T) {
+	cfg := Config{HashTypes: []string{"sha1", "xxhash", "nthash"}, CollisionThreshold: defaultCollisionThreshold}
+
+	// A CRC32-sized (32-bit) hash is at risk with a million records
+	if p := collisionProbability(1_000_000, 32); p < 0.99 {
+		t.Errorf("Expected a near-certain 32-bit collision for 1e6 records, got %g", p)
+	}
+
+	warnings := collisionWarnings(cfg, 1_000_000)
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings for 64-bit hashes and 1e6 records, got %v", warnings)
+	}
+
+	warnings = collisionWarnings(cfg, 10_000_000_000)
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "xxhash collision") || !strings.Contains(warnings[1], "nthash collision") {
+		t.Fatalf("Expected warnings for xxhash and nthash, got %v", warnings)
+	}
+	if !strings.Contains(warnings[0], "(64-bit hash, above the threshold of 1e-06)") {
+		t.Errorf("Unexpected warning: %s", warnings[0])
+	}
+
+	// Unique prefixes can't collide within the input
+	cfg.HashTypes, cfg.MinimalUniquePrefix = []string{"xxhash"}, true
+	if warnings := collisionWarnings(cfg, 10_000_000_000); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for unique prefixes, got %v", warnings)
+	}
+}
+
+func TestCollisionWarningRun(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	cfg := Config{HashTypes: []string{"xxhash"}, CollisionWarn: true, CollisionThreshold: 1e-30}
+	if err := processSequences(strings.NewReader(testSequences), &bytes.Buffer{}, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	if !strings.Contains(logs.String(), "xxhash collision among 3 digests") {
+		t.Errorf("Expected a collision warning, got logs:\n%s", logs.String())
+	}
+}
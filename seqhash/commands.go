@@ -0,0 +1,146 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Command read from stdin in the coprocess mode (--stdin-commands), one JSON object per line:
+//
+//	{"op":"hash","input":"in.fasta","output":"out.fasta","options":{"hash":"sha1,xxhash","dedup":true}}
+//	{"op":"ping"}
+//	{"op":"shutdown"}
+//
+// Options are the long command-line options without dashes (values may be strings, numbers, or booleans).
+type command struct {
+	ID      json.RawMessage            `json:"id,omitempty"` // Echoed in the result
+	Op      string                     `json:"op"`
+	Input   string                     `json:"input"`
+	Output  string                     `json:"output"`
+	Options map[string]json.RawMessage `json:"options"`
+}
+
+// Result of a command, written to stdout as a single JSON line
+type commandResult struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Op     string          `json:"op"`
+	Status string          `json:"status"` // "ok" or "error"
+	Stats  *commandStats   `json:"stats,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type commandStats struct {
+	Records  int64 `json:"records"`
+	Bases    int64 `json:"bases"`
+	Excluded int64 `json:"excluded,omitempty"`
+}
+
+// runCommands executes the commands read from r one by one, writing a result line for each to w.
+// Errors of a command (including malformed commands) are reported in its result;
+// the loop ends with a shutdown command or at the end of the input.
+func runCommands(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var cmd command
+		result := commandResult{Status: "ok"}
+		if err := json.Unmarshal(line, &cmd); err != nil {
+			result.Status, result.Error = "error", fmt.Sprintf("Invalid command: %v", err)
+		} else {
+			result.ID, result.Op = cmd.ID, cmd.Op
+			switch cmd.Op {
+			case "ping":
+			case "shutdown":
+			case "hash":
+				stats, err := runHashCommand(cmd)
+				if err != nil {
+					result.Status, result.Error = "error", err.Error()
+				}
+				result.Stats = &commandStats{Records: stats.Records, Bases: stats.Bases, Excluded: stats.Excluded}
+			default:
+				result.Status, result.Error = "error", fmt.Sprintf("Unknown operation: %q (supported: hash, ping, shutdown)", cmd.Op)
+			}
+		}
+
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("Error writing command result: %v", err)
+		}
+		if cmd.Op == "shutdown" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error reading commands: %v", err)
+	}
+	return nil
+}
+
+// runHashCommand validates the options of a hash command, as on the command line, and runs it
+func runHashCommand(cmd command) (Stats, error) {
+	if cmd.Input == "" || cmd.Input == "-" {
+		return Stats{}, fmt.Errorf("A hash command needs an input file (stdin carries the commands)")
+	}
+	if cmd.Output == "" || cmd.Output == "-" {
+		return Stats{}, fmt.Errorf("A hash command needs an output file (stdout carries the results)")
+	}
+
+	args, err := commandArgs(cmd.Options)
+	if err != nil {
+		return Stats{}, err
+	}
+	fs := flag.NewFlagSet("seqhasher", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg, err := parseArgs(fs, append(args, cmd.Input, cmd.Output))
+	if err != nil {
+		return Stats{}, err
+	}
+	switch {
+	case cfg.StdinCommands, cfg.ShowVersion, cfg.ExplainOutput, cfg.Compare, cfg.OutputDir != "":
+		return Stats{}, fmt.Errorf("Options of other modes (stdin-commands, version, explain-output, compare, output-dir) can't be used in commands")
+	}
+	return processFile(io.Discard, cfg)
+}
+
+// commandArgs converts the options of a command to command-line arguments ("--name=value")
+func commandArgs(options map[string]json.RawMessage) ([]string, error) {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Deterministic error messages
+
+	args := make([]string, 0, len(options))
+	for _, name := range names {
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(options[name]))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("Invalid value of option %q: %v", name, err)
+		}
+		switch v := value.(type) {
+		case string, bool, json.Number:
+			args = append(args, fmt.Sprintf("--%s=%v", name, v))
+		default:
+			return nil, fmt.Errorf("Invalid value of option %q: must be a string, number, or boolean", name)
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,104 @@
+package seqhash
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStdinCommands(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(tmpDir, "out.fasta")
+	missing := filepath.Join(tmpDir, "missing.fasta")
+
+	session := strings.Join([]string{
+		`{"op":"ping","id":1}`,
+		`{"op":"hash","id":2,"input":"` + input + `","output":"` + output + `","options":{"hash":"xxhash","nofilename":true,"dedup":true}}`,
+		`{"op":"hash","id":3,"input":"` + input + `","output":"` + output + `","options":{"hash":"crc32"}}`,
+		`{"op":"hash","id":4,"input":"` + missing + `","output":"` + filepath.Join(tmpDir, "out2.fasta") + `"}`,
+		`{"op":"hash","id":5,"input":"` + input + `"}`,
+		`not json`,
+		``,
+		`{"op":"rehash","id":6}`,
+		`{"op":"shutdown","id":7}`,
+		`{"op":"ping","id":8}`,
+	}, "\n") + "\n"
+
+	stdout := &bytes.Buffer{}
+	if err := runCommands(strings.NewReader(session), stdout); err != nil {
+		t.Fatalf("runCommands() error = %v", err)
+	}
+
+	var results []commandResult
+	for _, line := range strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n") {
+		var r commandResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Result is not a JSON line: %q", line)
+		}
+		results = append(results, r)
+	}
+	if len(results) != 8 {
+		t.Fatalf("Expected 8 results (none after shutdown), got %d:\n%s", len(results), stdout)
+	}
+
+	checks := []struct {
+		id     string
+		status string
+		error  string
+	}{
+		{"1", "ok", ""},
+		{"2", "ok", ""},
+		{"3", "error", "Invalid hash type: crc32. Supported types are: " + strings.Join(supportedHashTypes, ", ")},
+		{"4", "error", "Error opening input: open " + missing + ": no such file or directory"},
+		{"5", "error", "A hash command needs an output file (stdout carries the results)"},
+		{"", "error", "Invalid command: invalid character 'o' in literal null (expecting 'u')"},
+		{"6", "error", `Unknown operation: "rehash" (supported: hash, ping, shutdown)`},
+		{"7", "ok", ""},
+	}
+	for i, c := range checks {
+		r := results[i]
+		if string(r.ID) != c.id || r.Status != c.status || r.Error != c.error {
+			t.Errorf("Result %d: got id %s, status %q, error %q; want id %s, status %q, error %q",
+				i, r.ID, r.Status, r.Error, c.id, c.status, c.error)
+		}
+	}
+
+	// The data output goes only to the named file; seq1 and its lowercase copy collapse
+	if stats := results[1].Stats; stats == nil || stats.Records != 3 {
+		t.Errorf("Expected stats of 3 records, got %+v", results[1].Stats)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), ">") != 2 || !strings.HasPrefix(string(data), ">") {
+		t.Errorf("Unexpected output file:\n%s", data)
+	}
+	if strings.Contains(stdout.String(), "ACTG") {
+		t.Errorf("Sequences must not be written to stdout:\n%s", stdout)
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	args, err := commandArgs(map[string]json.RawMessage{
+		"hash":    json.RawMessage(`"sha1,md5"`),
+		"threads": json.RawMessage(`4`),
+		"dedup":   json.RawMessage(`true`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(args, " "); got != "--dedup=true --hash=sha1,md5 --threads=4" {
+		t.Errorf("Unexpected arguments: %s", got)
+	}
+	if _, err := commandArgs(map[string]json.RawMessage{"hash": json.RawMessage(`["sha1"]`)}); err == nil {
+		t.Error("Expected an error for a list value")
+	}
+}
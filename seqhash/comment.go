@@ -0,0 +1,79 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bytes"
+)
+
+// splitComment separates the ID of a header from its comment,
+// returning the comment together with the separator (a space or a tab)
+// so that it can be written back verbatim
+func splitComment(name []byte) (id, tail []byte) {
+	if i := bytes.IndexAny(name, " \t"); i >= 0 {
+		return name[:i], name[i:]
+	}
+	return name, nil
+}
+
+// readDescriptor returns the read number and the barcode (index sequence) of a read.
+// They are taken from a CASAVA 1.8+ comment ("1:N:0:ACGTACGT", read:filtered:control:index),
+// or from an old-style Illumina ID ("HWUSI-EAS100R:6:73:941:1973#ACGT/1").
+// Both values are empty if the read carries no descriptor (malformed comments are ignored).
+func readDescriptor(id, comment []byte) (read, barcode string) {
+	if read, barcode, ok := parseCasavaComment(comment); ok {
+		return read, barcode
+	}
+
+	slash := bytes.LastIndexByte(id, '/')
+	if slash < 0 || slash == len(id)-1 || !isDigits(id[slash+1:]) {
+		return "", ""
+	}
+	read = string(id[slash+1:])
+	if hash := bytes.LastIndexByte(id[:slash], '#'); hash >= 0 {
+		barcode = string(id[hash+1 : slash])
+	}
+	return read, barcode
+}
+
+// illuminaReadID returns the part of an Illumina read ID that the mates of a pair share (--illumina-id):
+// the "/1" or "/2" suffix of an old-style ID ("HWUSI-EAS100R:6:73:941:1973#0/1") is removed.
+// CASAVA 1.8+ IDs ("<instrument>:<run>:<flowcell>:<lane>:<tile>:<x>:<y>") are already shared,
+// as the read number is in the comment. Trailing ";key=value" annotations are kept.
+func illuminaReadID(id []byte) []byte {
+	var notes annotations
+	bare := notes.trim(id)
+	slash := bytes.LastIndexByte(bare, '/')
+	if slash < 0 || !isDigits(bare[slash+1:]) {
+		return id
+	}
+	return append(bytes.Clone(bare[:slash]), id[len(bare):]...)
+}
+
+// parseCasavaComment parses the first token of a CASAVA 1.8+ comment
+func parseCasavaComment(comment []byte) (read, barcode string, ok bool) {
+	token, _ := splitComment(comment)
+	parts := bytes.Split(token, []byte(":"))
+	if len(parts) != 4 ||
+		!isDigits(parts[0]) ||
+		!(string(parts[1]) == "Y" || string(parts[1]) == "N") ||
+		!isDigits(parts[2]) {
+		return "", "", false
+	}
+	return string(parts[0]), string(parts[3]), true
+}
+
+func isDigits(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package seqhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadDescriptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		read    string
+		barcode string
+	}{
+		{"CASAVA 1.8+", "M00123:45:000000000-ABCDE:1:1101:15589:1333 1:N:0:ACGTACGT", "1", "ACGTACGT"},
+		{"CASAVA 1.8+ dual index", "A00123:8:H3:2:1101:1:1 2:Y:0:ACGT+TTGA", "2", "ACGT+TTGA"},
+		{"CASAVA 1.8+ sample number", "r1\t1:N:0:2 extra", "1", "2"},
+		{"Old-style", "HWUSI-EAS100R:6:73:941:1973#0/1", "1", "0"},
+		{"Old-style without barcode", "read7/2", "2", ""},
+		{"Comment-free", "read7", "", ""},
+		{"Malformed comment", "read7 1:X:0:ACGT", "", ""},
+		{"Free-text description", "seq1 sample A", "", ""},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			r := newHashedRecord("", nil, []byte(tt.header))
+			read, barcode := readDescriptor(r.id, r.comment())
+			if read != tt.read || barcode != tt.barcode {
+				t.Errorf("readDescriptor() = (%q, %q), want (%q, %q)", read, barcode, tt.read, tt.barcode)
+			}
+		})
+	}
+}
+
+func TestIlluminaReadID(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected string
+	}{
+		{"CASAVA 1.8+", "A00123:8:H3KJ2DSXY:2:1101:15589:1333", "A00123:8:H3KJ2DSXY:2:1101:15589:1333"},
+		{"Old-style", "HWUSI-EAS100R:6:73:941:1973#0/1", "HWUSI-EAS100R:6:73:941:1973#0"},
+		{"Old-style second read", "HWUSI-EAS100R:6:73:941:1973#0/2", "HWUSI-EAS100R:6:73:941:1973#0"},
+		{"Annotated", "read7/2;size=3", "read7;size=3"},
+		{"Non-numeric suffix", "sample/A", "sample/A"},
+		{"Trailing slash", "read7/", "read7/"},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			if id := illuminaReadID([]byte(tt.id)); string(id) != tt.expected {
+				t.Errorf("illuminaReadID(%q) = %q, want %q", tt.id, id, tt.expected)
+			}
+		})
+	}
+
+	runTest(t, "Paired reads share the ID", func(t *testing.T) {
+		const digest = "65c89f59d38cdbf90dfaf0b0a6884829df8396b0"
+		pairs := []struct {
+			r1, r2 string
+			id     string
+		}{
+			{"A00123:8:H3KJ2DSXY:2:1101:15589:1333 1:N:0:ACGTACGT", "A00123:8:H3KJ2DSXY:2:1101:15589:1333 2:N:0:ACGTACGT", "A00123:8:H3KJ2DSXY:2:1101:15589:1333"},
+			{"HWUSI-EAS100R:6:73:941:1973#0/1", "HWUSI-EAS100R:6:73:941:1973#0/2", "HWUSI-EAS100R:6:73:941:1973#0"},
+		}
+		for _, pair := range pairs {
+			input := "@" + pair.r1 + "\nACTG\n+\nIIII\n@" + pair.r2 + "\nACTG\n+\nIIII\n"
+			output := &bytes.Buffer{}
+			// --illumina-id implies --drop-comment
+			cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, HeadersOnly: true, IlluminaID: true, DropComment: true}
+			if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			header := digest + ";" + pair.id + "\n"
+			if output.String() != header+header {
+				t.Errorf("Got headers %q, want %q twice", output, header)
+			}
+		}
+	})
+}
+
+func TestCommentHandling(t *testing.T) {
+	const digest = "65c89f59d38cdbf90dfaf0b0a6884829df8396b0"
+	fastq := "@r1 1:N:0:ACGTACGT\nACTG\n+\nIIII\n@r2/2\nACTG\n+\nIIII\n@r3\tmalformed:comment\nACTG\n+\nIIII\n"
+	fasta := ">r1 1:N:0:ACGTACGT\nACTG\n>r2/2\nACTG\n>r3\tmalformed:comment\nACTG\n"
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected []string
+	}{
+		{
+			name:     "Comment kept by default",
+			cfg:      Config{},
+			expected: []string{digest + ";r1 1:N:0:ACGTACGT", digest + ";r2/2", digest + ";r3\tmalformed:comment"},
+		},
+		{
+			name:     "Dropped comment",
+			cfg:      Config{DropComment: true},
+			expected: []string{digest + ";r1", digest + ";r2/2", digest + ";r3"},
+		},
+		{
+			name:     "Template placeholders",
+			cfg:      Config{HeaderFormat: "{id}|read={read}|bc={barcode}|{sha1}"},
+			expected: []string{"r1|read=1|bc=ACGTACGT|" + digest + " 1:N:0:ACGTACGT", "r2/2|read=2|bc=|" + digest, "r3|read=|bc=|" + digest + "\tmalformed:comment"},
+		},
+		{
+			name:     "Comment placed by template",
+			cfg:      Config{HeaderFormat: "{sha1} {comment}"},
+			expected: []string{digest + " 1:N:0:ACGTACGT", digest + " ", digest + " malformed:comment"},
+		},
+		{
+			name:     "Seqkit-compatible header",
+			cfg:      Config{SeqkitCompat: true, DropComment: true},
+			expected: []string{"r1;sha1=" + digest + ";", "r2/2;sha1=" + digest + ";", "r3;sha1=" + digest + ";"},
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1"}
+			cfg.NoFileName = true
+			cfg.HeadersOnly = true
+
+			// FASTA and FASTQ headers are rewritten the same way
+			for _, input := range []string{fastq, fasta} {
+				output := &bytes.Buffer{}
+				if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+					t.Fatalf("processSequences() error = %v", err)
+				}
+				headers := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+				if strings.Join(headers, "\n") != strings.Join(tt.expected, "\n") {
+					t.Errorf("Got headers:\n%q\nWant:\n%q", headers, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestCommentColumns(t *testing.T) {
+	cfg := Config{HashTypes: []string{"md5"}, OutFormat: "tsv", NoFileName: true, NoMetadata: true}
+	output := &bytes.Buffer{}
+	input := "@r1 1:N:0:ACGTACGT\nACTG\n+\nIIII\n@HWUSI:6:73:941:1973#ACGT/2\nACTG\n+\nIIII\n"
+	if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	md5 := getHashFunc("md5")([]byte("ACTG"))
+	expected := "md5\tid\tcomment\tread\tbarcode\n" +
+		md5 + "\tr1\t1:N:0:ACGTACGT\t1\tACGTACGT\n" +
+		md5 + "\tHWUSI:6:73:941:1973#ACGT/2\t\t2\tACGT\n"
+	if output.String() != expected {
+		t.Errorf("Got:\n%s\nWant:\n%s", output.String(), expected)
+	}
+}
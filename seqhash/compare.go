@@ -0,0 +1,98 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+	"io"
+)
+
+// Result of comparing the sequence sets of two files
+type comparison struct {
+	uniqueToA int
+	uniqueToB int
+	shared    int
+}
+
+// compareFiles prints the number of distinct sequences (by the first hash type)
+// that are unique to file A, unique to file B, and shared between them
+func compareFiles(w io.Writer, fileA, fileB string, cfg Config) error {
+	if fileA == "" || fileB == "" || fileA == "-" && fileB == "-" {
+		return fmt.Errorf("Comparison requires two input files")
+	}
+
+	setA, err := hashSetFromFile(fileA, cfg)
+	if err != nil {
+		return err
+	}
+	setB, err := hashSetFromFile(fileB, cfg)
+	if err != nil {
+		return err
+	}
+
+	c := compareHashSets(setA, setB)
+	fmt.Fprintf(w, "unique_to_A\t%d\n", c.uniqueToA)
+	fmt.Fprintf(w, "unique_to_B\t%d\n", c.uniqueToB)
+	fmt.Fprintf(w, "shared\t%d\n", c.shared)
+	return nil
+}
+
+func hashSetFromFile(fileName string, cfg Config) (map[string]struct{}, error) {
+	input, err := getInput(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening input: %v", err)
+	}
+	defer input.Close()
+
+	set, err := hashSet(input, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %v", fileName, err)
+	}
+	return set, nil
+}
+
+// hashSet returns the set of hashes of all sequences in the input
+func hashSet(input io.Reader, cfg Config) (map[string]struct{}, error) {
+	set := make(map[string]struct{})
+	reader, err := newFastxReader(input)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create reader: %v", err)
+	}
+	if reader == nil { // Empty input
+		return set, nil
+	}
+	defer reader.Close()
+
+	hashFunc := getHashFunc(cfg.HashTypes[0])
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("Error reading record: %v", err)
+		}
+		seq := normalizeSequence(record.Seq.Seq, cfg)
+		if cfg.BothStrands {
+			seq = bothStrands(seq)
+		}
+		set[hashFunc(seq)] = struct{}{}
+	}
+	return set, nil
+}
+
+func compareHashSets(setA, setB map[string]struct{}) comparison {
+	var c comparison
+	for hash := range setA {
+		if _, ok := setB[hash]; ok {
+			c.shared++
+		} else {
+			c.uniqueToA++
+		}
+	}
+	c.uniqueToB = len(setB) - c.shared
+	return c
+}
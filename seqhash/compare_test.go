@@ -0,0 +1,83 @@
+package seqhash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	fileA := filepath.Join(tmpDir, "a.fasta")
+	fileB := filepath.Join(tmpDir, "b.fasta")
+	fileC := filepath.Join(tmpDir, "c.fasta")
+
+	// A: ACTG (twice, once lowercase), TGCA, AAAA
+	// B: ACTG, CCCC, GGGG
+	if err := os.WriteFile(fileA, []byte(">a1\nACTG\n>a2\nactg\n>a3\nTGCA\n>a4\nAAAA\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fileB, []byte(">b1\nACTG\n>b2\nCCCC\n>b3\nGGGG\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fileC, []byte(">c1\nTTTT\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		a, b     string
+		expected string
+	}{
+		{
+			name:     "Overlapping",
+			cfg:      Config{HashTypes: []string{"sha1"}},
+			a:        fileA,
+			b:        fileB,
+			expected: "unique_to_A\t2\nunique_to_B\t2\nshared\t1\n",
+		},
+		{
+			name:     "Case-sensitive",
+			cfg:      Config{HashTypes: []string{"sha1"}, CaseSensitive: true},
+			a:        fileA,
+			b:        fileB,
+			expected: "unique_to_A\t3\nunique_to_B\t2\nshared\t1\n",
+		},
+		{
+			name:     "Disjoint",
+			cfg:      Config{HashTypes: []string{"xxhash"}},
+			a:        testFastaPath,
+			b:        fileC,
+			expected: "unique_to_A\t2\nunique_to_B\t1\nshared\t0\n",
+		},
+		{
+			name:     "Identical",
+			cfg:      Config{HashTypes: []string{"md5"}},
+			a:        fileB,
+			b:        fileB,
+			expected: "unique_to_A\t0\nunique_to_B\t0\nshared\t3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := compareFiles(&buf, tt.a, tt.b, tt.cfg); err != nil {
+				t.Fatalf("compareFiles() error = %v", err)
+			}
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("compareFiles() output:\n%s\nWant:\n%s", got, tt.expected)
+			}
+		})
+	}
+
+	runTest(t, "Missing second file", func(t *testing.T) {
+		err := compareFiles(&bytes.Buffer{}, fileA, "", Config{HashTypes: []string{"sha1"}})
+		if err == nil || !strings.Contains(err.Error(), "two input files") {
+			t.Errorf("Expected an error about two input files, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const defaultXZLevel = 6 // Default compression level of the xz tool
+
+// supportedCompressions returns the output compression methods of the build
+// (an empty value of --compress selects the method by file extension)
+func supportedCompressions() []string {
+	return append([]string{"none"}, codecNames(true)...)
+}
+
+// Dictionary sizes of the xz presets (-0 ... -9)
+var xzDictSizes = []int{
+	256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20,
+	8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20,
+}
+
+// compressionFromName returns the compression method implied by the file extension.
+// Extensions of optional codecs that were not compiled in are recognized as well,
+// so that the output fails with the build tag to use rather than being left uncompressed.
+func compressionFromName(fileName string) string {
+	for _, c := range codecs {
+		if c.newWriter == nil && c.compiledIn() {
+			continue // Read-only codecs (gzip, zstd)
+		}
+		for _, ext := range c.extensions {
+			if strings.HasSuffix(fileName, ext) {
+				return c.name
+			}
+		}
+	}
+	return "none"
+}
+
+// outputCompression returns the compression method for the output
+func outputCompression(cfg Config) string {
+	if cfg.Compress != "" {
+		return cfg.Compress
+	}
+	return compressionFromName(cfg.OutputFileName)
+}
+
+// newCompressor wraps w into a compressing writer.
+// Closing it finalizes the compressed stream, but does not close w.
+func newCompressor(w io.Writer, method string, level int) (io.WriteCloser, error) {
+	if method == "" || method == "none" {
+		return nopWriteCloser{w}, nil
+	}
+	c := findCodec(method)
+	switch {
+	case c == nil:
+	case !c.compiledIn():
+		return nil, c.errNotCompiledIn()
+	case c.newWriter != nil:
+		return c.newWriter(w, level)
+	}
+	return nil, fmt.Errorf("Unsupported compression: %s", method)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// compressedFile closes both the compressor and the underlying file
+type compressedFile struct {
+	io.WriteCloser
+	file *os.File
+}
+
+func (cf *compressedFile) Close() error {
+	err := cf.WriteCloser.Close()
+	if ferr := cf.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// getCompressedOutput creates the output file, compressed with the given method
+func getCompressedOutput(fileName, method string, level int) (io.WriteCloser, error) {
+	if fileName == "" || fileName == "-" {
+		if method == "" || method == "none" {
+			return os.Stdout, nil
+		}
+		return newCompressor(os.Stdout, method, level)
+	}
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if method == "" || method == "none" {
+		return file, nil
+	}
+	compressor, err := newCompressor(file, method, level)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &compressedFile{WriteCloser: compressor, file: file}, nil
+}
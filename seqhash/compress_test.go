@@ -0,0 +1,183 @@
+package seqhash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompressedRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		method string
+		level  int
+		codec  string
+	}{
+		{"xz by extension", "out.fasta.xz", "", defaultXZLevel, "xz"},
+		{"xz explicit method", "out.fasta", "xz", 0, "xz"},
+		{"xz highest level", "out.fasta.xz", "xz", 9, "xz"},
+		{"bzip2 by extension", "out.fasta.bz2", "", defaultXZLevel, "bzip2"},
+		{"bzip2 explicit method", "out.fasta", "bzip2", defaultXZLevel, "bzip2"},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, Compress: tt.method, XZLevel: tt.level}
+			outputFile := filepath.Join(t.TempDir(), tt.file)
+			cfg.OutputFileName = outputFile
+
+			output, err := getCompressedOutput(outputFile, outputCompression(cfg), cfg.XZLevel)
+			if err != nil {
+				t.Fatalf("getCompressedOutput() error = %v", err)
+			}
+			if err := processSequences(strings.NewReader(testSequences), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if err := output.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			raw, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if codec := detectCodec(raw); codec != tt.codec {
+				t.Fatalf("Output is compressed with %s, want %s", codec, tt.codec)
+			}
+
+			input, err := getInput(outputFile)
+			if err != nil {
+				t.Fatalf("getInput() error = %v", err)
+			}
+			defer input.Close()
+
+			// Hashes are stable, so re-hashing the decompressed output must yield the same records
+			expected := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(testSequences), expected, Config{HashTypes: []string{"sha1"}, NoFileName: true}); err != nil {
+				t.Fatal(err)
+			}
+			var decompressed bytes.Buffer
+			if _, err := decompressed.ReadFrom(input); err != nil {
+				t.Fatalf("Error reading compressed output: %v", err)
+			}
+			if decompressed.String() != expected.String() {
+				t.Errorf("Round-trip mismatch:\nGot:\n%s\nExpected:\n%s", decompressed.String(), expected.String())
+			}
+		})
+	}
+}
+
+func TestCompressionFromName(t *testing.T) {
+	tests := map[string]string{
+		"out.fasta.xz": "xz",
+		"out.fa.bz2":   "bzip2",
+		"out.fasta":    "none",
+		"-":            "none",
+		"":             "none",
+	}
+	for name, expected := range tests {
+		if got := compressionFromName(name); got != expected {
+			t.Errorf("compressionFromName(%q) = %q, want %q", name, got, expected)
+		}
+	}
+}
+
+func TestXZOutputRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(tmpDir, "out.txt")
+
+	if _, err := runWithArgs([]string{"seqhasher", "--compress", "xz", "--xz-level", "1", input, output}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	raw, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}) {
+		t.Errorf("Expected xz output with --compress xz, got %q", raw[:min(len(raw), 8)])
+	}
+}
+
+// roundTripCodec compresses the output by the extension of fileName
+// and checks that reading it back yields the records of testSequences
+func roundTripCodec(t *testing.T, fileName, codec string) {
+	t.Helper()
+	outputFile := filepath.Join(t.TempDir(), fileName)
+	if got := compressionFromName(outputFile); got != codec {
+		t.Fatalf("compressionFromName(%q) = %q, want %q", fileName, got, codec)
+	}
+	cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true}
+	output, err := getOutput(outputFile)
+	if err != nil {
+		t.Fatalf("getOutput() error = %v", err)
+	}
+	if err := processSequences(strings.NewReader(testSequences), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	expected := &bytes.Buffer{}
+	if err := processSequences(strings.NewReader(testSequences), expected, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if raw, err := os.ReadFile(outputFile); err != nil {
+		t.Fatal(err)
+	} else if bytes.Equal(raw, expected.Bytes()) {
+		t.Fatalf("Output %s is not compressed", fileName)
+	}
+
+	report := inspectFile(outputFile, 1<<20)
+	if report.Codec != codec || report.Format != formatFASTA {
+		t.Errorf("Inspected as %s (%s), want %s (%s)", report.Codec, report.Format, codec, formatFASTA)
+	}
+	input, err := getInput(outputFile)
+	if err != nil {
+		t.Fatalf("getInput() error = %v", err)
+	}
+	defer input.Close()
+	var decompressed bytes.Buffer
+	if _, err := decompressed.ReadFrom(input); err != nil {
+		t.Fatalf("Error reading compressed output: %v", err)
+	}
+	if decompressed.String() != expected.String() {
+		t.Errorf("Round-trip mismatch:\nGot:\n%s\nExpected:\n%s", decompressed.String(), expected.String())
+	}
+}
+
+// Optional codecs missing from the build are reported with their build tag
+func TestCodecNotCompiledIn(t *testing.T) {
+	for _, c := range codecs {
+		if c.tag == "" || c.compiledIn() {
+			continue
+		}
+		runTest(t, c.name, func(t *testing.T) {
+			want := c.name + " codec not compiled in (rebuild with -tags " + c.tag + ")"
+			output := filepath.Join(t.TempDir(), "out.fasta"+c.extensions[0])
+			if _, err := getOutput(output); err == nil || err.Error() != want {
+				t.Errorf("Expected %q for output %s, got %v", want, output, err)
+			}
+
+			input := output
+			data := append(append([]byte{}, c.magic...), "not really compressed"...)
+			if err := os.WriteFile(input, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := getInput(input); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected %q for input %s, got %v", want, input, err)
+			}
+			if slices.Contains(supportedCompressions(), c.name) {
+				t.Errorf("Expected %s not to be listed in the supported compressions", c.name)
+			}
+		})
+	}
+}
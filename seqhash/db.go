@@ -0,0 +1,425 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Version of the digest database layout; databases of other versions are refused
+const dbFormatVersion = 1
+
+var (
+	dbMetaBucket    = []byte("meta")
+	dbDigestsBucket = []byte("digests")
+	dbVersionKey    = []byte("format_version")
+	dbHashTypeKey   = []byte("hash_type")
+)
+
+// How `db query` treats the records of the input
+var supportedQueryModes = []string{"annotate", "known", "new"}
+
+// First-seen metadata of a digest
+type dbEntry struct {
+	File string `json:"file"`
+	ID   string `json:"id"`
+	Date string `json:"date"`
+}
+
+// digestDB is a persistent set of sequence digests
+type digestDB struct {
+	bolt     *bolt.DB
+	hashType string
+	lockPath string // empty for read-only databases
+}
+
+// openDigestDB opens (or, if writable, creates) the database.
+// Writers hold a lock file next to the database for as long as it is open,
+// so that concurrent modifications fail with a clear error instead of waiting.
+func openDigestDB(path, hashType string, writable bool) (*digestDB, error) {
+	d := &digestDB{}
+	if writable {
+		d.lockPath = path + ".lock"
+		lock, err := os.OpenFile(d.lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return nil, fmt.Errorf("Database %s is locked by another process (lock file %s exists; remove it if no other seqhasher is running)", path, d.lockPath)
+			}
+			return nil, fmt.Errorf("Error creating lock file: %v", err)
+		}
+		fmt.Fprintf(lock, "%d\n", os.Getpid())
+		lock.Close()
+	} else if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("Error opening database: %v", err)
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second, ReadOnly: !writable})
+	if err != nil {
+		d.unlock()
+		return nil, fmt.Errorf("Error opening database %s: %v", path, err)
+	}
+	d.bolt = db
+
+	if writable {
+		err = db.Update(func(tx *bolt.Tx) error { return initDigestDB(tx, hashType) })
+	}
+	if err == nil {
+		err = db.View(func(tx *bolt.Tx) error {
+			var verr error
+			d.hashType, verr = checkDigestDB(tx, hashType)
+			return verr
+		})
+	}
+	if err != nil {
+		d.Close()
+		return nil, fmt.Errorf("Database %s: %v", path, err)
+	}
+	return d, nil
+}
+
+// initDigestDB creates the buckets and the format metadata of a new database
+func initDigestDB(tx *bolt.Tx, hashType string) error {
+	meta, err := tx.CreateBucketIfNotExists(dbMetaBucket)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.CreateBucketIfNotExists(dbDigestsBucket); err != nil {
+		return err
+	}
+	if meta.Get(dbVersionKey) != nil {
+		return nil
+	}
+	if hashType == "" {
+		hashType = defaultHashType
+	}
+	if err := meta.Put(dbVersionKey, []byte(strconv.Itoa(dbFormatVersion))); err != nil {
+		return err
+	}
+	return meta.Put(dbHashTypeKey, []byte(hashType))
+}
+
+// checkDigestDB verifies the format version and returns the hash type of the database
+func checkDigestDB(tx *bolt.Tx, hashType string) (string, error) {
+	meta := tx.Bucket(dbMetaBucket)
+	if meta == nil || tx.Bucket(dbDigestsBucket) == nil {
+		return "", fmt.Errorf("not a seqhasher digest database")
+	}
+	if version := string(meta.Get(dbVersionKey)); version != strconv.Itoa(dbFormatVersion) {
+		return "", fmt.Errorf("unsupported format version %s (this seqhasher supports version %d)", version, dbFormatVersion)
+	}
+	dbHashType := string(meta.Get(dbHashTypeKey))
+	if hashType != "" && hashType != dbHashType {
+		return "", fmt.Errorf("digests were computed with %s, not %s", dbHashType, hashType)
+	}
+	return dbHashType, nil
+}
+
+func (d *digestDB) unlock() {
+	if d.lockPath != "" {
+		os.Remove(d.lockPath)
+	}
+}
+
+func (d *digestDB) Close() error {
+	defer d.unlock()
+	if d.bolt == nil {
+		return nil
+	}
+	return d.bolt.Close()
+}
+
+// runDB implements the `seqhasher db <add|query|remove|stats>` subcommands
+func runDB(w io.Writer, args []string) error {
+	usage := "Usage: seqhasher db <add|query|remove|stats> --db <file> [options] [files...]"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	command := args[0]
+
+	fs := flag.NewFlagSet("db "+command, flag.ContinueOnError)
+	dbPath := fs.String("db", "", "Digest database file")
+	hashType := fs.String("hash", "", "Hash algorithm of a new database (default: sha1; existing databases keep theirs)")
+	caseSensitive := fs.Bool("casesensitive", false, "Case-sensitive hashing")
+	mode := fs.String("mode", "annotate", "db query: annotate records, or output only the known or the new ones ("+strings.Join(supportedQueryModes, ", ")+")")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *dbPath == "" {
+		return fmt.Errorf("The database file must be specified with --db")
+	}
+	if *hashType != "" && !isValidHashType(*hashType) {
+		return fmt.Errorf("Invalid hash type: %s. Supported types are: %s", *hashType, strings.Join(supportedHashTypes, ", "))
+	}
+	cfg := Config{CaseSensitive: *caseSensitive}
+
+	switch command {
+	case "add":
+		if fs.NArg() == 0 {
+			return fmt.Errorf("db add requires at least one input file")
+		}
+		d, err := openDigestDB(*dbPath, *hashType, true)
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+		cfg.HashTypes = []string{d.hashType}
+		return dbAdd(w, d, fs.Args(), cfg)
+
+	case "query":
+		if fs.NArg() < 1 || fs.NArg() > 2 {
+			return fmt.Errorf("db query requires an input file (and optionally an output file)")
+		}
+		if !isSupported(*mode, supportedQueryModes) {
+			return fmt.Errorf("Invalid query mode: %s. Supported modes are: %s", *mode, strings.Join(supportedQueryModes, ", "))
+		}
+		d, err := openDigestDB(*dbPath, *hashType, false)
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+		cfg.HashTypes = []string{d.hashType}
+		return dbQuery(w, d, fs.Arg(0), fs.Arg(1), *mode, cfg)
+
+	case "remove":
+		if fs.NArg() != 1 {
+			return fmt.Errorf("db remove requires a file with the digests to remove")
+		}
+		d, err := openDigestDB(*dbPath, *hashType, true)
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+		return dbRemove(w, d, fs.Arg(0))
+
+	case "stats":
+		d, err := openDigestDB(*dbPath, *hashType, false)
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+		return dbStats(w, d, *dbPath)
+	}
+	return fmt.Errorf("Unknown db command: %s\n%s", command, usage)
+}
+
+// forEachRecord calls fn with every record of the input and the digest of its sequence
+func forEachRecord(fileName string, cfg Config, fn func(record *fastx.Record, digest string) error) error {
+	input, err := getInput(fileName)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	defer input.Close()
+
+	reader, err := newFastxReader(input)
+	if err != nil {
+		return fmt.Errorf("Failed to create reader: %v", err)
+	}
+	if reader == nil { // Empty input
+		return nil
+	}
+	defer reader.Close()
+
+	hashFunc := getHashFunc(cfg.HashTypes[0])
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("Error reading %s: %v", fileName, err)
+		}
+		if !reader.IsFastq {
+			record.Seq.Qual = nil
+		}
+		if err := fn(record, hashFunc(normalizeSequence(record.Seq.Seq, cfg))); err != nil {
+			return err
+		}
+	}
+}
+
+// dbAdd inserts the digests of all inputs and reports the numbers of new and known sequences.
+// Each input is added in a single transaction, so a failed input leaves the database unchanged.
+func dbAdd(w io.Writer, d *digestDB, inputs []string, cfg Config) error {
+	date := time.Now().UTC().Format("2006-01-02")
+	var added, known, skipped int
+
+	for _, fileName := range inputs {
+		err := d.bolt.Update(func(tx *bolt.Tx) error {
+			digests := tx.Bucket(dbDigestsBucket)
+			return forEachRecord(fileName, cfg, func(record *fastx.Record, digest string) error {
+				if digest == "" {
+					skipped++ // Empty sequence or hash failure
+					return nil
+				}
+				if digests.Get([]byte(digest)) != nil {
+					known++
+					return nil
+				}
+				entry, err := json.Marshal(dbEntry{File: fileName, ID: string(record.ID), Date: date})
+				if err != nil {
+					return err
+				}
+				added++
+				return digests.Put([]byte(digest), entry)
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(w, "new\t%d\n", added)
+	fmt.Fprintf(w, "known\t%d\n", known)
+	if skipped > 0 {
+		fmt.Fprintf(w, "skipped\t%d\n", skipped)
+	}
+	return nil
+}
+
+// dbQuery writes the records of the input, annotated with their membership
+// (";db=known" or ";db=new" appended to the header), or filtered by it
+func dbQuery(w io.Writer, d *digestDB, inputFile, outputFile, mode string, cfg Config) (err error) {
+	output := w
+	if outputFile != "" && outputFile != "-" {
+		out, oerr := getOutput(outputFile)
+		if oerr != nil {
+			return fmt.Errorf("Error opening output: %v", oerr)
+		}
+		defer func() {
+			if cerr := out.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("Error closing output: %v", cerr)
+			}
+		}()
+		output = out
+	}
+	writer := bufio.NewWriter(output)
+
+	err = d.bolt.View(func(tx *bolt.Tx) error {
+		digests := tx.Bucket(dbDigestsBucket)
+		return forEachRecord(inputFile, cfg, func(record *fastx.Record, digest string) error {
+			status := "new"
+			if digest != "" && digests.Get([]byte(digest)) != nil {
+				status = "known"
+			}
+			switch mode {
+			case "annotate":
+				record.Name = append(record.Name, ";db="+status...)
+			case status:
+			default:
+				return nil
+			}
+			if _, err := writer.Write(record.Format(0)); err != nil {
+				return fmt.Errorf("Error writing record: %v", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// dbRemove deletes the digests listed in a file (the first column of each line,
+// so that index and tabular outputs of seqhasher can be used directly)
+func dbRemove(w io.Writer, d *digestDB, listFile string) error {
+	f, err := os.Open(listFile)
+	if err != nil {
+		return fmt.Errorf("Error opening digest list: %v", err)
+	}
+	defer f.Close()
+
+	var removed, missing int
+	err = d.bolt.Update(func(tx *bolt.Tx) error {
+		digests := tx.Bucket(dbDigestsBucket)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool { return r == '\t' || r == ',' || r == ' ' })
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			key := []byte(fields[0])
+			if digests.Get(key) == nil {
+				missing++
+				continue
+			}
+			if err := digests.Delete(key); err != nil {
+				return err
+			}
+			removed++
+		}
+		return scanner.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "removed\t%d\n", removed)
+	fmt.Fprintf(w, "not_found\t%d\n", missing)
+	return nil
+}
+
+// dbStats prints the size of the database and the numbers of digests by source file and date
+func dbStats(w io.Writer, d *digestDB, path string) error {
+	byFile := make(map[string]int)
+	byDate := make(map[string]int)
+	total := 0
+	err := d.bolt.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(dbDigestsBucket).ForEach(func(_, v []byte) error {
+			var entry dbEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			total++
+			byFile[entry.File]++
+			byDate[entry.Date]++
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	size := int64(0)
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	fmt.Fprintf(w, "format_version\t%d\n", dbFormatVersion)
+	fmt.Fprintf(w, "hash_type\t%s\n", d.hashType)
+	fmt.Fprintf(w, "file_size\t%d\n", size)
+	fmt.Fprintf(w, "digests\t%d\n", total)
+	for _, group := range []struct {
+		name   string
+		counts map[string]int
+	}{{"file", byFile}, {"date", byDate}} {
+		keys := make([]string, 0, len(group.counts))
+		for k := range group.counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", group.name, k, group.counts[k])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package seqhash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestDigestDB(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "seen.db")
+	first := filepath.Join(tmpDir, "run1.fasta")
+	second := filepath.Join(tmpDir, "run2.fasta")
+	if err := os.WriteFile(first, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte(">r2_1\nACTG\n>r2_2\nGGGG\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		// seq1 and seq1_lowercase share a sequence
+		{"Add", []string{"add", "--db", dbPath, first}, "new\t2\nknown\t1\n"},
+		{"Query annotate", []string{"query", "--db", dbPath, second},
+			">r2_1;db=known\nACTG\n>r2_2;db=new\nGGGG\n"},
+		{"Query new", []string{"query", "--db", dbPath, "--mode", "new", second}, ">r2_2\nGGGG\n"},
+		{"Add again", []string{"add", "--db", dbPath, first}, "new\t0\nknown\t3\n"},
+		{"Add second run", []string{"add", "--db", dbPath, second}, "new\t1\nknown\t1\n"},
+		{"Query known", []string{"query", "--db", dbPath, "--mode", "known", second},
+			">r2_1\nACTG\n>r2_2\nGGGG\n"},
+	}
+
+	for _, step := range steps {
+		runTest(t, step.name, func(t *testing.T) {
+			output, err := runWithArgs(append([]string{"seqhasher", "db"}, step.args...))
+			if err != nil {
+				t.Fatalf("db %s error = %v", step.args[0], err)
+			}
+			if output != step.expected {
+				t.Errorf("Got:\n%s\nWant:\n%s", output, step.expected)
+			}
+		})
+	}
+
+	stats, err := runWithArgs([]string{"seqhasher", "db", "stats", "--db", dbPath})
+	if err != nil {
+		t.Fatalf("db stats error = %v", err)
+	}
+	for _, line := range []string{"format_version\t1\n", "hash_type\tsha1\n", "digests\t3\n",
+		"file\t" + first + "\t2\n", "file\t" + second + "\t1\n"} {
+		if !strings.Contains(stats, line) {
+			t.Errorf("Expected %q in stats:\n%s", line, stats)
+		}
+	}
+
+	// Remove a digest listed in a TSV file (first column)
+	list := filepath.Join(tmpDir, "remove.tsv")
+	content := "# digests to remove\n65c89f59d38cdbf90dfaf0b0a6884829df8396b0\tseq1\n0000000000000000000000000000000000000000\n"
+	if err := os.WriteFile(list, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runWithArgs([]string{"seqhasher", "db", "remove", "--db", dbPath, list})
+	if err != nil || output != "removed\t1\nnot_found\t1\n" {
+		t.Errorf("db remove = %q, %v", output, err)
+	}
+	output, err = runWithArgs([]string{"seqhasher", "db", "add", "--db", dbPath, first})
+	if err != nil || output != "new\t1\nknown\t2\n" {
+		t.Errorf("db add after remove = %q, %v", output, err)
+	}
+
+	if _, err := os.Stat(dbPath + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed after use, got %v", err)
+	}
+}
+
+func TestDigestDBLocking(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "seen.db")
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// First writer holds the lock
+	d, err := openDigestDB(dbPath, "", true)
+	if err != nil {
+		t.Fatalf("openDigestDB() error = %v", err)
+	}
+
+	_, err = runWithArgs([]string{"seqhasher", "db", "add", "--db", dbPath, input})
+	if err == nil || !strings.Contains(err.Error(), "is locked by another process") {
+		t.Errorf("Expected a lock error for a concurrent add, got %v", err)
+	}
+
+	d.Close()
+	if _, err := runWithArgs([]string{"seqhasher", "db", "add", "--db", dbPath, input}); err != nil {
+		t.Errorf("Unexpected error after the lock was released: %v", err)
+	}
+}
+
+func TestDigestDBVersion(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "seen.db")
+	d, err := openDigestDB(dbPath, "md5", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(dbMetaBucket).Put(dbVersionKey, []byte("99"))
+	})
+	d.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := openDigestDB(dbPath, "", false); err == nil || !strings.Contains(err.Error(), "unsupported format version 99") {
+		t.Errorf("Expected a format version error, got %v", err)
+	}
+	if _, err := os.Stat(dbPath + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected no lock file to remain, got %v", err)
+	}
+}
+
+func TestDigestDBHashType(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "seen.db")
+	d, err := openDigestDB(dbPath, "md5", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	if _, err := openDigestDB(dbPath, "sha1", false); err == nil || !strings.Contains(err.Error(), "computed with md5") {
+		t.Errorf("Expected a hash type mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// IUPAC ambiguity codes (everything except A, C, G, T, and U)
+const ambiguityCodes = "NRYKMSWBDHV"
+
+// Symbol that replaces ambiguity codes in --n-wildcard-dedup keys
+const wildcardSymbol = 'N'
+
+// deduplicator tracks the sequences seen so far (--dedup, --n-wildcard-dedup).
+// Short sequences of A, C, G, and T are kept packed with 2 bits per base,
+// which is lossless, so equal keys mean equal sequences. Other sequences are
+// kept as SHA-1 digests (independently of the requested hash types, whose
+// sentinels for empty sequences or failures must not collapse records).
+// Memory use grows with the number of unique sequences.
+// The keys map to the unique sequences in first-seen order, with their numbers
+// of records (for --dedup-report) and total abundances (for --sizeout).
+type deduplicator struct {
+	wildcard             bool
+	packed               bool                    // Use packed keys where possible
+	short                map[uint64]int          // Packed sequences of up to 31 bases
+	long                 map[[2]uint64]int       // Packed sequences of 32 to 63 bases
+	seen                 map[[sha1.Size]byte]int // Digests of the other sequences
+	sequences            []uniqueSequence
+	records              int64
+	viaPacked, viaDigest int64 // Records of each path (--dedup-stats)
+}
+
+// Counts of a unique sequence
+type uniqueSequence struct {
+	records int64
+	size    int64 // Sum of the abundances of the records (1 each without --sizein)
+}
+
+// newDeduplicator returns nil if deduplication was not requested
+func newDeduplicator(cfg Config) *deduplicator {
+	if !cfg.Dedup && !cfg.NWildcardDedup {
+		return nil
+	}
+	return &deduplicator{
+		wildcard: cfg.NWildcardDedup,
+		packed:   true,
+		short:    make(map[uint64]int),
+		long:     make(map[[2]uint64]int),
+		seen:     make(map[[sha1.Size]byte]int),
+	}
+}
+
+// duplicate reports whether an equal sequence was seen before, and records the sequence
+func (d *deduplicator) duplicate(seq []byte) bool {
+	_, duplicate := d.add(seq, 1)
+	return duplicate
+}
+
+// add records a sequence with its abundance. It returns the position of the
+// unique sequence in first-seen order, and whether an equal sequence was seen before.
+func (d *deduplicator) add(seq []byte, size int64) (int, bool) {
+	if d.wildcard {
+		seq = maskAmbiguity(seq)
+	}
+	d.records++
+
+	if d.packed && len(seq) < 2*basesPerWord {
+		if len(seq) < basesPerWord {
+			if key, ok := packBases(1, seq); ok {
+				d.viaPacked++
+				return countKey(d, d.short, key, size)
+			}
+		} else {
+			lo, ok := packBases(0, seq[:basesPerWord])
+			hi, ok2 := packBases(1, seq[basesPerWord:])
+			if ok && ok2 {
+				d.viaPacked++
+				return countKey(d, d.long, [2]uint64{lo, hi}, size)
+			}
+		}
+	}
+
+	d.viaDigest++
+	return countKey(d, d.seen, sha1.Sum(seq), size)
+}
+
+// countKey adds a record to the unique sequence of the key, creating it if the key is new
+func countKey[K comparable](d *deduplicator, keys map[K]int, key K, size int64) (int, bool) {
+	i, duplicate := keys[key]
+	if !duplicate {
+		i = len(d.sequences)
+		keys[key] = i
+		d.sequences = append(d.sequences, uniqueSequence{})
+	}
+	d.sequences[i].records++
+	d.sequences[i].size += size
+	return i, duplicate
+}
+
+// unique returns the numbers of records of the unique sequences, in first-seen order
+func (d *deduplicator) unique() []int64 {
+	counts := make([]int64, len(d.sequences))
+	for i, s := range d.sequences {
+		counts[i] = s.records
+	}
+	return counts
+}
+
+// size returns the total abundance of the unique sequence at position i
+func (d *deduplicator) size(i int) int64 {
+	return d.sequences[i].size
+}
+
+// Bases in a packed word (2 bits each)
+const basesPerWord = 32
+
+// Codes of the bases in packed keys (0xff for bytes that can't be packed;
+// lowercase bases are left to digests, as they differ from uppercase ones with --casesensitive)
+var baseCodes = func() (codes [256]byte) {
+	for i := range codes {
+		codes[i] = 0xff
+	}
+	codes['A'], codes['C'], codes['G'], codes['T'] = 0, 1, 2, 3
+	return codes
+}()
+
+// packBases appends the bases of seq to key, 2 bits each. Starting from a key of 1
+// (up to 31 bases), the leading 1 bit marks the length, so that e.g. "A" and "AA" differ;
+// starting from 0, exactly 32 bases fill the word.
+func packBases(key uint64, seq []byte) (uint64, bool) {
+	for _, c := range seq {
+		code := baseCodes[c]
+		if code > 3 {
+			return 0, false
+		}
+		key = key<<2 | uint64(code)
+	}
+	return key, true
+}
+
+// stats describes how the sequences were keyed (--dedup-stats)
+func (d *deduplicator) stats() string {
+	return fmt.Sprintf("Dedup: %d record(s) keyed by packed sequence, %d by digest; %d unique sequence(s)",
+		d.viaPacked, d.viaDigest, len(d.sequences))
+}
+
+// writeReport writes the collapse statistics (--dedup-report): the numbers of
+// input records and unique sequences, followed by the distribution of the number
+// of records per unique sequence (singletons, doubletons, etc.) as TSV
+func (d *deduplicator) writeReport(fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	counts := d.unique()
+	histogram := make(map[int64]int64)
+	for _, n := range counts {
+		histogram[n]++
+	}
+	sizes := make([]int64, 0, len(histogram))
+	for size := range histogram {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+
+	fmt.Fprintf(w, "# input_records: %d\n", d.records)
+	fmt.Fprintf(w, "# unique_sequences: %d\n", len(counts))
+	fmt.Fprintln(w, "size\tsequences\trecords")
+	for _, size := range sizes {
+		fmt.Fprintf(w, "%d\t%d\t%d\n", size, histogram[size], size*histogram[size])
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// maskAmbiguity returns a copy of the sequence with every ambiguity code replaced by N
+// (keeping the case of the original character, as sequences may be hashed case-sensitively)
+func maskAmbiguity(seq []byte) []byte {
+	masked := make([]byte, len(seq))
+	for i, c := range seq {
+		switch {
+		case strings.IndexByte(ambiguityCodes, c) >= 0:
+			masked[i] = wildcardSymbol
+		case c >= 'a' && c <= 'z' && strings.IndexByte(ambiguityCodes, c-'a'+'A') >= 0:
+			masked[i] = wildcardSymbol - 'A' + 'a'
+		default:
+			masked[i] = c
+		}
+	}
+	return masked
+}
+
+// dedupOutput writes the first record of each unique sequence to a separate file (--dedup-output),
+// in the output format, while the main output receives all records.
+// It has its own deduplicator, so that the main output is not deduplicated.
+type dedupOutput struct {
+	seen    *deduplicator
+	file    io.WriteCloser
+	buf     *bufio.Writer
+	sink    OutputSink
+	label   string
+	written int64
+}
+
+// newDedupOutput returns nil if no deduplicated output was requested
+func newDedupOutput(cfg Config, label string) (*dedupOutput, error) {
+	if cfg.DedupOutput == "" {
+		return nil, nil
+	}
+	file, err := getOutput(cfg.DedupOutput)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening deduplicated output: %v", err)
+	}
+	dedupCfg := cfg
+	dedupCfg.Dedup = true
+	buf := bufio.NewWriter(file)
+	d := &dedupOutput{
+		seen: newDeduplicator(dedupCfg),
+		file: file,
+		buf:  buf,
+		sink: newOutputSink(buf, buf, cfg, label),
+	}
+	if !cfg.NoFileName {
+		d.label = label
+	}
+	return d, nil
+}
+
+// first reports whether the sequence is seen for the first time
+func (d *dedupOutput) first(seq []byte, size int64) bool {
+	_, duplicate := d.seen.add(seq, size)
+	return !duplicate
+}
+
+// write passes a hashed record, with its rewritten header, to the deduplicated output
+func (d *dedupOutput) write(record *fastx.Record, hashed *hashedRecord, hashTypes []string) error {
+	err := d.sink.WriteRecord(Record{
+		Index:     d.written,
+		File:      d.label,
+		ID:        record.ID,
+		Header:    record.Name,
+		HashTypes: hashTypes,
+		Hashes:    hashed.hashes,
+		Sequence:  record.Seq.Seq,
+		Quality:   record.Seq.Qual,
+		fastx:     record,
+		hashed:    hashed,
+	})
+	if err != nil {
+		return fmt.Errorf("Error writing deduplicated output: %v", err)
+	}
+	d.written++
+	return nil
+}
+
+// finish completes the deduplicated output (e.g., the JSON array) after the last record
+func (d *dedupOutput) finish(stats Stats) error {
+	if s, ok := d.sink.(finishingSink); ok {
+		if err := s.finish(stats); err != nil {
+			return fmt.Errorf("Error writing deduplicated output: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes the file; after a failed run, the file is removed (unless --keep-partial)
+func (d *dedupOutput) Close(cfg Config, err error) error {
+	err = closeSink(d.sink, cfg, err)
+	if cerr := d.file.Close(); cerr != nil && err == nil {
+		err = fmt.Errorf("Error closing deduplicated output: %v", cerr)
+	}
+	if err != nil && !cfg.KeepPartial {
+		os.Remove(cfg.DedupOutput)
+	}
+	return err
+}
+
+// duplicateReferences tracks the ID of the first record of each sequence, so that later records
+// with the same sequence can be written as references to it (--output-no-sequence-for-duplicates)
+type duplicateReferences struct {
+	seen *deduplicator
+	ids  []string // IDs of the first records, in the order of the unique sequences
+}
+
+// newDuplicateReferences returns nil if duplicates are written in full
+func newDuplicateReferences(cfg Config) *duplicateReferences {
+	if !cfg.DupReferences {
+		return nil
+	}
+	refCfg := cfg
+	refCfg.Dedup = true
+	return &duplicateReferences{seen: newDeduplicator(refCfg)}
+}
+
+// firstID returns the ID of the first record with an equal sequence,
+// or an empty string if the sequence is new (its record becomes the first one)
+func (d *duplicateReferences) firstID(seq, id []byte) string {
+	i, duplicate := d.seen.add(seq, 1)
+	if duplicate {
+		return d.ids[i]
+	}
+	d.ids = append(d.ids, string(id))
+	return ""
+}
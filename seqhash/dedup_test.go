@@ -0,0 +1,359 @@
+package seqhash
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	input := ">s1\nACNG\n" +
+		">s2\nACNG\n" + // exact duplicate
+		">s3\nACRG\n" + // ambiguity code at the same position
+		">s4\nANTG\n" + // N at a different position
+		">s5\nACTG\n" + // resolved base instead of N
+		">s6\nacng\n" // lowercase duplicate (sequences are uppercased)
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected []string
+	}{
+		{"No deduplication", Config{}, []string{"s1", "s2", "s3", "s4", "s5", "s6"}},
+		{"Exact", Config{Dedup: true}, []string{"s1", "s3", "s4", "s5"}},
+		{"N wildcard", Config{NWildcardDedup: true}, []string{"s1", "s4", "s5"}},
+		{"N wildcard, case-sensitive", Config{NWildcardDedup: true, CaseSensitive: true}, []string{"s1", "s4", "s5", "s6"}},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1"}
+			cfg.NoFileName = true
+			cfg.HeadersOnly = true
+			cfg.HeaderFormat = "{id}"
+
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			got := strings.Fields(output.String())
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Got records %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDedupSizeout(t *testing.T) {
+	// Abundances are only known at the end, so the unique records are
+	// written in first-seen order after the last duplicate of TTTT
+	input := ">s1 first\nACGT\n" +
+		">s2;size=4\nTTTT\n" +
+		">s3;size=5\nACGT\n" +
+		">s4\nGG\n" +
+		">s5\nACGT\n" +
+		">s6;size=2\ntttt\n"
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected string
+	}{
+		{"Records", Config{Dedup: true, SizeOut: true},
+			"s1;size=3 first\ns2;size=2\ns4;size=1\n"},
+		{"Input abundances", Config{Dedup: true, SizeOut: true, SizeIn: true},
+			"s1;size=7 first\ns2;size=6\ns4;size=1\n"},
+		{"Input abundances, multithreaded", Config{Dedup: true, SizeOut: true, SizeIn: true, Threads: 3},
+			"s1;size=7 first\ns2;size=6\ns4;size=1\n"},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1"}
+			cfg.NoFileName = true
+			cfg.HeadersOnly = true
+			cfg.HeaderFormat = "{id}"
+
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Got:\n%s\nwant:\n%s", output, tt.expected)
+			}
+		})
+	}
+
+	runTest(t, "Full records", func(t *testing.T) {
+		cfg := Config{HashTypes: []string{"xxhash"}, NoFileName: true, Dedup: true, SizeOut: true}
+		output := &bytes.Buffer{}
+		if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		expected := ">f40a8ecfa26af897;s1;size=3 first\nACGT\n" +
+			">fce1cde36bb8af7b;s2;size=2\nTTTT\n" +
+			">c990ef291373497e;s4;size=1\nGG\n"
+		if output.String() != expected {
+			t.Errorf("Got:\n%s\nwant:\n%s", output, expected)
+		}
+	})
+}
+
+func TestMaskAmbiguity(t *testing.T) {
+	tests := map[string]string{
+		"ACGT":        "ACGT",
+		"ACNGRYKMSWB": "ACNGNNNNNNN",
+		"DHVU":        "NNNU",
+		"acrgN-":      "acngN-",
+	}
+	for seq, expected := range tests {
+		if got := string(maskAmbiguity([]byte(seq))); got != expected {
+			t.Errorf("maskAmbiguity(%q) = %q, want %q", seq, got, expected)
+		}
+	}
+}
+
+func TestDedupReport(t *testing.T) {
+	report := filepath.Join(t.TempDir(), "dedup.tsv")
+	cfg := Config{HashTypes: []string{"sha1"}, Dedup: true, DedupReport: report}
+	if err := processSequences(strings.NewReader(testSequences), &bytes.Buffer{}, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// seq1 and seq1_lowercase collapse into a doubleton, seq2 is a singleton
+	expected := "# input_records: 3\n" +
+		"# unique_sequences: 2\n" +
+		"size\tsequences\trecords\n" +
+		"1\t1\t1\n" +
+		"2\t1\t2\n"
+	if string(data) != expected {
+		t.Errorf("Got report:\n%s\nWant:\n%s", data, expected)
+	}
+}
+
+func TestDedupOutput(t *testing.T) {
+	// testSequences has three records, two of which have the same sequence
+	all := []string{"seq1", "seq1_lowercase", "seq2"}
+	tests := []struct {
+		name string
+		cfg  Config
+		main []string
+		ids  []string
+	}{
+		{"FASTA", Config{}, all, []string{"seq1", "seq2"}},
+		// The first record of a sequence in the input is written, in the order of the main output
+		{"Reversed", Config{ReverseOutput: true}, []string{"seq2", "seq1_lowercase", "seq1"}, []string{"seq2", "seq1"}},
+		{"Compressed", Config{}, all, []string{"seq1", "seq2"}},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			dedupOutput := filepath.Join(t.TempDir(), "unique.fasta")
+			if tt.name == "Compressed" {
+				dedupOutput += ".gz"
+			}
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1"}
+			cfg.NoFileName = true
+			cfg.HeaderFormat = "{id}"
+			cfg.DedupOutput = dedupOutput
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(testSequences), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if got := fastaIDs(t, output.Bytes()); !slices.Equal(got, tt.main) {
+				t.Errorf("Got main output records %v, want %v", got, tt.main)
+			}
+
+			input, err := getInput(dedupOutput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer input.Close()
+			data, err := io.ReadAll(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fastaIDs(t, data); !slices.Equal(got, tt.ids) {
+				t.Errorf("Got deduplicated records %v, want %v", got, tt.ids)
+			}
+		})
+	}
+
+	runTest(t, "Removed on failure", func(t *testing.T) {
+		dedupOutput := filepath.Join(t.TempDir(), "unique.fasta")
+		cfg := Config{HashTypes: []string{"sha1"}, DedupOutput: dedupOutput}
+		if err := processSequences(strings.NewReader("@a\nACGT\n+\nIIII\n@b\nACGT\n+\nII\n"), &bytes.Buffer{}, cfg); err == nil {
+			t.Fatal("Expected an error for the malformed record")
+		}
+		if _, err := os.Stat(dedupOutput); !os.IsNotExist(err) {
+			t.Errorf("Expected the deduplicated output to be removed, got %v", err)
+		}
+	})
+}
+
+func TestDuplicateReferences(t *testing.T) {
+	const digest = "65c89f59d38cdbf90dfaf0b0a6884829df8396b0"
+	input := ">seq1\nACTG\n>seq2\nactg\n>seq3\nTT\n>seq4 desc\nAC TG\n"
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected string
+	}{
+		{"Default", Config{},
+			">" + digest + ";seq1\nACTG\n" +
+				">" + digest + ";seq2;dup-of=seq1\n" +
+				">8c2408452ca428cdc3ee78c1b09ab347350250a8;seq3\nTT\n" +
+				">" + digest + ";seq4;dup-of=seq1 desc\n"},
+		{"Synthesized IDs", Config{SynthesizeIDs: true, HeaderFormat: "{id}"},
+			">seq_65c89f59\nACTG\n>seq_65c89f59;dup-of=seq_65c89f59\n>seq_8c240845\nTT\n>seq_65c89f59;dup-of=seq_65c89f59 desc\n"},
+		{"Reversed", Config{ReverseOutput: true, HeaderFormat: "{id}"},
+			">seq4;dup-of=seq1 desc\n>seq3\nTT\n>seq2;dup-of=seq1\n>seq1\nACTG\n"},
+		{"Spot-checked", Config{SpotCheck: 1, ValidateRoundtrip: true, HeaderFormat: "{id}"},
+			">seq1\nACTG\n>seq2;dup-of=seq1\n>seq3\nTT\n>seq4;dup-of=seq1 desc\n"},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1"}
+			cfg.NoFileName = true
+			cfg.DupReferences = true
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Got output:\n%s\nwant:\n%s", output, tt.expected)
+			}
+		})
+	}
+
+	runTest(t, "FASTQ input", func(t *testing.T) {
+		cfg := Config{HashTypes: []string{"sha1"}, DupReferences: true}
+		err := processSequences(strings.NewReader("@a\nACTG\n+\nIIII\n"), &bytes.Buffer{}, cfg)
+		if err == nil || !strings.Contains(err.Error(), "requires FASTA input") {
+			t.Errorf("Expected an error for FASTQ input, got %v", err)
+		}
+	})
+}
+
+// fastaIDs returns the headers of FASTA records
+func fastaIDs(t *testing.T, data []byte) []string {
+	t.Helper()
+	var ids []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, ">") {
+			ids = append(ids, line[1:])
+		}
+	}
+	return ids
+}
+
+// Sequences with many duplicates, around the limits of packed keys
+func dedupTestSequences(n int) [][]byte {
+	rng := rand.New(rand.NewSource(1))
+	pool := [][]byte{{}, []byte("A"), []byte("AA"), []byte("C"), []byte("ACGTN"), []byte("acgt")}
+	for _, length := range []int{1, 2, 30, 31, 32, 33, 62, 63, 64, 65, 150} {
+		for i := 0; i < 20; i++ {
+			seq := make([]byte, length)
+			for j := range seq {
+				seq[j] = "ACGT"[rng.Intn(2)] // Few distinct short sequences
+			}
+			if i%7 == 0 {
+				seq[rng.Intn(length)] = 'N'
+			}
+			pool = append(pool, seq)
+		}
+	}
+	seqs := make([][]byte, n)
+	for i := range seqs {
+		seqs[i] = pool[rng.Intn(len(pool))]
+	}
+	return seqs
+}
+
+func TestPackedDedupMatchesDigests(t *testing.T) {
+	for _, wildcard := range []bool{false, true} {
+		packed := newDeduplicator(Config{Dedup: true, NWildcardDedup: wildcard})
+		digests := newDeduplicator(Config{Dedup: true, NWildcardDedup: wildcard})
+		digests.packed = false
+
+		for i, seq := range dedupTestSequences(5000) {
+			if got, want := packed.duplicate(seq), digests.duplicate(seq); got != want {
+				t.Fatalf("Record %d (%q): duplicate = %v with packed keys, %v with digests", i, seq, got, want)
+			}
+		}
+		if packed.viaPacked == 0 || packed.viaDigest == 0 {
+			t.Errorf("Expected records on both paths, got %d packed and %d digests", packed.viaPacked, packed.viaDigest)
+		}
+
+		got, want := packed.unique(), digests.unique()
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("Sizes of unique sequences differ: %v with packed keys, %v with digests", got, want)
+		}
+	}
+}
+
+func TestPackBases(t *testing.T) {
+	a, _ := packBases(1, []byte("A"))
+	aa, _ := packBases(1, []byte("AA"))
+	empty, _ := packBases(1, nil)
+	if a == aa || a == empty || aa == empty {
+		t.Errorf("Sequences of different lengths must get different keys: %x, %x, %x", empty, a, aa)
+	}
+	if _, ok := packBases(1, []byte("ACGN")); ok {
+		t.Error("Expected a sequence with N not to be packed")
+	}
+	if _, ok := packBases(1, []byte("acgt")); ok {
+		t.Error("Expected a lowercase sequence not to be packed")
+	}
+}
+
+func BenchmarkDedupShortReads(b *testing.B) {
+	// Unique 50-bp reads, as in a large short-read run
+	seqs := make([][]byte, 200000)
+	rng := rand.New(rand.NewSource(1))
+	for i := range seqs {
+		seqs[i] = make([]byte, 50)
+		for j := range seqs[i] {
+			seqs[i][j] = "ACGT"[rng.Intn(4)]
+		}
+	}
+	for _, packed := range []bool{true, false} {
+		name := "digests"
+		if packed {
+			name = "packed"
+		}
+		b.Run(name, func(b *testing.B) {
+			var heap int64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				d := newDeduplicator(Config{Dedup: true})
+				d.packed = packed
+				for _, seq := range seqs {
+					d.duplicate(seq)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				heap += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+				runtime.KeepAlive(d)
+			}
+			b.ReportMetric(float64(heap)/float64(b.N)/float64(len(seqs)), "heap-B/seq")
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Delimiters tried when detecting the digest field, in order of preference
+var layoutDelimiters = []string{";", "|", "\t", ",", " "}
+
+// Number of headers sampled for the detection of the digest field
+const layoutSampleSize = 100
+
+// errNoDigest is returned if no field of the headers looks like a digest
+var errNoDigest = errors.New("No digest-like fields found in the headers")
+
+// digestLayout locates the digest within headers rewritten by seqhasher
+// (e.g., 'file;digest;id' or 'id;sha1=digest;file=name')
+type digestLayout struct {
+	Delimiter  string   `json:"delimiter"`
+	Field      int      `json:"field"`           // 1-based
+	Label      string   `json:"label,omitempty"` // Key of a labeled digest ('sha1=...')
+	Width      int      `json:"width"`           // Hex characters (0 if digests differ in width)
+	Candidates []string `json:"candidates"`      // Hash types with digests of this width
+}
+
+// digestWidths maps the number of hex characters to the hash types that produce them
+// (e.g., 16 for 64-bit hashes, 40 for SHA-1)
+func digestWidths() map[int][]string {
+	widths := make(map[int][]string)
+	for _, hashType := range supportedHashTypes {
+		width := len(getHashFunc(hashType)([]byte("A")))
+		widths[width] = append(widths[width], hashType)
+	}
+	return widths
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLabel separates the key of a labeled value ('sha1=...')
+func splitLabel(field string) (label, value string) {
+	if i := strings.IndexByte(field, '='); i > 0 {
+		return field[:i], field[i+1:]
+	}
+	return "", field
+}
+
+// digestColumn checks that a field holds hex digests in all headers, with the same label.
+// Empty digests (of empty sequences) are allowed, as long as one digest is not empty.
+// Unless explicit, digests must have the width of a supported hash type.
+func digestColumn(headers [][]string, field int, widths map[int][]string, explicit bool) (digestLayout, bool) {
+	layout := digestLayout{Field: field + 1, Width: -1}
+	for i, fields := range headers {
+		if field >= len(fields) {
+			return layout, false
+		}
+		label, value := splitLabel(fields[field])
+		if i == 0 {
+			layout.Label = label
+		} else if label != layout.Label {
+			return layout, false
+		}
+		if value == "" {
+			continue
+		}
+		if !isHex(value) {
+			return layout, false
+		}
+		switch {
+		case layout.Width < 0:
+			layout.Width = len(value)
+		case layout.Width != len(value):
+			if !explicit {
+				return layout, false
+			}
+			layout.Width = 0
+		}
+	}
+	if layout.Width < 0 || !explicit && widths[layout.Width] == nil {
+		return layout, false
+	}
+	layout.Candidates = widths[layout.Width]
+	if isValidHashType(layout.Label) {
+		// Labeled hashes name their type
+		layout.Candidates = []string{layout.Label}
+	}
+	if layout.Candidates == nil {
+		layout.Candidates = []string{}
+	}
+	return layout, true
+}
+
+// detectDigestLayout finds the delimiter and the field of the digests in a sample of headers.
+// A non-empty delimiter and a positive field (1-based) override the detection.
+// With several digest fields (e.g., '--hash sha1,md5'), the first one is taken,
+// unless two of them have the same width and can't be told apart by their labels.
+func detectDigestLayout(headers []string, delimiter string, field int) (*digestLayout, error) {
+	if len(headers) == 0 {
+		return nil, errNoDigest
+	}
+	delimiters := layoutDelimiters
+	if delimiter != "" {
+		delimiters = []string{delimiter}
+	}
+	widths := digestWidths()
+
+	for _, d := range delimiters {
+		split := make([][]string, len(headers))
+		fields := -1
+		for i, header := range headers {
+			split[i] = strings.Split(header, d)
+			if fields < 0 || len(split[i]) < fields {
+				fields = len(split[i])
+			}
+		}
+
+		var columns []digestLayout
+		for f := 0; f < fields; f++ {
+			if field > 0 && f != field-1 {
+				continue
+			}
+			if layout, ok := digestColumn(split, f, widths, field > 0); ok {
+				layout.Delimiter = d
+				columns = append(columns, layout)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		for i, a := range columns {
+			for _, b := range columns[i+1:] {
+				if a.Width == b.Width && a.Label == b.Label {
+					return nil, fmt.Errorf("Ambiguous digest layout: fields %d and %d (delimited by %q) both hold %d-character digests. "+
+						"Please select the digest field with --hash-field and --delimiter", a.Field, b.Field, d, a.Width)
+				}
+			}
+		}
+		return &columns[0], nil
+	}
+
+	if field > 0 {
+		return nil, fmt.Errorf("Field %d of the headers does not hold hex digests", field)
+	}
+	return nil, errNoDigest
+}
+
+// digest extracts the digest from a header (false if the header has no such field)
+func (l *digestLayout) digest(header string) (string, bool) {
+	fields := strings.Split(header, l.Delimiter)
+	if l.Field > len(fields) {
+		return "", false
+	}
+	label, value := splitLabel(fields[l.Field-1])
+	if label != l.Label {
+		return "", false
+	}
+	return value, true
+}
+
+func (l *digestLayout) String() string {
+	s := fmt.Sprintf("field %d of %q", l.Field, l.Delimiter)
+	if l.Label != "" {
+		s += fmt.Sprintf(", labeled %s=", l.Label)
+	}
+	if l.Width == 0 {
+		return s + " (hex digests of varying width)"
+	}
+	candidates := "unknown hash type"
+	if len(l.Candidates) > 0 {
+		candidates = strings.Join(l.Candidates, ", ")
+	}
+	return s + fmt.Sprintf(" (%d hex: %s)", l.Width, candidates)
+}
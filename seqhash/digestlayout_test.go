@@ -0,0 +1,160 @@
+package seqhash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Headers of testSequences as rewritten with the configuration
+func rewrittenHeaders(t *testing.T, cfg Config) []string {
+	t.Helper()
+	cfg.HeadersOnly = true
+	cfg.InputFileName = "test.fasta"
+	output := &bytes.Buffer{}
+	if err := processSequences(strings.NewReader(testSequences), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	var headers []string
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		headers = append(headers, strings.TrimPrefix(line, ">"))
+	}
+	return headers
+}
+
+func TestDetectDigestLayout(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		delimiter  string
+		field      int
+		expected   digestLayout
+		digestType string // of the detected field
+	}{
+		{
+			name:       "Default header",
+			cfg:        Config{HashTypes: []string{"sha1"}},
+			expected:   digestLayout{Delimiter: ";", Field: 2, Width: 40, Candidates: []string{"sha1"}},
+			digestType: "sha1",
+		},
+		{
+			name:       "Without file names",
+			cfg:        Config{HashTypes: []string{"xxhash"}, NoFileName: true},
+			expected:   digestLayout{Delimiter: ";", Field: 1, Width: 16, Candidates: []string{"xxhash", "nthash"}},
+			digestType: "xxhash",
+		},
+		{
+			name:       "Hashes of different widths",
+			cfg:        Config{HashTypes: []string{"blake3", "md5"}},
+			expected:   digestLayout{Delimiter: ";", Field: 2, Width: 64, Candidates: []string{"blake3"}},
+			digestType: "blake3",
+		},
+		{
+			name:       "Hash as the whole header",
+			cfg:        Config{HashTypes: []string{"md5"}, IDIsHash: true, NoFileName: true},
+			expected:   digestLayout{Delimiter: ";", Field: 1, Width: 32, Candidates: []string{"md5", "cityhash", "murmur3"}},
+			digestType: "md5",
+		},
+		{
+			name:       "Header template",
+			cfg:        Config{HashTypes: []string{"sha1"}, HeaderFormat: "{id}|{file}|{sha1}"},
+			expected:   digestLayout{Delimiter: "|", Field: 3, Width: 40, Candidates: []string{"sha1"}},
+			digestType: "sha1",
+		},
+		{
+			name:       "Labeled hashes",
+			cfg:        Config{HashTypes: []string{"md5", "murmur3"}, SeqkitCompat: true},
+			expected:   digestLayout{Delimiter: ";", Field: 2, Label: "md5", Width: 32, Candidates: []string{"md5"}},
+			digestType: "md5",
+		},
+		{
+			name:       "Explicit field of ambiguous layout",
+			cfg:        Config{HashTypes: []string{"md5", "murmur3"}},
+			delimiter:  ";",
+			field:      3,
+			expected:   digestLayout{Delimiter: ";", Field: 3, Width: 32, Candidates: []string{"md5", "cityhash", "murmur3"}},
+			digestType: "murmur3",
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			headers := rewrittenHeaders(t, tt.cfg)
+			layout, err := detectDigestLayout(headers, tt.delimiter, tt.field)
+			if err != nil {
+				t.Fatalf("detectDigestLayout() error = %v", err)
+			}
+			if layout.String() != tt.expected.String() || layout.Label != tt.expected.Label {
+				t.Errorf("Detected %s, want %s", layout, &tt.expected)
+			}
+			hash := getHashFunc(tt.digestType)
+			for i, seq := range []string{"ACTG", "ACTG", "TGCA"} {
+				if digest, ok := layout.digest(headers[i]); !ok || digest != hash([]byte(seq)) {
+					t.Errorf("Digest of %q = %q, want %q", headers[i], digest, hash([]byte(seq)))
+				}
+			}
+		})
+	}
+}
+
+func TestDetectDigestLayoutErrors(t *testing.T) {
+	runTest(t, "Ambiguous fields", func(t *testing.T) {
+		headers := rewrittenHeaders(t, Config{HashTypes: []string{"md5", "cityhash"}})
+		_, err := detectDigestLayout(headers, "", 0)
+		expected := `Ambiguous digest layout: fields 2 and 3 (delimited by ";") both hold 32-character digests. ` +
+			"Please select the digest field with --hash-field and --delimiter"
+		if err == nil || err.Error() != expected {
+			t.Errorf("Got error %v, want %q", err, expected)
+		}
+	})
+
+	runTest(t, "Input without digests", func(t *testing.T) {
+		if _, err := detectDigestLayout([]string{"seq1 description", "seq2"}, "", 0); err != errNoDigest {
+			t.Errorf("Got error %v, want %v", err, errNoDigest)
+		}
+	})
+
+	runTest(t, "Explicit field without digests", func(t *testing.T) {
+		headers := rewrittenHeaders(t, Config{HashTypes: []string{"sha1"}})
+		_, err := detectDigestLayout(headers, ";", 3)
+		if err == nil || err.Error() != "Field 3 of the headers does not hold hex digests" {
+			t.Errorf("Got error %v", err)
+		}
+	})
+
+	runTest(t, "Digests of empty sequences", func(t *testing.T) {
+		digest := getHashFunc("sha1")([]byte("ACGT"))
+		layout, err := detectDigestLayout([]string{";empty", digest + ";seq"}, "", 0)
+		if err != nil || layout.Field != 1 || layout.Width != 40 {
+			t.Errorf("Got %v, %v", layout, err)
+		}
+	})
+}
+
+func TestInspectDigest(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "hashed.fasta")
+	output := &bytes.Buffer{}
+	cfg := Config{HashTypes: []string{"sha1"}, InputFileName: "test.fasta"}
+	if err := processSequences(strings.NewReader(testSequences), output, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fileName, output.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var table bytes.Buffer
+	if err := runInspect(&table, []string{fileName, testFastaPath}); err != nil {
+		t.Fatalf("runInspect() error = %v", err)
+	}
+	lines := strings.Split(table.String(), "\n")
+	if !strings.HasSuffix(lines[1], `field 2 of ";" (40 hex: sha1)`) || !strings.HasSuffix(strings.TrimSpace(lines[2]), "-") {
+		t.Errorf("Unexpected digest columns:\n%s", table.String())
+	}
+
+	// An explicit field is required to hold digests
+	if err := runInspect(&bytes.Buffer{}, []string{"--hash-field", "3", fileName}); err == nil {
+		t.Error("Expected inspection to fail for a field without digests")
+	}
+}
@@ -0,0 +1,339 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// Outcome of a diagnostic check
+type checkStatus int
+
+const (
+	checkPass checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+func (s checkStatus) String() string {
+	switch s {
+	case checkPass:
+		return "pass"
+	case checkWarn:
+		return "warn"
+	}
+	return "fail"
+}
+
+// Result of a single diagnostic check
+type doctorCheck struct {
+	name   string
+	status checkStatus
+	detail string
+	hint   string // Remediation (for warnings and failures)
+}
+
+// Assumed compression ratio of compressed inputs and outputs, for space estimates
+const assumedCompressionRatio = 4
+
+// Files that are always open (stdin, stdout, stderr)
+const baseOpenFiles = 3
+
+// runDoctor checks the resources implied by the given options, prints the results,
+// and returns an error if any check failed. Checks never leave files behind.
+func runDoctor(w io.Writer, cfg Config) error {
+	checks := doctorChecks(cfg)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	failed := 0
+	for _, c := range checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", strings.ToUpper(c.status.String()), c.name, c.detail)
+		if c.status != checkPass && c.hint != "" {
+			fmt.Fprintf(tw, "\t\t  hint: %s\n", c.hint)
+		}
+		if c.status == checkFail {
+			failed++
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d preflight checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+func doctorChecks(cfg Config) []doctorCheck {
+	var checks []doctorCheck
+
+	input := checkInput(cfg.InputFileName)
+	checks = append(checks, input.check)
+	if strings.HasPrefix(cfg.InputFileName, "s3://") {
+		checks = append(checks, checkAWSCredentials())
+	}
+	if cfg.SampleSheet != "" {
+		checks = append(checks, checkReadable("sample sheet", cfg.SampleSheet))
+	}
+	if cfg.IncludeIDFile != "" {
+		checks = append(checks, checkReadable("ID list", cfg.IncludeIDFile))
+	}
+
+	if cfg.OutputFileName != "" && cfg.OutputFileName != "-" {
+		checks = append(checks, checkWritableFile("output", cfg.OutputFileName))
+		checks = append(checks, checkFreeSpace(cfg, input.report))
+	}
+	for _, side := range []struct{ name, file string }{
+		{"index", cfg.IndexFileName},
+		{"audit log", cfg.AuditLog},
+		{"clusters report", cfg.ClustersFile},
+		{"top report", cfg.TopFile},
+		{"dedup report", cfg.DedupReport},
+		{"rejects file", cfg.RejectsFileName},
+		{"group report", cfg.GroupReport},
+	} {
+		if side.file != "" {
+			checks = append(checks, checkWritableFile(side.name, side.file))
+		}
+	}
+
+	if cfg.MinimalUniquePrefix {
+		dir := cfg.TmpDir
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		checks = append(checks, checkWritableFile("temporary file", filepath.Join(dir, "seqhasher-spool")))
+	}
+
+	checks = append(checks, checkOpenFiles(cfg))
+	return checks
+}
+
+// Input check result, with the sample used for the size estimate
+type inputCheck struct {
+	check  doctorCheck
+	report *inspectReport
+}
+
+// checkInput verifies that the input is readable and in a supported format,
+// using the same detection as `seqhasher inspect` and regular runs
+func checkInput(fileName string) inputCheck {
+	c := doctorCheck{name: "input"}
+	if strings.Contains(fileName, "://") {
+		c.status = checkFail
+		c.detail = fileName + ": remote inputs can't be read directly"
+		c.hint = "download the file first, or stream it to stdin (e.g., 'aws s3 cp " + fileName + " - | seqhasher ... -')"
+		return inputCheck{check: c}
+	}
+	if fileName == "" {
+		c.status = checkFail
+		c.detail = "no input file given"
+		c.hint = "give the input file (or '-' for stdin) as the first argument"
+		return inputCheck{check: c}
+	}
+	if fileName == "-" {
+		c.detail = "stdin (not checked, it can only be read once)"
+		return inputCheck{check: c}
+	}
+
+	report := inspectFile(fileName, 1<<20)
+	switch {
+	case report.Error != "":
+		c.status = checkFail
+		c.detail = report.Error
+		c.hint = "check the file name and its read permissions"
+	case report.Format == formatOther:
+		c.status = checkFail
+		c.detail = fmt.Sprintf("%s (%s): not a FASTA or FASTQ file", fileName, report.Codec)
+		c.hint = "seqhasher reads FASTA/FASTQ, optionally compressed with gzip, zstd, xz, or bzip2"
+	case report.Format == formatEmpty:
+		c.status = checkWarn
+		c.detail = fileName + ": no records"
+		c.hint = "the output will be empty"
+	default:
+		c.detail = fmt.Sprintf("%s: %s, %s", fileName, report.Format, report.Codec)
+	}
+	return inputCheck{check: c, report: report}
+}
+
+func checkReadable(name, fileName string) doctorCheck {
+	c := doctorCheck{name: name, detail: fileName}
+	f, err := os.Open(fileName)
+	if err != nil {
+		c.status = checkFail
+		c.detail = err.Error()
+		c.hint = "check the file name and its read permissions"
+		return c
+	}
+	f.Close()
+	return c
+}
+
+// checkWritableFile verifies that a file can be created in the directory of fileName
+// (by creating and removing a temporary file)
+func checkWritableFile(name, fileName string) doctorCheck {
+	c := doctorCheck{name: name + " directory"}
+	dir := filepath.Dir(fileName)
+	c.detail = dir
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		c.status = checkFail
+		c.detail = err.Error()
+		c.hint = "create the directory first (mkdir -p " + dir + ")"
+		return c
+	}
+	if !info.IsDir() {
+		c.status = checkFail
+		c.detail = dir + " is not a directory"
+		c.hint = "choose a different " + name + " path"
+		return c
+	}
+
+	probe, err := os.CreateTemp(dir, ".seqhasher-doctor-*")
+	if err != nil {
+		c.status = checkFail
+		c.detail = fmt.Sprintf("%s is not writable: %v", dir, err)
+		c.hint = "choose a writable directory or fix its permissions"
+		return c
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	if info, err := os.Stat(fileName); err == nil && info.Mode().IsRegular() && name != "audit log" {
+		c.status = checkWarn
+		c.detail = fileName + " exists and will be overwritten"
+		c.hint = "choose a different name to keep the existing file"
+	}
+	return c
+}
+
+// estimateOutputSize roughly estimates the output size from the input sample
+func estimateOutputSize(cfg Config, report *inspectReport) (uint64, bool) {
+	if report == nil || report.EstimatedRecords == nil {
+		return 0, false
+	}
+	info, err := os.Stat(cfg.InputFileName)
+	if err != nil {
+		return 0, false
+	}
+
+	size := uint64(info.Size())
+	if report.Codec != "none" {
+		size *= assumedCompressionRatio
+	}
+	// Every header gets the file name, digests, and separators
+	perRecord := len(cfg.InputFileName) + 1
+	for _, hashType := range cfg.HashTypes {
+		if algorithm, ok := hashAlgorithms[hashType]; ok {
+			perRecord += algorithm.width + 1
+		}
+	}
+	size += uint64(*report.EstimatedRecords) * uint64(perRecord)
+
+	if outputCompression(cfg) != "none" {
+		size /= assumedCompressionRatio
+	}
+	return size, true
+}
+
+func checkFreeSpace(cfg Config, report *inspectReport) doctorCheck {
+	c := doctorCheck{name: "free space"}
+	dir := filepath.Dir(cfg.OutputFileName)
+	free, err := freeSpace(dir)
+	if err != nil {
+		c.status = checkWarn
+		c.detail = fmt.Sprintf("can't determine free space in %s: %v", dir, err)
+		return c
+	}
+	needed, ok := estimateOutputSize(cfg, report)
+	if !ok {
+		c.detail = fmt.Sprintf("%s available in %s (output size unknown)", formatBytes(free), dir)
+		return c
+	}
+
+	c.detail = fmt.Sprintf("%s available in %s, output estimated at %s", formatBytes(free), dir, formatBytes(needed))
+	switch {
+	case free < needed:
+		c.status = checkFail
+		c.hint = "free up space, choose another output directory, or compress the output (e.g., --compress xz)"
+	case free < 2*needed:
+		c.status = checkWarn
+		c.hint = "the estimate is rough, the output may not fit"
+	}
+	return c
+}
+
+// checkOpenFiles compares the number of files a run keeps open with the limit
+func checkOpenFiles(cfg Config) doctorCheck {
+	c := doctorCheck{name: "open files"}
+	planned := uint64(baseOpenFiles + 1) // input
+	for _, file := range []string{cfg.OutputFileName, cfg.IndexFileName, cfg.AuditLog, cfg.ClustersFile, cfg.TopFile, cfg.DedupReport, cfg.RejectsFileName, cfg.GroupReport, cfg.SampleSheet} {
+		if file != "" && file != "-" {
+			planned++
+		}
+	}
+	if cfg.PipeTo != "" {
+		planned += 2 // Pipe to the command
+	}
+
+	limit, err := openFilesLimit()
+	if err != nil {
+		c.status = checkWarn
+		c.detail = fmt.Sprintf("can't determine the open files limit: %v", err)
+		return c
+	}
+	c.detail = fmt.Sprintf("%d planned, limit %d", planned, limit)
+	if planned > limit {
+		c.status = checkFail
+		c.hint = "raise the limit (e.g., 'ulimit -n 1024')"
+	}
+	return c
+}
+
+// checkAWSCredentials reports whether credentials for s3:// inputs can be found
+func checkAWSCredentials() doctorCheck {
+	c := doctorCheck{name: "AWS credentials"}
+	switch {
+	case os.Getenv("AWS_ACCESS_KEY_ID") != "" && os.Getenv("AWS_SECRET_ACCESS_KEY") != "":
+		c.detail = "found in the environment"
+	case os.Getenv("AWS_PROFILE") != "":
+		c.detail = "profile " + os.Getenv("AWS_PROFILE")
+	default:
+		home, _ := os.UserHomeDir()
+		credentials := filepath.Join(home, ".aws", "credentials")
+		if file := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); file != "" {
+			credentials = file
+		}
+		if _, err := os.Stat(credentials); err == nil {
+			c.detail = "found in " + credentials
+			return c
+		}
+		c.status = checkFail
+		c.detail = "no credentials found"
+		c.hint = "set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, AWS_PROFILE, or run 'aws configure'"
+	}
+	return c
+}
+
+// formatBytes returns a human-readable size
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,16 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+//go:build !unix
+
+package seqhash
+
+import "errors"
+
+var errNotAvailable = errors.New("not available on this platform")
+
+var freeSpace = func(dir string) (uint64, error) { return 0, errNotAvailable }
+
+var openFilesLimit = func() (uint64, error) { return 0, errNotAvailable }
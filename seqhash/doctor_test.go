@@ -0,0 +1,170 @@
+package seqhash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// checkStatuses returns the status of each check by name
+func checkStatuses(checks []doctorCheck) map[string]checkStatus {
+	statuses := make(map[string]checkStatus)
+	for _, c := range checks {
+		statuses[c.name] = c.status
+	}
+	return statuses
+}
+
+func TestDoctorChecks(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notFasta := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(notFasta, []byte("just some text\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		check    string
+		expected checkStatus
+	}{
+		{"Readable input", Config{InputFileName: input}, "input", checkPass},
+		{"Missing input", Config{InputFileName: filepath.Join(tmpDir, "missing.fasta")}, "input", checkFail},
+		{"Not FASTA", Config{InputFileName: notFasta}, "input", checkFail},
+		{"Remote input", Config{InputFileName: "s3://bucket/in.fasta"}, "input", checkFail},
+		{"Writable output directory", Config{InputFileName: input, OutputFileName: filepath.Join(tmpDir, "out.fasta")}, "output directory", checkPass},
+		{"Existing output", Config{InputFileName: input, OutputFileName: input}, "output directory", checkWarn},
+		{"Missing output directory", Config{InputFileName: input, OutputFileName: filepath.Join(tmpDir, "missing", "out.fasta")}, "output directory", checkFail},
+		{"Output directory is a file", Config{InputFileName: input, OutputFileName: filepath.Join(input, "out.fasta")}, "output directory", checkFail},
+		{"Missing index directory", Config{InputFileName: input, IndexFileName: filepath.Join(tmpDir, "missing", "index.tsv")}, "index directory", checkFail},
+		{"Missing sample sheet", Config{InputFileName: input, SampleSheet: filepath.Join(tmpDir, "sheet.csv")}, "sample sheet", checkFail},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			tt.cfg.HashTypes = []string{"sha1"}
+			statuses := checkStatuses(doctorChecks(tt.cfg))
+			if got, ok := statuses[tt.check]; !ok || got != tt.expected {
+				t.Errorf("Expected %q check to %v, got %v (%v)", tt.check, tt.expected, got, statuses)
+			}
+		})
+	}
+}
+
+func TestDoctorReadOnlyDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("Permissions are not enforced for root")
+	}
+	tmpDir := t.TempDir()
+	readOnly := filepath.Join(tmpDir, "ro")
+	if err := os.Mkdir(readOnly, 0555); err != nil {
+		t.Fatal(err)
+	}
+	unreadable := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(unreadable, []byte(testSequences), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{InputFileName: unreadable, OutputFileName: filepath.Join(readOnly, "out.fasta"), HashTypes: []string{"sha1"}}
+	statuses := checkStatuses(doctorChecks(cfg))
+	if statuses["input"] != checkFail || statuses["output directory"] != checkFail {
+		t.Errorf("Expected unreadable input and read-only output directory to fail, got %v", statuses)
+	}
+}
+
+func TestDoctorResourceLimits(t *testing.T) {
+	oldFreeSpace, oldOpenFilesLimit := freeSpace, openFilesLimit
+	defer func() { freeSpace, openFilesLimit = oldFreeSpace, oldOpenFilesLimit }()
+
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(randomRecords(100, 100)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{InputFileName: input, OutputFileName: filepath.Join(tmpDir, "out.fasta"), HashTypes: []string{"sha1"}}
+	needed, ok := estimateOutputSize(cfg, checkInput(input).report)
+	if !ok || needed < uint64(len(randomRecords(100, 100))) {
+		t.Fatalf("Expected an output size estimate above the input size, got %d", needed)
+	}
+
+	tests := []struct {
+		name      string
+		free      uint64
+		limit     uint64
+		limitErr  error
+		freeCheck checkStatus
+		fileCheck checkStatus
+	}{
+		{"Plenty", 1 << 40, 1024, nil, checkPass, checkPass},
+		{"Tight space", needed * 3 / 2, 1024, nil, checkWarn, checkPass},
+		{"No space", needed / 2, 1024, nil, checkFail, checkPass},
+		{"Low open files limit", 1 << 40, 4, nil, checkPass, checkFail},
+		{"Unknown limit", 1 << 40, 0, errors.New("not supported"), checkPass, checkWarn},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			freeSpace = func(string) (uint64, error) { return tt.free, nil }
+			openFilesLimit = func() (uint64, error) { return tt.limit, tt.limitErr }
+			statuses := checkStatuses(doctorChecks(cfg))
+			if statuses["free space"] != tt.freeCheck || statuses["open files"] != tt.fileCheck {
+				t.Errorf("Expected free space %v and open files %v, got %v", tt.freeCheck, tt.fileCheck, statuses)
+			}
+		})
+	}
+}
+
+func TestDoctorLeavesNoFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runWithArgs([]string{"seqhasher", "doctor",
+		"--index", filepath.Join(tmpDir, "index.tsv"),
+		"--clusters", filepath.Join(tmpDir, "clusters.tsv"),
+		input, filepath.Join(tmpDir, "out.fasta")})
+	if err != nil {
+		t.Fatalf("Expected all checks to pass, got %v:\n%s", err, output)
+	}
+	if !strings.Contains(output, "PASS") || strings.Contains(output, "FAIL") {
+		t.Errorf("Unexpected report:\n%s", output)
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the input file to remain, got %d entries", len(entries))
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Aborted before any output is written
+	_, err := runWithArgs([]string{"seqhasher", "--preflight", input, filepath.Join(tmpDir, "missing", "out.fasta")})
+	if err == nil || !strings.Contains(err.Error(), "preflight checks failed") {
+		t.Errorf("Expected the preflight to fail, got %v", err)
+	}
+
+	output := filepath.Join(tmpDir, "out.fasta")
+	if _, err := runWithArgs([]string{"seqhasher", "--preflight", input, output}); err != nil {
+		t.Fatalf("Expected the run to succeed, got %v", err)
+	}
+	if _, err := os.Stat(output); errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected the output to be written after the preflight")
+	}
+}
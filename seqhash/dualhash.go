@@ -0,0 +1,36 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// With --dual-hash, the complete original header is hashed along with the sequence,
+// and both hashes are labeled (seqhash=<digest>;hdrhash=<digest>)
+
+// headerHash hashes the header of a record as read, before IDs are replaced or annotations changed
+func headerHash(cfg Config, record *fastx.Record) (string, error) {
+	digest, err := hashAlgorithms[cfg.HashTypes[0]].sum(record.Name)
+	if err != nil {
+		return "", fmt.Errorf("Error computing %s hash of the header of record %q: %v", cfg.HashTypes[0], record.ID, err)
+	}
+	return digest, nil
+}
+
+// headerHashField is the header hash in the output (hdrhash=<digest>)
+func headerHashField(cfg Config) outputField {
+	return outputField{
+		name:     "hdrhash",
+		source:   cfg.HashTypes[0] + " digest of the complete original header, without the leading '>' or '@' (written as hdrhash=<digest>)",
+		width:    hashAlgorithms[cfg.HashTypes[0]].width,
+		inHeader: true,
+		key:      "hdrhash",
+		value:    func(r *hashedRecord) string { return r.headerHash },
+	}
+}
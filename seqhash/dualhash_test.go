@@ -0,0 +1,53 @@
+package seqhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDualHash(t *testing.T) {
+	// Returns the labeled hashes of the single record of input
+	hashes := func(t *testing.T, input string, cfg Config) (seqhash, hdrhash string) {
+		t.Helper()
+		cfg.HashTypes = []string{"sha1"}
+		cfg.NoFileName = true
+		cfg.HeadersOnly = true
+		cfg.DualHash = true
+		output := &bytes.Buffer{}
+		if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		fields := strings.SplitN(strings.TrimSpace(output.String()), ";", 3)
+		if len(fields) != 3 || !strings.HasPrefix(fields[0], "seqhash=") || !strings.HasPrefix(fields[1], "hdrhash=") {
+			t.Fatalf("Unexpected header %q", output)
+		}
+		return fields[0][len("seqhash="):], fields[1][len("hdrhash="):]
+	}
+	sha1 := getHashFunc("sha1")
+
+	seqhash, hdrhash := hashes(t, ">seq1 sample A;size=2\nACTG\n", Config{})
+	if seqhash != sha1([]byte("ACTG")) || hdrhash != sha1([]byte("seq1 sample A;size=2")) {
+		t.Errorf("Got seqhash=%s, hdrhash=%s", seqhash, hdrhash)
+	}
+
+	runTest(t, "Changed sequence", func(t *testing.T) {
+		s, h := hashes(t, ">seq1 sample A;size=2\nACTT\n", Config{})
+		if s == seqhash || h != hdrhash {
+			t.Errorf("Expected only the sequence hash to change, got seqhash=%s, hdrhash=%s", s, h)
+		}
+	})
+	runTest(t, "Changed header", func(t *testing.T) {
+		s, h := hashes(t, ">seq1 sample B;size=2\nACTG\n", Config{})
+		if s != seqhash || h == hdrhash {
+			t.Errorf("Expected only the header hash to change, got seqhash=%s, hdrhash=%s", s, h)
+		}
+	})
+	runTest(t, "Original header", func(t *testing.T) {
+		// The header is hashed before it is rewritten or its annotations are changed
+		s, h := hashes(t, ">seq1 sample A;size=2\nactg\n", Config{SynthesizeIDs: true, StripAnnotations: true})
+		if s != seqhash || h != hdrhash {
+			t.Errorf("Expected the hashes of the input, got seqhash=%s, hdrhash=%s", s, h)
+		}
+	})
+}
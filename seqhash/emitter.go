@@ -0,0 +1,162 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// Hashed (or passed-through) record on its way to the output
+type keptRecord struct {
+	record   *fastx.Record
+	hashes   []string
+	unique   int  // Position of the sequence in the deduplicator (-1 without --sizeout or --stratified-sample)
+	excluded bool // Passed through unchanged
+	original *originalRecord
+	comments *recordComments
+	first    bool   // First record of its sequence (--dedup-output)
+	header   string // Digest of the original header (--dual-hash)
+	dupOf    string // ID of the first record of the sequence, for duplicates written without it (--output-no-sequence-for-duplicates)
+	cluster  string // Bucket of near-identical sequences (--clustered-hash)
+	hashType string // Hash type chosen for the record (--auto-hash)
+}
+
+// recordEmitter passes the records to the sink, in output order, and keeps the outputs
+// that follow the written records (--spot-check, --validate-roundtrip, --index, --dedup-output)
+type recordEmitter struct {
+	cfg       Config
+	sink      OutputSink
+	header    func(r *hashedRecord) []byte
+	fileName  string          // Label of the input in the headers
+	label     string          // Label of the input in the records (empty with --nofilename)
+	counter   *countingWriter // Output position (nil for sinks of other callers)
+	spot      *spotChecker
+	roundtrip *roundtripValidator
+	index     *indexWriter
+	dedupOut  *dedupOutput
+	captured  bytes.Buffer // Written bytes of the checked record
+
+	written   int64 // Records passed to the sink
+	delimited bool  // The next record is preceded by --record-delimiter (not at the start of a section)
+}
+
+// write passes a record to the sink as it is
+func (e *recordEmitter) write(record *fastx.Record, hashed *hashedRecord, comments *recordComments, noSeq bool) error {
+	r := Record{
+		Index:     e.written,
+		File:      e.label,
+		ID:        record.ID,
+		Header:    record.Name,
+		HashTypes: e.cfg.HashTypes,
+		Sequence:  record.Seq.Seq,
+		Quality:   record.Seq.Qual,
+		fastx:     record,
+		hashed:    hashed,
+		comments:  comments,
+		noSeq:     noSeq,
+	}
+	if hashed != nil {
+		r.Hashes = hashed.hashes
+	}
+	if err := e.sink.WriteRecord(r); err != nil {
+		return &SinkError{Index: e.written, Err: err}
+	}
+	e.written++
+	e.delimited = e.cfg.RecordDelimiter != ""
+	return nil
+}
+
+// emit rewrites the header of a hashed record and writes it (also to --dedup-output, if its sequence is seen first);
+// a non-negative size replaces the size annotation (--sizeout)
+func (e *recordEmitter) emit(k keptRecord, size int64) error {
+	cfg := e.cfg
+	record, hashes, comments := k.record, k.hashes, k.comments
+	hashed := newHashedRecord(e.fileName, hashes, record.Name)
+	hashed.headerHash = k.header
+	if cfg.StripAnnotations {
+		hashed.annotations = annotations{}
+	}
+	if size >= 0 {
+		hashed.annotations.setSize(size)
+	}
+	if k.dupOf != "" {
+		hashed.annotations.replace("dup-of", k.dupOf)
+	}
+	if k.cluster != "" {
+		hashed.annotations.replace("cluster", k.cluster)
+	}
+	if k.hashType != "" {
+		hashed.annotations.replace("hash-type", k.hashType)
+	}
+
+	// Modify header in-place
+	record.Name = e.header(hashed)
+
+	var offset int64
+	if e.counter != nil {
+		offset = e.counter.n
+	}
+	// The bytes of a checked record are captured as they are written
+	// Duplicates without sequences have nothing to verify
+	check := e.spot != nil && k.dupOf == "" && e.spot.due()
+	validate := e.roundtrip != nil && k.dupOf == ""
+	if check || validate {
+		e.captured.Reset()
+		e.counter.tap = &e.captured
+	}
+	recordIndex := e.written
+	skip := int64(0) // Bytes of the delimiter and of the comment lines written before the record
+	if e.delimited && e.counter != nil {
+		skip = int64(len(cfg.RecordDelimiter))
+	}
+	if comments != nil {
+		for _, line := range comments.leading {
+			skip += int64(len(line)) + 1
+		}
+	}
+	err := e.write(record, hashed, comments, k.dupOf != "")
+	if e.counter != nil {
+		e.counter.tap = nil
+	}
+	if err != nil {
+		return err
+	}
+	// The delimiter (--record-delimiter) and the comments (--keep-comments) written before the record are not part of it
+	offset += skip
+	e.captured.Next(int(skip))
+	if check {
+		if err := e.spot.verify(e.captured.Bytes(), recordIndex, offset, record.Name, hashes); err != nil {
+			return err
+		}
+	}
+	if validate {
+		if err := e.roundtrip.verify(e.captured.Bytes(), recordIndex, offset, record.ID, k.original); err != nil {
+			return err
+		}
+	}
+
+	if e.index != nil {
+		if err := e.index.add(record.ID, hashes, offset, e.counter.n-offset); err != nil {
+			return fmt.Errorf("Error writing index: %v", err)
+		}
+	}
+	if k.first {
+		return e.dedupOut.write(record, hashed, cfg.HashTypes)
+	}
+	return nil
+}
+
+// section starts a group of records in the output (--group-by-length)
+func (e *recordEmitter) section(title string) error {
+	if err := e.sink.(sectioningSink).section(title); err != nil {
+		return &SinkError{Index: e.written, Err: err}
+	}
+	e.delimited = false
+	return nil
+}
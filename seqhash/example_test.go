@@ -0,0 +1,45 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/vmikk/seqhasher/seqhash"
+)
+
+// printSink prints the ID and the digests of each record
+type printSink struct{}
+
+func (printSink) WriteRecord(r seqhash.Record) error {
+	fmt.Println(string(r.ID), strings.Join(r.Hashes, " "))
+	return nil
+}
+
+func (printSink) Flush() error { return nil }
+func (printSink) Close() error { return nil }
+
+func ExampleProcess() {
+	cfg, err := seqhash.ParseArgs([]string{"--hash", "sha1,xxhash"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.InputFileName = "input.fasta"
+
+	input := strings.NewReader(">seq1\nACTG\n>seq2\nactg\n")
+	stats, err := seqhash.Process(context.Background(), input, printSink{}, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(stats.Records, "records,", stats.Bases, "bases")
+	// Output:
+	// seq1 65c89f59d38cdbf90dfaf0b0a6884829df8396b0 704b34bf20faedf2
+	// seq2 65c89f59d38cdbf90dfaf0b0a6884829df8396b0 704b34bf20faedf2
+	// 2 records, 8 bases
+}
@@ -0,0 +1,132 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+)
+
+// recordComments are the FASTA comment lines of a record (without line breaks)
+type recordComments struct {
+	leading [][]byte // Before the header
+	inner   [][]byte // Between the header and the first sequence line
+}
+
+// commentFilter removes the ';'-prefixed comment lines of FASTA input (--keep-comments),
+// which the parser does not support, and keeps them for the output. Comment lines directly
+// after a header belong to its record; other lines belong to the record whose header follows them,
+// and the lines after the last record are trailing. FASTQ input is passed through unchanged,
+// as its quality lines may start with ';'. The parser reads ahead (possibly in another goroutine),
+// so comments are queued until their records are taken.
+type commentFilter struct {
+	r         *bufio.Reader
+	buf       []byte // Filtered bytes not yet read
+	err       error
+	lineStart bool
+	format    byte   // '>' or '@' (0 before the first record)
+	comment   []byte // The comment line being read (nil outside comments)
+	pending   [][]byte
+	current   *recordComments // Of the last header
+	seqSeen   bool            // A sequence line follows the last header
+
+	mu    sync.Mutex
+	queue []*recordComments // Comments of the headers read so far and not yet taken
+}
+
+func newCommentFilter(r io.Reader) *commentFilter {
+	return &commentFilter{r: bufio.NewReader(r), lineStart: true}
+}
+
+func (f *commentFilter) Read(p []byte) (int, error) {
+	for len(f.buf) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		// Lines longer than the buffer come in several pieces
+		line, err := f.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			err = nil
+		}
+		f.err = err
+		atStart := f.lineStart
+		f.lineStart = len(line) > 0 && line[len(line)-1] == '\n'
+
+		if atStart && len(line) > 0 {
+			if f.format == 0 && (line[0] == '>' || line[0] == '@') {
+				f.format = line[0]
+			}
+			switch {
+			case line[0] == ';' && f.format != '@':
+				f.comment = []byte{}
+			case line[0] == '>' && f.format == '>':
+				f.current = &recordComments{leading: f.pending}
+				f.pending, f.seqSeen = nil, false
+				f.mu.Lock()
+				f.queue = append(f.queue, f.current)
+				f.mu.Unlock()
+			case line[0] != '\n' && line[0] != '\r':
+				f.seqSeen = true
+			}
+		}
+		if f.comment == nil {
+			f.buf = line
+			continue
+		}
+		f.comment = append(f.comment, line...)
+		if f.lineStart || f.err != nil {
+			f.addComment(bytes.TrimRight(f.comment, "\r\n"))
+			f.comment = nil
+		}
+	}
+	n := copy(p, f.buf)
+	f.buf = f.buf[n:]
+	return n, nil
+}
+
+// addComment assigns a comment line to the last record, if it directly follows its header,
+// or to the next one. The last record is not taken yet, as the parser has not reached its end.
+func (f *commentFilter) addComment(line []byte) {
+	if f.current == nil || f.seqSeen {
+		f.pending = append(f.pending, line)
+		return
+	}
+	f.mu.Lock()
+	f.current.inner = append(f.current.inner, line)
+	f.mu.Unlock()
+}
+
+// take returns the comment lines of the next record (nil if it has none)
+func (f *commentFilter) take() *recordComments {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queue) == 0 {
+		return nil
+	}
+	comments := f.queue[0]
+	f.queue = f.queue[1:]
+	return comments
+}
+
+// trailing returns the comment lines after the last record (once the input is read)
+func (f *commentFilter) trailing() [][]byte {
+	return f.pending
+}
+
+// withoutInnerComments removes the comment lines between the header and the sequence of a written
+// FASTA record, so that it can be parsed back (--spot-check, --validate-roundtrip)
+func withoutInnerComments(record []byte) []byte {
+	header, rest, ok := bytes.Cut(record, []byte("\n"))
+	if !ok || !bytes.HasPrefix(rest, []byte(";")) {
+		return record
+	}
+	for bytes.HasPrefix(rest, []byte(";")) {
+		_, rest, _ = bytes.Cut(rest, []byte("\n"))
+	}
+	return append(append(bytes.Clone(header), '\n'), rest...)
+}
@@ -0,0 +1,86 @@
+package seqhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestKeepComments(t *testing.T) {
+	input := "; file comment\n" +
+		">a desc\n; about a\nACGT\nAC\n" +
+		";; between records\n>b\nTTTT\n" +
+		"; trailing"
+	sha1 := getHashFunc("sha1")
+	a, b := sha1([]byte("ACGTAC"))+";a desc", sha1([]byte("TTTT"))+";b"
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected string
+	}{
+		{"Full records", Config{},
+			"; file comment\n>" + a + "\n; about a\nACGTAC\n;; between records\n>" + b + "\nTTTT\n; trailing\n"},
+		{"Headers only", Config{HeadersOnly: true},
+			"; file comment\n" + a + "\n; about a\n;; between records\n" + b + "\n; trailing\n"},
+		{"Reversed records", Config{ReverseOutput: true},
+			";; between records\n>" + b + "\nTTTT\n; file comment\n>" + a + "\n; about a\nACGTAC\n; trailing\n"},
+		{"Shortest unique prefixes", Config{MinimalUniquePrefix: true, Threads: 2},
+			"; file comment\n>" + a[:1] + a[40:] + "\n; about a\nACGTAC\n;; between records\n>" + b[:1] + b[40:] + "\nTTTT\n; trailing\n"},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			tt.cfg.HashTypes = []string{"sha1"}
+			tt.cfg.NoFileName = true
+			tt.cfg.KeepComments = true
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(input), output, tt.cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Got output:\n%s\nwant:\n%s", output, tt.expected)
+			}
+		})
+	}
+
+	runTest(t, "Without --keep-comments", func(t *testing.T) {
+		cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true}
+		if err := processSequences(strings.NewReader(input), &bytes.Buffer{}, cfg); err == nil {
+			t.Error("Expected the parser to reject comment lines")
+		}
+	})
+
+	runTest(t, "Checked records", func(t *testing.T) {
+		cfg := Config{HashTypes: []string{"sha1"}, KeepComments: true, SpotCheck: 1, ValidateRoundtrip: true, RecordDelimiter: "\n"}
+		if err := processSequences(strings.NewReader(input), &bytes.Buffer{}, cfg); err != nil {
+			t.Errorf("processSequences() error = %v", err)
+		}
+	})
+}
+
+func TestCommentFilter(t *testing.T) {
+	runTest(t, "Long comment lines", func(t *testing.T) {
+		long := ";" + strings.Repeat("x", 10000)
+		output := &bytes.Buffer{}
+		cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, HeadersOnly: true, KeepComments: true}
+		if err := processSequences(strings.NewReader(long+"\r\n>a\r\nACGT\r\n"), output, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		if !strings.HasPrefix(output.String(), long+"\n") {
+			t.Errorf("Expected the long comment to be kept, got %d bytes", output.Len())
+		}
+	})
+
+	runTest(t, "FASTQ input", func(t *testing.T) {
+		// Quality lines may start with ';'
+		input := "@a\nACGT\n+\n;;;;\n"
+		filtered := &bytes.Buffer{}
+		filter := newCommentFilter(strings.NewReader(input))
+		if _, err := filtered.ReadFrom(filter); err != nil || filtered.String() != input {
+			t.Errorf("Expected FASTQ input to pass unchanged, got %q, %v", filtered, err)
+		}
+		if comments := filter.take(); comments != nil || filter.trailing() != nil {
+			t.Errorf("Expected no comments in FASTQ input, got %v", comments)
+		}
+	})
+}
@@ -0,0 +1,374 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Abnormal conditions under which a field does not hold a regular value
+type abnormalCondition int
+
+const (
+	condEmptySequence abnormalCondition = iota // Sequence is empty after normalization
+	condHashFailure                            // Hash algorithm could not produce a digest
+)
+
+var abnormalConditions = []abnormalCondition{condEmptySequence, condHashFailure}
+
+func (c abnormalCondition) String() string {
+	switch c {
+	case condEmptySequence:
+		return "empty sequence"
+	case condHashFailure:
+		return "hash failure"
+	}
+	return "unknown"
+}
+
+// Values emitted in place of a digest under abnormal conditions
+// (shared by all hash algorithms, so that every failure looks the same)
+var hashSentinels = map[abnormalCondition]string{
+	condEmptySequence: "",
+	condHashFailure:   "",
+}
+
+// Values derived from a record that are used to fill the output fields
+type hashedRecord struct {
+	label  string   // Input file name (or its replacement)
+	hashes []string // Digests, in the order of the requested hash types
+	name   []byte   // Original (or synthesized) header
+	id     []byte   // ID part of the header (up to the first space or tab), without annotations
+	tail   []byte   // Comment with its leading separator, as in the input (empty if none), without annotations
+
+	headerHash string // Digest of the original header (--dual-hash)
+
+	annotations annotations // Trailing ";key=value" pairs of the ID and the comment
+}
+
+// comment returns the part of the header after the ID
+func (r *hashedRecord) comment() []byte {
+	return bytes.TrimLeft(r.tail, " \t")
+}
+
+// newHashedRecord splits the header of a record into its ID, comment, and annotations
+func newHashedRecord(label string, hashes []string, name []byte) *hashedRecord {
+	bare, notes := splitAnnotations(name)
+	id, tail := splitComment(bare)
+	return &hashedRecord{label: label, hashes: hashes, name: name, id: id, tail: tail, annotations: notes}
+}
+
+// Description of a single output field
+type outputField struct {
+	name      string
+	source    string
+	width     int                          // Fixed width in characters (0 if variable)
+	inHeader  bool                         // Part of the rewritten header
+	key       string                       // Written as <key>=<value> in the header (empty for bare values)
+	sentinels map[abnormalCondition]string // Values under abnormal conditions (nil if unaffected)
+	value     func(r *hashedRecord) string // Value of header fields and columns of tabular outputs
+}
+
+// outputFields lists the fields of an output record for the given options.
+// The header fields are joined with ';' in the listed order,
+// followed by the comment of the original header (unless --drop-comment).
+// Tabular outputs have a column for each field with a value (header fields and sample metadata).
+func outputFields(cfg Config) []outputField {
+	var fields []outputField
+
+	if !cfg.NoFileName {
+		source := "input file name"
+		if cfg.NameOverride != "" {
+			source = "--name"
+		} else if cfg.StdinName != "" && cfg.InputFileName == "-" {
+			source = "--stdin-name"
+		}
+		if cfg.AnonymizeLabels {
+			source = "keyed pseudonym of the " + source
+		}
+		fields = append(fields, outputField{
+			name:     "file",
+			source:   source,
+			inHeader: true,
+			value:    func(r *hashedRecord) string { return r.label },
+		})
+	}
+
+	for i, hashType := range cfg.HashTypes {
+		i := i
+		algorithm, ok := hashAlgorithms[hashType]
+		if !ok {
+			algorithm = hashAlgorithms[defaultHashType]
+		}
+		source, width := hashType+" digest of the sequence", algorithm.width
+		if cfg.Ends > 0 {
+			source = fmt.Sprintf("%s digest of the first and last %d bases of the sequence", hashType, cfg.Ends)
+		}
+		if cfg.BothStrands {
+			source = strings.Replace(source, " of the sequence", " of both strands of the sequence", 1)
+		}
+		if cfg.CollapseHomopolymers {
+			source += ", with homopolymers collapsed to single bases"
+		}
+		if cfg.LengthPrefix {
+			source += ", preceded by its length"
+		}
+		if cfg.TreeChunk > 0 {
+			source = fmt.Sprintf("%s digest of the %s digests of %d-byte chunks of the sequence (tree root)", hashType, hashType, cfg.TreeChunk)
+		}
+		if cfg.AutoHash {
+			chosen := fmt.Sprintf("%s (sequences of up to %d bases) or %s (longer ones; named by ';hash-type=<type>') digest",
+				autoHashShort, cfg.AutoHashThreshold, autoHashLong)
+			source, width = strings.Replace(source, hashType+" digest", chosen, 1), 0
+		}
+		if i == 0 && cfg.MinimalUniquePrefix {
+			source, width = "shortest prefix of the "+source+" that is unique within the input", 0
+		}
+		key := ""
+		if cfg.DualHash {
+			source, key = source+" (written as seqhash=<digest>)", "seqhash"
+		}
+		fields = append(fields, outputField{
+			name:      hashType,
+			source:    source,
+			width:     width,
+			inHeader:  !cfg.IDIsHash, // The first hash is the ID
+			key:       key,
+			sentinels: hashSentinels,
+			value:     func(r *hashedRecord) string { return r.hashes[i] },
+		})
+	}
+	if cfg.DualHash {
+		fields = append(fields, headerHashField(cfg))
+	}
+
+	idSource := "original ID (blank IDs replaced by seq_<hash prefix>)"
+	if cfg.SynthesizeIDs {
+		idSource = "seq_<hash prefix>"
+	} else if cfg.IDIsHash {
+		idSource = "first hash (the original header is dropped)"
+	} else if cfg.IlluminaID {
+		idSource = "original ID without the /1 or /2 read suffix (shared by paired Illumina reads)"
+	}
+	fields = append(fields, outputField{
+		name:     "id",
+		source:   idSource,
+		inHeader: true,
+		value:    func(r *hashedRecord) string { return string(r.id) },
+	})
+
+	commentSource := "text after the ID (appended to the header after a space)"
+	if cfg.DropComment {
+		commentSource = "text after the ID (dropped from the header)"
+	}
+	fields = append(fields, outputField{
+		name:   "comment",
+		source: commentSource,
+		value:  func(r *hashedRecord) string { return string(r.comment()) },
+	}, outputField{
+		name:   "read",
+		source: "read number from a CASAVA 1.8+ comment (1:N:0:<index>) or a /1 ID suffix",
+		value: func(r *hashedRecord) string {
+			read, _ := readDescriptor(r.id, r.comment())
+			return read
+		},
+	}, outputField{
+		name:   "barcode",
+		source: "index sequence from a CASAVA 1.8+ comment or a #<index>/1 ID suffix",
+		value: func(r *hashedRecord) string {
+			_, barcode := readDescriptor(r.id, r.comment())
+			return barcode
+		},
+	})
+
+	if cfg.meta != nil {
+		for i, column := range cfg.meta.columns {
+			value := cfg.meta.values[i]
+			fields = append(fields, outputField{
+				name:   "meta:" + column,
+				source: "sample sheet column " + strconv.Quote(column),
+				value:  func(*hashedRecord) string { return value },
+			})
+		}
+	}
+
+	if !cfg.HeadersOnly {
+		fields = append(fields, outputField{
+			name:      "sequence",
+			source:    sequenceSource(cfg),
+			sentinels: map[abnormalCondition]string{condEmptySequence: ""},
+		})
+		fields = append(fields, outputField{
+			name:   "quality",
+			source: "original quality scores (FASTQ only)",
+		})
+	}
+
+	return fields
+}
+
+// sequenceSource describes the normalization of the sequences
+func sequenceSource(cfg Config) string {
+	source := "sequence without whitespace, uppercased"
+	if cfg.CaseSensitive {
+		source = "sequence without whitespace"
+	}
+	if cfg.EmitCollapsed {
+		source += ", with homopolymers collapsed"
+	}
+	return source
+}
+
+// buildHeader joins the values of the header fields
+func buildHeader(fields []outputField, r *hashedRecord) []byte {
+	values := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f.inHeader && f.key != "" {
+			values = append(values, f.key+"="+f.value(r))
+		} else if f.inHeader {
+			values = append(values, f.value(r))
+		}
+	}
+	return []byte(strings.Join(values, ";"))
+}
+
+// tabularFields returns the fields that form the columns of tabular outputs
+func tabularFields(fields []outputField) []outputField {
+	var columns []outputField
+	for _, f := range fields {
+		if f.value != nil {
+			columns = append(columns, f)
+		}
+	}
+	return columns
+}
+
+// headerBuilder returns the function that builds the rewritten header of a record:
+// header fields joined with ';', or the --header-format template.
+// The annotations follow the header fields, and the comment of the original header
+// is appended verbatim, unless it was dropped (--drop-comment) or placed by the template.
+func headerBuilder(cfg Config, fields []outputField) (func(r *hashedRecord) []byte, error) {
+	if cfg.SeqkitCompat {
+		return func(r *hashedRecord) []byte { return seqkitHeader(cfg, r) }, nil
+	}
+	withComment := func(build func(r *hashedRecord) []byte) func(r *hashedRecord) []byte {
+		if cfg.DropComment || strings.Contains(cfg.HeaderFormat, "{comment}") {
+			return func(r *hashedRecord) []byte { return r.annotations.appendTo(build(r)) }
+		}
+		return func(r *hashedRecord) []byte { return append(r.annotations.appendTo(build(r)), r.tail...) }
+	}
+	if cfg.HeaderFormat == "" {
+		return withComment(func(r *hashedRecord) []byte { return buildHeader(fields, r) }), nil
+	}
+
+	// The template is split into literal text and placeholder values
+	var parts []func(r *hashedRecord) string
+	rest := cfg.HeaderFormat
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			literal := rest
+			parts = append(parts, func(*hashedRecord) string { return literal })
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("Invalid header format: unterminated placeholder in %q", cfg.HeaderFormat)
+		}
+		if start > 0 {
+			literal := rest[:start]
+			parts = append(parts, func(*hashedRecord) string { return literal })
+		}
+		value, err := placeholderValue(cfg, fields, rest[start+1:start+end])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, value)
+		rest = rest[start+end+1:]
+	}
+
+	return withComment(func(r *hashedRecord) []byte {
+		var b strings.Builder
+		for _, part := range parts {
+			b.WriteString(part(r))
+		}
+		return []byte(b.String())
+	}), nil
+}
+
+// placeholderValue resolves a --header-format placeholder:
+// {file}, {id}, {<hash type>}, {comment}, {read}, {barcode}, or {meta:<column>}
+func placeholderValue(cfg Config, fields []outputField, name string) (func(r *hashedRecord) string, error) {
+	if name == "file" {
+		// Available even when the file name is omitted from the default header
+		return func(r *hashedRecord) string { return r.label }, nil
+	}
+	for _, f := range fields {
+		if f.value != nil && f.name == name {
+			return f.value, nil
+		}
+	}
+	if strings.HasPrefix(name, "meta:") && cfg.SampleSheet != "" && cfg.meta != nil && len(cfg.meta.columns) == 0 {
+		// Metadata columns were skipped for an input missing from the sample sheet
+		return func(*hashedRecord) string { return "" }, nil
+	}
+	return nil, fmt.Errorf("Invalid header format: unknown placeholder {%s}", name)
+}
+
+// explainRows renders the field descriptions as table rows (including the column names)
+func explainRows(cfg Config) [][]string {
+	columns := []string{"FIELD", "POSITION", "SOURCE", "WIDTH"}
+	for _, c := range abnormalConditions {
+		columns = append(columns, strings.ToUpper(c.String()))
+	}
+	rows := [][]string{columns}
+
+	position := 0
+	for _, f := range outputFields(cfg) {
+		pos := "record"
+		if f.inHeader {
+			position++
+			pos = fmt.Sprintf("header %d", position)
+		} else if f.value != nil {
+			pos = "column"
+		}
+		width := "variable"
+		if f.width > 0 {
+			width = strconv.Itoa(f.width)
+		}
+		row := []string{f.name, pos, f.source, width}
+		for _, c := range abnormalConditions {
+			if sentinel, ok := f.sentinels[c]; ok {
+				row = append(row, strconv.Quote(sentinel))
+			} else {
+				row = append(row, "-")
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// explainOutput prints the description of every output field for the given options
+func explainOutput(w io.Writer, cfg Config) error {
+	fileLabel(&cfg)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range explainRows(cfg) {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nHeader fields are separated by ';'. '-' means the field is not affected by the condition.")
+	return nil
+}
@@ -0,0 +1,131 @@
+package seqhash
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// explainedSentinel returns the sentinel listed by --explain-output for a field and condition
+func explainedSentinel(t *testing.T, cfg Config, field string, cond abnormalCondition) string {
+	t.Helper()
+	rows := explainRows(cfg)
+
+	column := -1
+	for i, name := range rows[0] {
+		if name == strings.ToUpper(cond.String()) {
+			column = i
+		}
+	}
+	if column < 0 {
+		t.Fatalf("No column for condition %q", cond)
+	}
+
+	for _, row := range rows[1:] {
+		if row[0] == field {
+			sentinel, err := strconv.Unquote(row[column])
+			if err != nil {
+				t.Fatalf("Field %q has no sentinel for %q: %s", field, cond, row[column])
+			}
+			return sentinel
+		}
+	}
+	t.Fatalf("Field %q is not explained", field)
+	return ""
+}
+
+func TestExplainedSentinelsMatchOutput(t *testing.T) {
+	// Algorithm that always fails, to trigger the hash failure condition
+	hashAlgorithms["failing"] = hashAlgorithm{16, func([]byte) (string, error) {
+		return "", errors.New("simulated failure")
+	}}
+	defer delete(hashAlgorithms, "failing")
+
+	tests := []struct {
+		name  string
+		cfg   Config
+		input string
+		field string
+		index int // Position of the field in the header
+		cond  abnormalCondition
+	}{
+		{
+			name:  "Empty sequence",
+			cfg:   Config{HashTypes: []string{"sha1", "xxhash"}, HeadersOnly: true, InputFileName: "test.fasta"},
+			input: ">empty\n\n>seq1\nACTG\n",
+			field: "xxhash",
+			index: 2,
+			cond:  condEmptySequence,
+		},
+		{
+			name:  "Hash failure",
+			cfg:   Config{HashTypes: []string{"sha1", "failing"}, HeadersOnly: true, InputFileName: "test.fasta"},
+			input: ">seq1\nACTG\n",
+			field: "failing",
+			index: 2,
+			cond:  condHashFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), output, tt.cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			header := strings.SplitN(output.String(), "\n", 2)[0]
+			values := strings.Split(header, ";")
+
+			want := explainedSentinel(t, tt.cfg, tt.field, tt.cond)
+			if got := values[tt.index]; got != want {
+				t.Errorf("Field %s under %q: output has %q, explanation says %q\nHeader: %s",
+					tt.field, tt.cond, got, want, header)
+			}
+		})
+	}
+}
+
+func TestExplainOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected []string
+		absent   []string
+	}{
+		{
+			name:     "Default fields",
+			cfg:      Config{HashTypes: []string{"sha1", "nthash"}, InputFileName: "test.fasta"},
+			expected: []string{"file", "sha1", "40", "nthash", "16", "id", "sequence", "EMPTY SEQUENCE", "HASH FAILURE"},
+		},
+		{
+			name:     "Headers only from stdin",
+			cfg:      Config{HashTypes: []string{"md5"}, HeadersOnly: true, InputFileName: "-"},
+			expected: []string{"md5", "32", "id"},
+			absent:   []string{"file", "sequence"},
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := explainOutput(&buf, tt.cfg); err != nil {
+				t.Fatalf("explainOutput() error = %v", err)
+			}
+			output := buf.String()
+			for _, str := range tt.expected {
+				if !strings.Contains(output, str) {
+					t.Errorf("Expected explanation to contain %q\nGot:\n%s", str, output)
+				}
+			}
+			for _, str := range tt.absent {
+				for _, line := range strings.Split(output, "\n") {
+					if strings.HasPrefix(line, str+" ") {
+						t.Errorf("Expected explanation to omit field %q\nGot:\n%s", str, output)
+					}
+				}
+			}
+		})
+	}
+}
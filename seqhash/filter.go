@@ -0,0 +1,57 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// loadIDList reads the IDs of the records to hash (--include-id), one per line;
+// blank lines and lines starting with '#' are ignored, as is a leading '>' or '@'
+func loadIDList(fileName string) (map[string]struct{}, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ids := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimLeft(line, ">@")
+		if id, _, _ := strings.Cut(line, " "); id != "" {
+			ids[id] = struct{}{}
+		}
+	}
+	return ids, scanner.Err()
+}
+
+// selectedRecord reports whether the record passes the filters (--include-id, --min-len).
+// The length is that of the hashed sequence (normalized and, with --trim-ns, trimmed).
+func selectedRecord(id []byte, seq []byte, cfg Config) bool {
+	if cfg.includeIDs != nil {
+		if _, ok := cfg.includeIDs[string(id)]; !ok {
+			return false
+		}
+	}
+	if cfg.MinLen > 0 {
+		length := len(seq)
+		if cfg.TrimNs {
+			start, end := trimNsRange(seq)
+			length = end - start
+		}
+		if length < cfg.MinLen {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package seqhash
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPassthroughExcluded(t *testing.T) {
+	idList := filepath.Join(t.TempDir(), "ids.txt")
+	if err := os.WriteFile(idList, []byte("# IDs to hash\nkeep1\n>keep2 description\n\nshort1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sha1 := getHashFunc("sha1")
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		input   string
+		want    string
+		summary string
+	}{
+		{
+			name:  "Minimum length, FASTA",
+			cfg:   Config{MinLen: 5},
+			input: ">keep1 sample A\nacgtac\n>short1 kept as is\nacg\n>keep2\nGGGGCCCC\n>short2\nNNNN\n",
+			want: ">" + sha1([]byte("ACGTAC")) + ";keep1 sample A\nACGTAC\n" +
+				">short1 kept as is\nacg\n" +
+				">" + sha1([]byte("GGGGCCCC")) + ";keep2\nGGGGCCCC\n" +
+				">short2\nNNNN\n",
+			summary: "Hashed 2 record(s); passed through 2 excluded record(s) unchanged",
+		},
+		{
+			name:  "Minimum length, FASTQ",
+			cfg:   Config{MinLen: 5},
+			input: "@short1 1:N:0:ACGT\nacg\n+\nII#\n@keep1\nACGTAC\n+\nIIIIII\n",
+			want: "@short1 1:N:0:ACGT\nacg\n+\nII#\n" +
+				"@" + sha1([]byte("ACGTAC")) + ";keep1\nACGTAC\n+\nIIIIII\n",
+			summary: "Hashed 1 record(s); passed through 1 excluded record(s) unchanged",
+		},
+		{
+			name:  "ID list and minimum length",
+			cfg:   Config{MinLen: 4, IncludeIDFile: idList},
+			input: ">other\nACGTACGT\n>keep1\nACGT\n>short1\nACG\n>keep2 x\nTTTT\n",
+			want: ">other\nACGTACGT\n" +
+				">" + sha1([]byte("ACGT")) + ";keep1\nACGT\n" +
+				">short1\nACG\n" +
+				">" + sha1([]byte("TTTT")) + ";keep2 x\nTTTT\n",
+			summary: "Hashed 2 record(s); passed through 2 excluded record(s) unchanged",
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1"}
+			cfg.NoFileName = true
+			cfg.PassthroughExcluded = true
+			for _, threads := range []int{1, 3} {
+				cfg.Threads = threads
+				output := &bytes.Buffer{}
+				if err := processSequences(strings.NewReader(tt.input), output, cfg); err != nil {
+					t.Fatalf("processSequences() error = %v", err)
+				}
+				if output.String() != tt.want {
+					t.Errorf("threads=%d: got output:\n%s\nWant:\n%s", threads, output.String(), tt.want)
+				}
+			}
+			if !strings.Contains(logs.String(), tt.summary) {
+				t.Errorf("Summary not reported:\n%s", logs.String())
+			}
+		})
+	}
+}
+
+func TestExcludedRecordsDropped(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, HeadersOnly: true, MinLen: 5, TrimNs: true}
+	output := &bytes.Buffer{}
+	input := ">a\nNNACGTNN\n>b\nNNACGTANN\n"
+	if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	// The length is counted after --trim-ns
+	if want := getHashFunc("sha1")([]byte("ACGTA")) + ";b\n"; output.String() != want {
+		t.Errorf("Got %q, want %q", output.String(), want)
+	}
+	if !strings.Contains(logs.String(), "Hashed 1 record(s); dropped 1 excluded record(s)") {
+		t.Errorf("Summary not reported:\n%s", logs.String())
+	}
+}
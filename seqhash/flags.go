@@ -0,0 +1,112 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// strictFlagsRequested reports whether the arguments enable --strict-flags.
+// It is looked up before parsing, as the flag package stops at the first unknown flag.
+func strictFlagsRequested(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "strict-flags" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		return !hasValue || err != nil || enabled // Invalid values are reported by the parser
+	}
+	return false
+}
+
+// checkFlags reports undefined flags with a suggestion, before the flag package fails on them.
+// With --strict-flags, options after the input and output files are checked as well, and are errors:
+// the flag package stops parsing at the first non-option argument, so it would silently take them for file names.
+func checkFlags(fs *flag.FlagSet, args []string, strict bool) error {
+	files := false // A non-option argument was seen
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			if !strict {
+				break
+			}
+			files = true
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f := fs.Lookup(name)
+		if f == nil && name != "h" && name != "help" {
+			return unknownFlagError(fs, name)
+		}
+		if files {
+			return fmt.Errorf("Option --%s after the input and output files would be taken for a file name; options must come before the files", name)
+		}
+		if f != nil && !hasValue && !isBoolFlag(f) {
+			i++ // The value is the next argument
+		}
+	}
+	return nil
+}
+
+// unknownFlagError reports an undefined flag, suggesting the closest defined one
+func unknownFlagError(fs *flag.FlagSet, name string) error {
+	if suggestion := suggestFlag(fs, name); suggestion != "" {
+		return fmt.Errorf("Unknown option --%s (did you mean --%s?)", name, suggestion)
+	}
+	return fmt.Errorf("Unknown option --%s (see --help for the list of options)", name)
+}
+
+// suggestFlag returns the defined flag with the smallest edit distance to name,
+// or an empty string if no flag is close enough to be a likely typo
+func suggestFlag(fs *flag.FlagSet, name string) string {
+	best, bestDistance := "", max(1, len(name)/3)+1
+	fs.VisitAll(func(f *flag.Flag) { // In lexicographical order, so ties go to the first name
+		if d := editDistance(name, f.Name); d < bestDistance {
+			best, bestDistance = f.Name, d
+		}
+	})
+	return best
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// the number of insertions, deletions, substitutions, and transpositions of adjacent characters
+func editDistance(a, b string) int {
+	// Three rows of the dynamic programming matrix (transpositions look two rows back)
+	prev2, prev, cur := make([]int, len(b)+1), make([]int, len(b)+1), make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
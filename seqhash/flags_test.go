@@ -0,0 +1,63 @@
+package seqhash
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"hash", "hash", 0},
+		{"hsah", "hash", 1}, // Transposition
+		{"hashh", "hash", 1},
+		{"has", "hash", 1},
+		{"dedpu-stats", "dedup-stats", 1},
+		{"sizeot", "sizeout", 1},
+		{"", "hash", 4},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if d := editDistance(tt.a, tt.b); d != tt.distance {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, d, tt.distance)
+		}
+	}
+}
+
+func TestStrictFlags(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		expectedErrMsg string
+	}{
+		{"Typo", []string{"-hsah", "md5", "input.fasta"}, "Unknown option --hsah (did you mean --hash?)"},
+		{"Typo with a value", []string{"--out-fromat=json", "input.fasta"}, "Unknown option --out-fromat (did you mean --out-format?)"},
+		{"No close option", []string{"--xyzzy", "input.fasta"}, "Unknown option --xyzzy (see --help for the list of options)"},
+		{"Options after the files", []string{"input.fasta", "output.fasta", "--hash", "md5"}, ""},
+		{"Strict, options after the files", []string{"--strict-flags", "input.fasta", "output.fasta", "--hash", "md5"},
+			"Option --hash after the input and output files would be taken for a file name; options must come before the files"},
+		{"Strict, typo after the files", []string{"-strict-flags", "input.fasta", "--hahs", "md5"}, "Unknown option --hahs (did you mean --hash?)"},
+		{"Strict, values starting with '-'", []string{"--strict-flags", "--record-delimiter", "-", "-n", "input.fasta", "-"}, ""},
+		{"Strict, files after --", []string{"--strict-flags", "--", "-input.fasta"}, ""},
+		{"Strict disabled", []string{"--strict-flags=false", "input.fasta", "output.fasta", "--hash", "md5"}, ""},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("seqhasher", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			_, err := parseArgs(fs, tt.args)
+			if tt.expectedErrMsg == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expectedErrMsg {
+				t.Errorf("Expected error %q, got %v", tt.expectedErrMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Default limit on the number of --group-by groups
+const defaultMaxGroups = 1000
+
+// Group of the records whose header does not match the --group-by pattern
+const unmatchedGroup = "(unmatched)"
+
+// Approximate memory taken by a digest in an exact set (map entry overhead, besides the digest itself)
+const exactSetEntryOverhead = 48
+
+// Summary of a --group-by group (a row of the --group-report, or an item of the JSON summary)
+type groupSummary struct {
+	Group     string `json:"group"`
+	Records   int64  `json:"records"`
+	Bases     int64  `json:"bases"`
+	Unique    *int64 `json:"unique_digests,omitempty"`   // With --group-unique
+	Estimated bool   `json:"unique_estimated,omitempty"` // Counted with a sketch (see --max-memory)
+}
+
+type headerGroup struct {
+	records int64
+	bases   int64
+	digests map[string]struct{} // Exact set of digests, until the memory limit is reached
+	sketch  *hllSketch          // Then, the estimate
+}
+
+// headerGroups summarizes the records by a key captured from their headers (--group-by).
+// Unique digests (--group-unique) are counted exactly while the sets fit in --max-memory;
+// past that, all groups switch to HyperLogLog sketches (about 1.6% error).
+type headerGroups struct {
+	re          *regexp.Regexp
+	maxGroups   int // 0 means no limit
+	unique      bool
+	memoryLimit int64 // 0 means no limit
+	memory      int64 // Estimated size of the exact sets
+	sketched    bool
+	groups      map[string]*headerGroup
+}
+
+// compileGroupPattern validates the --group-by pattern
+func compileGroupPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid group pattern: %v", err)
+	}
+	if re.NumSubexp() < 1 {
+		return nil, fmt.Errorf("Invalid group pattern: %s. It must contain a capture group for the group key", pattern)
+	}
+	return re, nil
+}
+
+// newHeaderGroups returns nil if grouping was not requested
+func newHeaderGroups(cfg Config) (*headerGroups, error) {
+	if cfg.GroupBy == "" {
+		return nil, nil
+	}
+	re, err := compileGroupPattern(cfg.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	return &headerGroups{
+		re:          re,
+		maxGroups:   cfg.MaxGroups,
+		unique:      cfg.GroupUnique,
+		memoryLimit: cfg.MaxMemory,
+		groups:      make(map[string]*headerGroup),
+	}, nil
+}
+
+// key returns the first capture group of the pattern in the original header
+func (hg *headerGroups) key(header []byte) string {
+	match := hg.re.FindSubmatch(header)
+	if match == nil || len(match[1]) == 0 {
+		return unmatchedGroup
+	}
+	return string(match[1])
+}
+
+// add counts a record; digest is empty for records without a primary digest (e.g., empty sequences)
+func (hg *headerGroups) add(header []byte, digest string, bases int, recordID []byte) error {
+	key := hg.key(header)
+	g, ok := hg.groups[key]
+	if !ok {
+		if hg.maxGroups > 0 && len(hg.groups) >= hg.maxGroups {
+			return fmt.Errorf("--group-by found more than %d groups (group %q of record %q); "+
+				"the pattern probably captures a per-record value (raise the limit with --max-groups)",
+				hg.maxGroups, key, recordID)
+		}
+		g = &headerGroup{}
+		if hg.unique {
+			if hg.sketched {
+				g.sketch = newHLLSketch()
+			} else {
+				g.digests = make(map[string]struct{})
+			}
+		}
+		hg.groups[key] = g
+	}
+	g.records++
+	g.bases += int64(bases)
+
+	if !hg.unique || digest == "" {
+		return nil
+	}
+	if g.sketch != nil {
+		g.sketch.add(xxhash.Sum64String(digest))
+		return nil
+	}
+	if _, ok := g.digests[digest]; !ok {
+		g.digests[digest] = struct{}{}
+		hg.memory += int64(len(digest)) + exactSetEntryOverhead
+		if hg.memoryLimit > 0 && hg.memory > hg.memoryLimit {
+			hg.switchToSketches()
+		}
+	}
+	return nil
+}
+
+// switchToSketches replaces the exact sets of all groups by sketches
+func (hg *headerGroups) switchToSketches() {
+	for _, g := range hg.groups {
+		if g.digests == nil {
+			continue
+		}
+		g.sketch = newHLLSketch()
+		for digest := range g.digests {
+			g.sketch.add(xxhash.Sum64String(digest))
+		}
+		g.digests = nil
+	}
+	hg.memory = 0
+	hg.sketched = true
+}
+
+// summaries returns the groups sorted by key, with the unmatched records last
+func (hg *headerGroups) summaries() []groupSummary {
+	keys := make([]string, 0, len(hg.groups))
+	for key := range hg.groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == unmatchedGroup) != (keys[j] == unmatchedGroup) {
+			return keys[j] == unmatchedGroup
+		}
+		return keys[i] < keys[j]
+	})
+
+	summaries := make([]groupSummary, len(keys))
+	for i, key := range keys {
+		g := hg.groups[key]
+		summaries[i] = groupSummary{Group: key, Records: g.records, Bases: g.bases}
+		if hg.unique {
+			unique := int64(len(g.digests))
+			if g.sketch != nil {
+				unique = g.sketch.estimate()
+				summaries[i].Estimated = true
+			}
+			summaries[i].Unique = &unique
+		}
+	}
+	return summaries
+}
+
+// writeReport writes the group summaries as TSV (--group-report)
+func (hg *headerGroups) writeReport(fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	columns := []string{"group", "records", "bases"}
+	if hg.unique {
+		columns = append(columns, "unique_digests", "unique_estimated")
+	}
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+	for _, s := range hg.summaries() {
+		row := []string{tsvEscaper.Replace(s.Group), strconv.FormatInt(s.Records, 10), strconv.FormatInt(s.Bases, 10)}
+		if s.Unique != nil {
+			row = append(row, strconv.FormatInt(*s.Unique, 10), strconv.FormatBool(s.Estimated))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Number of index bits of the HyperLogLog sketches (4096 one-byte registers)
+const hllPrecision = 12
+
+// hllSketch estimates the number of distinct 64-bit hashes (HyperLogLog)
+type hllSketch struct {
+	registers [1 << hllPrecision]uint8
+}
+
+func newHLLSketch() *hllSketch {
+	return &hllSketch{}
+}
+
+func (s *hllSketch) add(h uint64) {
+	index := h >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(h<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+func (s *hllSketch) estimate() int64 {
+	const m = float64(1 << hllPrecision)
+	sum, zeros := 0.0, 0
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros)) // Linear counting for small cardinalities
+	}
+	return int64(math.Round(estimate))
+}
+
+// parseByteSize parses sizes such as 512M or 2G (binary units; plain numbers are bytes)
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B"), "I")
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("KMGT", s[n-1]); i >= 0 {
+			multiplier = 1 << (10 * (i + 1))
+			s = s[:n-1]
+		}
+	}
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || value < 0 || value > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid size")
+	}
+	return value * multiplier, nil
+}
@@ -0,0 +1,158 @@
+package seqhash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Three groups (sampleA, sampleB, sampleC), a duplicate sequence within sampleA,
+// an empty sequence, and a header that does not match the pattern
+const groupedFasta = ">sampleA|gut|ASV_001\nACTG\n" +
+	">sampleA|gut|ASV_002\nACTG\n" +
+	">sampleA|gut|ASV_003\nTGCAA\n" +
+	">sampleB|skin|ASV_001\nACTG\n" +
+	">sampleB|skin|ASV_004\n\n" +
+	">sampleC|soil|ASV_005\nGGGCCC\n" +
+	">orphan\nAC\n"
+
+func TestGroupBy(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantRows  [][]string
+		wantError string
+	}{
+		{
+			name: "Counts",
+			cfg:  Config{},
+			wantRows: [][]string{
+				{"group", "records", "bases"},
+				{"sampleA", "3", "13"},
+				{"sampleB", "2", "4"},
+				{"sampleC", "1", "6"},
+				{"(unmatched)", "1", "2"},
+			},
+		},
+		{
+			name: "Exact unique digests",
+			cfg:  Config{GroupUnique: true},
+			wantRows: [][]string{
+				{"group", "records", "bases", "unique_digests", "unique_estimated"},
+				{"sampleA", "3", "13", "2", "false"},
+				{"sampleB", "2", "4", "1", "false"},
+				{"sampleC", "1", "6", "1", "false"},
+				{"(unmatched)", "1", "2", "1", "false"},
+			},
+		},
+		{
+			// The first digest already exceeds the limit, so all groups are sketched
+			name: "Sketched unique digests",
+			cfg:  Config{GroupUnique: true, MaxMemory: 1},
+			wantRows: [][]string{
+				{"group", "records", "bases", "unique_digests", "unique_estimated"},
+				{"sampleA", "3", "13", "2", "true"},
+				{"sampleB", "2", "4", "1", "true"},
+				{"sampleC", "1", "6", "1", "true"},
+				{"(unmatched)", "1", "2", "1", "true"},
+			},
+		},
+		{
+			name:      "Too many groups",
+			cfg:       Config{MaxGroups: 2},
+			wantError: `--group-by found more than 2 groups (group "sampleC" of record "sampleC|soil|ASV_005")`,
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			reportPath := filepath.Join(t.TempDir(), "groups.tsv")
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1"}
+			cfg.GroupBy = `^([^|]+)\|`
+			cfg.GroupReport = reportPath
+
+			err := processSequences(strings.NewReader(groupedFasta), &bytes.Buffer{}, cfg)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("processSequences() error = %v, want error containing %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if rows := readTSV(t, reportPath); !equalRows(rows, tt.wantRows) {
+				t.Errorf("Got report:\n%v\nWant:\n%v", rows, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestGroupByJSONSummary(t *testing.T) {
+	cfg := Config{HashTypes: []string{"sha1"}, OutFormat: "json", JsonSummary: true, HeadersOnly: true,
+		GroupBy: `^[^|]+\|([^|]+)\|`, GroupUnique: true}
+	output := &bytes.Buffer{}
+	if err := processSequences(strings.NewReader(groupedFasta), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+
+	var document struct {
+		Summary jsonSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(output.Bytes(), &document); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, output.String())
+	}
+	var got []string
+	for _, g := range document.Summary.Groups {
+		got = append(got, fmt.Sprintf("%s:%d:%d:%d", g.Group, g.Records, g.Bases, *g.Unique))
+	}
+	want := []string{"gut:3:13:2", "skin:2:4:1", "soil:1:6:1", "(unmatched):1:2:1"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Got groups %v, want %v", got, want)
+	}
+}
+
+func TestHLLSketch(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		s := newHLLSketch()
+		for i := 0; i < n; i++ {
+			digest := getHashFunc("sha1")([]byte(fmt.Sprint(i)))
+			s.add(xxhash.Sum64String(digest))
+			s.add(xxhash.Sum64String(digest)) // Duplicates are not counted
+		}
+		estimate := s.estimate()
+		if diff := float64(estimate-int64(n)) / float64(n); diff > 0.05 || diff < -0.05 {
+			t.Errorf("estimate() = %d for %d distinct values", estimate, n)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"1024", 1024},
+		{"512K", 512 << 10},
+		{"512M", 512 << 20},
+		{"2g", 2 << 30},
+		{"2GiB", 2 << 30},
+		{"1T", 1 << 40},
+	}
+	for _, tt := range tests {
+		if got, err := parseByteSize(tt.input); err != nil || got != tt.want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"", "M", "-1K", "1.5G", "lots", "99999999999T"} {
+		if _, err := parseByteSize(input); err == nil {
+			t.Errorf("parseByteSize(%q) succeeded, want error", input)
+		}
+	}
+}
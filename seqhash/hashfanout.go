@@ -0,0 +1,132 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Sequences of at least this many bases have their digests computed concurrently
+const defaultFanoutThreshold = 4096
+
+// hashFanout computes the digests of long sequences concurrently,
+// one algorithm per goroutine of a small pool that is reused across records
+type hashFanout struct {
+	algorithms []hashAlgorithm
+	threshold  int
+	jobs       chan fanoutJob
+}
+
+// Digests of one record being computed by the pool
+type fanoutRecord struct {
+	seq    []byte
+	hashes []string
+	failed atomic.Bool // Set on the first error; pending algorithms are skipped
+	wg     sync.WaitGroup
+}
+
+type fanoutJob struct {
+	record *fanoutRecord
+	index  int // Position of the algorithm in the requested hash types
+}
+
+// newHashFanout starts the worker pool, or returns nil if concurrent hashing would not help:
+// with a single hash type, with the threshold set to 0, or when recordWorkers
+// (goroutines that already process records in parallel) occupy all CPUs
+func newHashFanout(cfg Config, recordWorkers int) *hashFanout {
+	workers := cfg.FanoutWorkers
+	if workers <= 0 {
+		// The calling goroutine computes the first digest itself
+		workers = min(len(cfg.HashTypes)-1, runtime.GOMAXPROCS(0)-recordWorkers)
+	}
+	if cfg.FanoutThreshold <= 0 || len(cfg.HashTypes) < 2 || workers < 1 ||
+		recordWorkers >= runtime.GOMAXPROCS(0) {
+		return nil
+	}
+
+	f := &hashFanout{threshold: cfg.FanoutThreshold, jobs: make(chan fanoutJob)}
+	for _, hashType := range cfg.HashTypes {
+		algorithm, ok := hashAlgorithms[hashType]
+		if !ok { // Default to SHA1, as getHashFunc does
+			algorithm = hashAlgorithms[defaultHashType]
+		}
+		f.algorithms = append(f.algorithms, algorithm)
+	}
+	for i := 0; i < workers; i++ {
+		go f.work()
+	}
+	return f
+}
+
+func (f *hashFanout) work() {
+	for job := range f.jobs {
+		f.compute(job.record, job.index)
+	}
+}
+
+func (f *hashFanout) compute(r *fanoutRecord, i int) {
+	defer r.wg.Done()
+	if r.failed.Load() {
+		return // Another algorithm failed, this record is hashed serially
+	}
+	hash, err := f.algorithms[i].sum(r.seq)
+	if err != nil {
+		r.failed.Store(true)
+		return
+	}
+	r.hashes[i] = hash
+}
+
+// Close stops the worker pool
+func (f *hashFanout) Close() {
+	if f != nil {
+		close(f.jobs)
+	}
+}
+
+// computeHashes returns the digests of the sequence, in the order of the requested hash types.
+// The result is identical to the serial computation: if any algorithm fails,
+// the others are cancelled and the record falls back to the serial path,
+// which substitutes the sentinel values (and reports the failure).
+func computeHashes(seq []byte, cfg Config, fanout *hashFanout) []string {
+	if cfg.TreeChunk > 0 {
+		roots, _ := treeHashes(seq, cfg)
+		return roots
+	}
+	// The type is chosen by the length of the sequence itself, without the length prefix
+	if cfg.AutoHash {
+		hashType := autoHashType(len(seq), cfg.AutoHashThreshold)
+		if cfg.LengthPrefix && len(seq) > 0 {
+			seq = lengthPrefixed(seq)
+		}
+		return []string{getHashFunc(hashType)(seq)}
+	}
+	// Empty sequences have no digests, with or without the length
+	if cfg.LengthPrefix && len(seq) > 0 {
+		seq = lengthPrefixed(seq)
+	}
+	if fanout != nil && len(seq) >= fanout.threshold {
+		r := &fanoutRecord{seq: seq, hashes: make([]string, len(cfg.HashTypes))}
+		r.wg.Add(len(cfg.HashTypes))
+		for i := 1; i < len(cfg.HashTypes); i++ {
+			fanout.jobs <- fanoutJob{record: r, index: i}
+		}
+		fanout.compute(r, 0)
+		r.wg.Wait()
+		if !r.failed.Load() {
+			return r.hashes
+		}
+	}
+
+	hashes := make([]string, 0, len(cfg.HashTypes))
+	for _, hashType := range cfg.HashTypes {
+		hashFunc := getHashFunc(hashType)
+		hashes = append(hashes, hashFunc(seq))
+	}
+	return hashes
+}
@@ -0,0 +1,129 @@
+package seqhash
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+var fiveHashTypes = []string{"sha1", "sha3", "md5", "blake3", "murmur3"}
+
+// randomRecords returns FASTA records with random sequences of the given length
+func randomRecords(n, length int) string {
+	rng := rand.New(rand.NewSource(1))
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString(">seq\n")
+		for j := 0; j < length; j++ {
+			b.WriteByte("ACGT"[rng.Intn(4)])
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestFanoutMatchesSerial(t *testing.T) {
+	// Records below and above the threshold
+	input := randomRecords(20, 10000) + randomRecords(5, 100) + ">empty\n\n"
+
+	serial := &bytes.Buffer{}
+	cfg := Config{HashTypes: fiveHashTypes, HeadersOnly: true, InputFileName: "test.fasta"}
+	if err := processSequences(strings.NewReader(input), serial, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 2, 4} {
+		cfg.FanoutThreshold = 50
+		cfg.FanoutWorkers = workers
+		parallel := &bytes.Buffer{}
+		if err := processSequences(strings.NewReader(input), parallel, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(serial.Bytes(), parallel.Bytes()) {
+			t.Errorf("Output with %d fan-out workers differs from the serial output", workers)
+		}
+	}
+}
+
+func TestFanoutFailure(t *testing.T) {
+	hashAlgorithms["failing"] = hashAlgorithm{16, func([]byte) (string, error) {
+		return "", errors.New("simulated failure")
+	}}
+	defer delete(hashAlgorithms, "failing")
+
+	cfg := Config{HashTypes: []string{"sha1", "failing", "md5"}, FanoutThreshold: 1, FanoutWorkers: 2}
+	fanout := newHashFanout(cfg, 0)
+	if fanout == nil {
+		t.Fatal("Expected the fan-out to be enabled")
+	}
+	defer fanout.Close()
+
+	seq := []byte("ACTG")
+	got := computeHashes(seq, cfg, fanout)
+	want := computeHashes(seq, cfg, nil)
+	if strings.Join(got, ";") != strings.Join(want, ";") {
+		t.Errorf("Fan-out hashes %v differ from serial ones %v", got, want)
+	}
+	if got[1] != hashSentinels[condHashFailure] {
+		t.Errorf("Expected the hash failure sentinel, got %q", got[1])
+	}
+
+	// Algorithms of a failed record are skipped
+	calls := 0
+	hashAlgorithms["counting"] = hashAlgorithm{16, func([]byte) (string, error) {
+		calls++
+		return "x", nil
+	}}
+	defer delete(hashAlgorithms, "counting")
+	counting := newHashFanout(Config{HashTypes: []string{"counting", "counting"}, FanoutThreshold: 1, FanoutWorkers: 1}, 0)
+	defer counting.Close()
+	r := &fanoutRecord{seq: seq, hashes: make([]string, 2)}
+	r.failed.Store(true)
+	r.wg.Add(1)
+	counting.compute(r, 0)
+	if calls != 0 {
+		t.Errorf("Expected no computation after a failure, got %d calls", calls)
+	}
+}
+
+func TestFanoutDisabled(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           Config
+		recordWorkers int
+	}{
+		{"Single hash type", Config{HashTypes: []string{"sha1"}, FanoutThreshold: 1}, 1},
+		{"Zero threshold", Config{HashTypes: fiveHashTypes}, 1},
+		{"Saturated by record workers", Config{HashTypes: fiveHashTypes, FanoutThreshold: 1}, 1 << 20},
+	}
+	for _, tt := range tests {
+		if f := newHashFanout(tt.cfg, tt.recordWorkers); f != nil {
+			f.Close()
+			t.Errorf("%s: expected the fan-out to be disabled", tt.name)
+		}
+	}
+}
+
+// Latency of hashing a 10 kb record with five algorithms
+func BenchmarkHashes5Algorithms10kb(b *testing.B) {
+	seq := []byte(strings.Split(randomRecords(1, 10000), "\n")[1])
+	cfg := Config{HashTypes: fiveHashTypes, FanoutThreshold: defaultFanoutThreshold}
+
+	b.Run("Serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			computeHashes(seq, cfg, nil)
+		}
+	})
+	b.Run("Fanout", func(b *testing.B) {
+		fanout := newHashFanout(cfg, 1)
+		if fanout == nil {
+			b.Skip("Fan-out is disabled on a single CPU")
+		}
+		defer fanout.Close()
+		for i := 0; i < b.N; i++ {
+			computeHashes(seq, cfg, fanout)
+		}
+	})
+}
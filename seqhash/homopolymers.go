@@ -0,0 +1,40 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+// collapseHomopolymers reduces each run of identical bases to a single base
+// (--collapse-homopolymers), so that e.g. 'AAACCCTG' becomes 'ACTG'.
+// Sequences that differ only in the lengths of their homopolymers
+// (a common error of nanopore and 454 reads) thus have the same digests.
+// Bases are compared as bytes, so with --casesensitive, 'aA' is not a run.
+func collapseHomopolymers(seq []byte) []byte {
+	collapsed, _ := collapseRuns(seq, nil)
+	return collapsed
+}
+
+// collapseRuns collapses the homopolymers of a sequence and, if they have the same length,
+// its quality scores, keeping the quality of the first base of each run (--emit-collapsed).
+// The sequence is copied, as it may share its bytes with the record.
+func collapseRuns(seq, qual []byte) ([]byte, []byte) {
+	if len(qual) != len(seq) {
+		qual = nil
+	}
+	collapsed := make([]byte, 0, len(seq))
+	var collapsedQual []byte
+	if qual != nil {
+		collapsedQual = make([]byte, 0, len(seq))
+	}
+	for i, c := range seq {
+		if i > 0 && seq[i-1] == c {
+			continue
+		}
+		collapsed = append(collapsed, c)
+		if qual != nil {
+			collapsedQual = append(collapsedQual, qual[i])
+		}
+	}
+	return collapsed, collapsedQual
+}
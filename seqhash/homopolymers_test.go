@@ -0,0 +1,64 @@
+package seqhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCollapseRuns(t *testing.T) {
+	tests := []struct{ seq, qual, want, wantQual string }{
+		{"AAACCCTG", "ABCDEFGH", "ACTG", "ADGH"},
+		{"ACTG", "", "ACTG", ""},
+		{"NNNANNN", "", "NAN", ""},
+		{"aAaa", "", "aAa", ""}, // Bytes are compared as-is
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		seq, qual := collapseRuns([]byte(tt.seq), []byte(tt.qual))
+		if string(seq) != tt.want || string(qual) != tt.wantQual {
+			t.Errorf("collapseRuns(%q, %q) = %q, %q, want %q, %q", tt.seq, tt.qual, seq, qual, tt.want, tt.wantQual)
+		}
+	}
+}
+
+func TestCollapseHomopolymers(t *testing.T) {
+	run := func(input string, cfg Config) string {
+		cfg.HashTypes = []string{"sha1", "xxhash"}
+		cfg.NoFileName = true
+		output := &bytes.Buffer{}
+		if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		return output.String()
+	}
+
+	header := func(output string) string { return strings.SplitN(output, "\n", 2)[0] }
+	collapsed := run(">s\nAAACCCTG\n", Config{CollapseHomopolymers: true})
+	if plain := run(">s\nACTG\n", Config{}); header(collapsed) != header(plain) {
+		t.Errorf("Expected AAACCCTG to hash as ACTG, got %s, want %s", header(collapsed), header(plain))
+	}
+	if !strings.HasSuffix(collapsed, "\nAAACCCTG\n") {
+		t.Errorf("Expected the sequence to be output as-is, got:\n%s", collapsed)
+	}
+	if run(">s\naaaCCctg\n", Config{CollapseHomopolymers: true}) != collapsed {
+		t.Error("Expected lowercase runs to collapse after uppercasing")
+	}
+	if run(">s\nAAACCCTG\n", Config{}) == collapsed {
+		t.Error("Expected homopolymers to be hashed as-is by default")
+	}
+
+	runTest(t, "Collapsed output", func(t *testing.T) {
+		output := run("@s\nAAACCCTG\n+\nABCDEFGH\n", Config{CollapseHomopolymers: true, EmitCollapsed: true, SpotCheck: 1})
+		if lines := strings.Split(output, "\n"); lines[1] != "ACTG" || lines[3] != "ADGH" {
+			t.Errorf("Expected the collapsed sequence with the qualities of the first bases of the runs, got:\n%s", output)
+		}
+	})
+
+	runTest(t, "Deduplication", func(t *testing.T) {
+		output := run(">a\nACCTG\n>b\nAACTTG\n>c\nACTGG\n>d\nACGT\n", Config{CollapseHomopolymers: true, Dedup: true, HeadersOnly: true})
+		if lines := strings.Split(strings.TrimSpace(output), "\n"); len(lines) != 2 {
+			t.Errorf("Expected reads differing in homopolymer lengths to collapse, got:\n%s", output)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// countingWriter keeps track of the number of bytes written through it
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	tap *bytes.Buffer // Receives a copy of the written bytes, if set (--spot-check)
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	if c.tap != nil {
+		c.tap.Write(p[:n])
+	}
+	return n, err
+}
+
+// indexWriter writes the output index, one line per record:
+// ID, hashes (';'-separated), byte offset, and record length in bytes.
+// Offsets refer to the uncompressed output stream.
+type indexWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func newIndexWriter(fileName string) (*indexWriter, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &indexWriter{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+func (ix *indexWriter) add(id []byte, hashes []string, offset, length int64) error {
+	_, err := fmt.Fprintf(ix.writer, "%s\t%s\t%d\t%d\n", id, strings.Join(hashes, ";"), offset, length)
+	return err
+}
+
+// Close flushes the index and closes the file (subsequent calls are no-ops)
+func (ix *indexWriter) Close() error {
+	if ix.file == nil {
+		return nil
+	}
+	err := ix.writer.Flush()
+	if cerr := ix.file.Close(); err == nil {
+		err = cerr
+	}
+	ix.file = nil
+	return err
+}
@@ -0,0 +1,75 @@
+package seqhash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestOutputIndex(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"Full records", Config{HashTypes: []string{"sha1"}, InputFileName: "test.fasta"}},
+		{"Headers only", Config{HashTypes: []string{"md5", "xxhash"}, HeadersOnly: true, InputFileName: "test.fasta"}},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			indexPath := filepath.Join(t.TempDir(), "out.idx")
+			tt.cfg.IndexFileName = indexPath
+
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(testSequences), output, tt.cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+
+			content, err := os.ReadFile(indexPath)
+			if err != nil {
+				t.Fatalf("Failed to read index: %v", err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+
+			expectedIDs := []string{"seq1", "seq1_lowercase", "seq2"}
+			if len(lines) != len(expectedIDs) {
+				t.Fatalf("Expected %d index lines, got %d:\n%s", len(expectedIDs), len(lines), content)
+			}
+
+			data := output.Bytes()
+			var end int64
+			for i, line := range lines {
+				parts := strings.Split(line, "\t")
+				if len(parts) != 4 {
+					t.Fatalf("Malformed index line: %q", line)
+				}
+				offset, _ := strconv.ParseInt(parts[2], 10, 64)
+				length, _ := strconv.ParseInt(parts[3], 10, 64)
+
+				if parts[0] != expectedIDs[i] {
+					t.Errorf("Index line %d: expected ID %q, got %q", i+1, expectedIDs[i], parts[0])
+				}
+				if offset != end {
+					t.Errorf("Index line %d: expected offset %d, got %d", i+1, end, offset)
+				}
+				end = offset + length
+
+				// The indexed bytes must be exactly one record, starting with its header
+				record := string(data[offset:end])
+				if !strings.HasSuffix(strings.SplitN(record, "\n", 2)[0], ";"+expectedIDs[i]) {
+					t.Errorf("Index line %d does not locate record %q, got %q", i+1, expectedIDs[i], record)
+				}
+				if !strings.Contains(record, parts[1]) {
+					t.Errorf("Index line %d: hashes %q not found in record %q", i+1, parts[1], record)
+				}
+			}
+
+			if end != int64(len(data)) {
+				t.Errorf("Index covers %d bytes, output has %d", end, len(data))
+			}
+		})
+	}
+}
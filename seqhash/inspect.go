@@ -0,0 +1,308 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const defaultInspectBytes = 8 << 20 // Amount of decompressed data sampled per input
+
+// Summary of an input file, based on a sample of its first records
+type inspectReport struct {
+	File             string        `json:"file"`
+	Codec            string        `json:"codec"`
+	Format           string        `json:"format"`
+	SampleBytes      int64         `json:"sample_bytes"`      // decompressed bytes read
+	Complete         bool          `json:"complete"`          // the whole input fit into the sample
+	SampledRecords   int64         `json:"sampled_records"`   // complete records in the sample
+	EstimatedRecords *int64        `json:"estimated_records"` // unknown for streams of unknown size
+	MinLength        int           `json:"min_length"`
+	MaxLength        int           `json:"max_length"`
+	Lowercase        bool          `json:"lowercase"`
+	Ambiguous        bool          `json:"ambiguous"` // characters other than A, C, G, T
+	FirstID          string        `json:"first_id"`
+	Digest           *digestLayout `json:"digest,omitempty"` // in headers rewritten by seqhasher
+	Error            string        `json:"error,omitempty"`
+
+	headers []string // of the first records, for the detection of the digest field
+}
+
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// runInspect implements the read-only `seqhasher inspect FILE...` subcommand
+func runInspect(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	sampleSize := fs.Int64("inspect-bytes", defaultInspectBytes, "Maximum number of decompressed bytes read from each input")
+	hashField := fs.Int("hash-field", 0, "Field of the headers holding the digest (1-based; detected by default)")
+	delimiter := fs.String("delimiter", "", "Delimiter of the header fields (detected by default)")
+	verbose := fs.Bool("verbose", false, "Report how the digest field was detected")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: seqhasher inspect [--json] [--inspect-bytes N] [--hash-field N] [--delimiter D] [--verbose] <input_file>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *sampleSize <= 0 {
+		return fmt.Errorf("Invalid sample size: %d. Must be a positive number", *sampleSize)
+	}
+	if *hashField < 0 {
+		return fmt.Errorf("Invalid hash field: %d. Must be a positive number", *hashField)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("No input files given")
+	}
+
+	reports := make([]*inspectReport, 0, fs.NArg())
+	failed := 0
+	for _, fileName := range fs.Args() {
+		report := inspectFile(fileName, *sampleSize)
+		if report.Error == "" {
+			inspectDigest(report, *delimiter, *hashField, *verbose)
+		}
+		if report.Error != "" {
+			failed++
+		}
+		reports = append(reports, report)
+	}
+
+	var err error
+	if *asJSON {
+		err = writeInspectJSON(w, reports)
+	} else {
+		err = writeInspectTable(w, reports, *sampleSize)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("Inspection failed for %d of %d input(s)", failed, len(reports))
+	}
+	return nil
+}
+
+// inspectFile samples the first sampleSize decompressed bytes of the input.
+// Decompression and parsing go through the same code as a regular run.
+func inspectFile(fileName string, sampleSize int64) *inspectReport {
+	report := &inspectReport{File: fileName}
+
+	var src io.ReadCloser = os.Stdin
+	size := int64(-1)
+	if fileName != "-" {
+		file, err := os.Open(fileName)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			size = info.Size()
+		}
+		src = file
+	}
+	raw := &countingReader{ReadCloser: src}
+	decoded, err := decodeInput(raw, fileName)
+	if err != nil {
+		src.Close()
+		report.Error = err.Error()
+		return report
+	}
+	defer decoded.Close()
+	report.Codec = decoded.codec
+
+	buffered := bufio.NewReader(decoded)
+	header, _ := buffered.Peek(64)
+	report.Format = detectFormat(header)
+
+	sample := &io.LimitedReader{R: buffered, N: sampleSize}
+	err = inspectRecords(sample, report)
+	report.SampleBytes = sampleSize - sample.N
+
+	// The sample is complete if nothing is left after it
+	if sample.N > 0 {
+		report.Complete = true
+	} else if _, perr := buffered.Peek(1); perr == io.EOF {
+		report.Complete = true
+	}
+	if err != nil && report.Complete {
+		// Errors in a truncated sample are expected at its end
+		report.Error = err.Error()
+		return report
+	}
+
+	if report.Complete {
+		estimated := report.SampledRecords
+		report.EstimatedRecords = &estimated
+	} else if size > 0 && report.SampleBytes > 0 {
+		consumed := report.SampleBytes
+		if decoded.codec != "none" {
+			consumed = raw.n // compressed bytes behind the sample (approximate due to read-ahead)
+		}
+		estimated := int64(float64(report.SampledRecords) * float64(size) / float64(consumed))
+		report.EstimatedRecords = &estimated
+	}
+	return report
+}
+
+// inspectDigest detects the digest field in the sampled headers.
+// Inputs without digests are not an error, unless the field was given explicitly.
+func inspectDigest(report *inspectReport, delimiter string, field int, verbose bool) {
+	layout, err := detectDigestLayout(report.headers, delimiter, field)
+	switch {
+	case err == nil:
+		report.Digest = layout
+		if verbose {
+			log.Printf("%s: digest in %s, detected from %d header(s)", report.File, layout, len(report.headers))
+		}
+	case err == errNoDigest && delimiter == "" && field == 0:
+		if verbose {
+			log.Printf("%s: %v", report.File, err)
+		}
+	default:
+		report.Error = err.Error()
+	}
+}
+
+// inspectRecords collects statistics on the records of the sample.
+// If the sample ends within a record, that record is not counted.
+func inspectRecords(sample *io.LimitedReader, report *inspectReport) error {
+	reader, err := newFastxReader(sample)
+	if err != nil || reader == nil {
+		return err
+	}
+	defer reader.Close()
+
+	var lengths []int
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			if sample.N == 0 {
+				break // truncated by the sample size
+			}
+			return fmt.Errorf("Error reading record: %v", err)
+		}
+		if len(lengths) == 0 {
+			report.FirstID = string(record.ID)
+		}
+		if len(report.headers) < layoutSampleSize {
+			report.headers = append(report.headers, string(record.Name))
+		}
+
+		seq := bytes.Join(bytes.Fields(record.Seq.Seq), nil)
+		lengths = append(lengths, len(seq))
+		for _, c := range seq {
+			switch c {
+			case 'A', 'C', 'G', 'T':
+			case 'a', 'c', 'g', 't':
+				report.Lowercase = true
+			default:
+				if c >= 'a' && c <= 'z' {
+					report.Lowercase = true
+				}
+				report.Ambiguous = true
+			}
+		}
+	}
+
+	// The last record may be cut short by the sample size
+	if sample.N == 0 && len(lengths) > 1 {
+		lengths = lengths[:len(lengths)-1]
+	}
+	for i, l := range lengths {
+		if i == 0 || l < report.MinLength {
+			report.MinLength = l
+		}
+		if l > report.MaxLength {
+			report.MaxLength = l
+		}
+	}
+	report.SampledRecords = int64(len(lengths))
+	return nil
+}
+
+func writeInspectJSON(w io.Writer, reports []*inspectReport) error {
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func writeInspectTable(w io.Writer, reports []*inspectReport, sampleSize int64) error {
+	yesNo := map[bool]string{true: "yes", false: "no"}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tCODEC\tFORMAT\tSAMPLED_BYTES\tRECORDS\tLENGTH\tLOWERCASE\tAMBIGUOUS\tFIRST_ID\tDIGEST")
+	for _, r := range reports {
+		if r.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t%s\terror: %s\n", r.File, dash(r.Codec), dash(r.Format), r.Error)
+			continue
+		}
+
+		sampled := strconv.FormatInt(r.SampleBytes, 10)
+		if r.Complete {
+			sampled += " (all)"
+		}
+		records := "?"
+		if r.EstimatedRecords != nil {
+			records = strconv.FormatInt(*r.EstimatedRecords, 10)
+			if !r.Complete {
+				records = "~" + records
+			}
+		}
+		length := "-"
+		if r.SampledRecords > 0 {
+			length = fmt.Sprintf("%d-%d", r.MinLength, r.MaxLength)
+		}
+		digest := "-"
+		if r.Digest != nil {
+			digest = r.Digest.String()
+		}
+		fmt.Fprintln(tw, strings.Join([]string{
+			r.File, r.Codec, r.Format, sampled, records, length,
+			yesNo[r.Lowercase], yesNo[r.Ambiguous], dash(r.FirstID), digest,
+		}, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nAt most %d decompressed bytes were read per input; '~' marks record counts extrapolated from the sample.\n", sampleSize)
+	return nil
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package seqhash
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		file      string
+		codec     string
+		format    string
+		records   int64
+		lowercase bool
+	}{
+		{"../test/test.fasta", "none", formatFASTA, 3, true},
+		{"../test/test.fasta.gz", "gzip", formatFASTA, 3, true},
+		{"../test/test.fasta.bz2", "bzip2", formatFASTA, 3, true},
+		{"../test/test.fasta.xz", "xz", formatFASTA, 3, true},
+		{"../test/test.fasta.zst", "zstd", formatFASTA, 3, true},
+		{"../test/test3.fastq", "none", formatFASTQ, 3, false},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.file, func(t *testing.T) {
+			r := inspectFile(tt.file, defaultInspectBytes)
+			if r.Error != "" {
+				t.Fatalf("Unexpected error: %s", r.Error)
+			}
+			if r.Codec != tt.codec || r.Format != tt.format {
+				t.Errorf("Detected %s/%s, want %s/%s", r.Codec, r.Format, tt.codec, tt.format)
+			}
+			if !r.Complete || r.SampledRecords != tt.records || r.EstimatedRecords == nil || *r.EstimatedRecords != tt.records {
+				t.Errorf("Expected all %d records to be sampled, got %+v", tt.records, r)
+			}
+			if r.MinLength != 4 || r.MaxLength != 4 || r.Lowercase != tt.lowercase || r.Ambiguous || r.FirstID != "seq1" {
+				t.Errorf("Unexpected sequence statistics: %+v", r)
+			}
+		})
+	}
+}
+
+// Inspection must agree with a regular run on the records it sees
+func TestInspectMatchesPipeline(t *testing.T) {
+	for _, fileName := range []string{"../test/test2.fasta.xz", "../test/problematic_sequences.fasta"} {
+		input, err := getInput(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats, err := processRecords(input, &strings.Builder{}, Config{HashTypes: []string{"sha1"}})
+		input.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := inspectFile(fileName, defaultInspectBytes)
+		if r.SampledRecords != stats.Records {
+			t.Errorf("%s: inspect found %d records, a regular run %d", fileName, r.SampledRecords, stats.Records)
+		}
+	}
+}
+
+func TestInspectSampleSize(t *testing.T) {
+	// 30 bytes cover the first two records of test2.fasta, cutting the third one
+	r := inspectFile("../test/test2.fasta", 30)
+	if r.Error != "" {
+		t.Fatalf("Unexpected error: %s", r.Error)
+	}
+	if r.Complete || r.SampleBytes != 30 {
+		t.Errorf("Expected an incomplete sample of 30 bytes, got %+v", r)
+	}
+	if r.SampledRecords != 2 || r.EstimatedRecords == nil || *r.EstimatedRecords != 2 {
+		t.Errorf("Expected 2 sampled records, got %+v", r)
+	}
+}
+
+func TestInspectCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runWithArgs([]string{"seqhasher", "inspect", "--json", input, filepath.Join(tmpDir, "missing.fasta")})
+	if err == nil || !strings.Contains(err.Error(), "Inspection failed for 1 of 2") {
+		t.Errorf("Expected a failure for the missing file, got %v", err)
+	}
+
+	var reports []inspectReport
+	if err := json.Unmarshal([]byte(output), &reports); err != nil {
+		t.Fatalf("Invalid JSON report: %v\n%s", err, output)
+	}
+	if len(reports) != 2 || reports[0].SampledRecords != 3 || reports[1].Error == "" {
+		t.Errorf("Unexpected reports: %+v", reports)
+	}
+
+	// Nothing is written next to the inputs
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected inspect to leave the directory untouched, found %d entries", len(entries))
+	}
+
+	table, err := runWithArgs([]string{"seqhasher", "inspect", input})
+	if err != nil {
+		t.Fatalf("inspect error = %v", err)
+	}
+	if !strings.Contains(table, "FIRST_ID") || !strings.Contains(table, "(all)") {
+		t.Errorf("Unexpected table output:\n%s", table)
+	}
+}
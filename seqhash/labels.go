@@ -0,0 +1,67 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Number of base32 characters in label pseudonyms (50 bits)
+const pseudonymLength = 10
+
+var pseudonymEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// pseudonym returns a short keyed digest of the label (HMAC-SHA256, base32-encoded).
+// The same label and key always give the same pseudonym;
+// without the key, the label can't be recovered or confirmed.
+func pseudonym(label, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(label))
+	return strings.ToLower(pseudonymEncoding.EncodeToString(mac.Sum(nil))[:pseudonymLength])
+}
+
+// anonymizeLabel replaces the file label with its pseudonym (--anonymize-labels)
+// and records the mapping in the --label-map-out file, if requested
+func anonymizeLabel(cfg Config, label string) (string, error) {
+	if !cfg.AnonymizeLabels || label == "" {
+		return label, nil
+	}
+	anonymized := pseudonym(label, cfg.HashKey)
+	if cfg.LabelMapOut != "" {
+		if err := appendLabelMap(cfg.LabelMapOut, label, anonymized); err != nil {
+			return "", fmt.Errorf("Error writing label map: %v", err)
+		}
+	}
+	return anonymized, nil
+}
+
+// appendLabelMap adds a row to the TSV with the true labels of pseudonyms,
+// so that mappings of several runs can be collected in the same file
+func appendLabelMap(fileName, label, anonymized string) error {
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	row := tsvEscaper.Replace(label) + "\t" + anonymized + "\n"
+	if info.Size() == 0 {
+		row = "label\tpseudonym\n" + row
+	}
+	if _, err := f.WriteString(row); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,98 @@
+package seqhash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPseudonym(t *testing.T) {
+	a := pseudonym("sample_A.fasta", "key1")
+	if len(a) != pseudonymLength || strings.Trim(a, "abcdefghijklmnopqrstuvwxyz234567") != "" {
+		t.Errorf("Expected %d lowercase base32 characters, got %q", pseudonymLength, a)
+	}
+	if b := pseudonym("sample_A.fasta", "key1"); a != b {
+		t.Errorf("Pseudonyms differ for the same label and key: %q and %q", a, b)
+	}
+	if b := pseudonym("sample_A.fasta", "key2"); a == b {
+		t.Errorf("Pseudonyms are the same for different keys: %q", a)
+	}
+	if b := pseudonym("sample_B.fasta", "key1"); a == b {
+		t.Errorf("Pseudonyms are the same for different labels: %q", a)
+	}
+}
+
+func TestAnonymizedLabels(t *testing.T) {
+	tmpDir := t.TempDir()
+	label := "Patient_Smith"
+	input := filepath.Join(tmpDir, label+".fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+	labelMap := filepath.Join(tmpDir, "labels.tsv")
+
+	run := func(key string, extra ...string) string {
+		t.Helper()
+		args := append([]string{"seqhasher", "--anonymize-labels", "--hash-key", key, "--label-map-out", labelMap}, extra...)
+		output, err := runWithArgs(append(args, input))
+		if err != nil {
+			t.Fatalf("run() error = %v", err)
+		}
+		return output
+	}
+
+	first := run("key1")
+	if second := run("key1"); first != second {
+		t.Errorf("Outputs differ across runs with the same key:\n%s\n%s", first, second)
+	}
+	if other := run("key2"); first == other {
+		t.Error("Outputs are the same for different keys")
+	}
+
+	outputs := []string{
+		first,
+		run("key1", "--out-format", "tsv"),
+		run("key1", "--out-format", "json", "--json-with-summary"),
+		run("key1", "--seqkit-compat"),
+		run("key1", "--header-format", "{file}|{id}"),
+		run("key1", "--name", "Smith"),
+	}
+	for _, output := range outputs {
+		if strings.Contains(output, "Smith") || strings.Contains(output, tmpDir) {
+			t.Errorf("Raw label found in the anonymized output:\n%s", output)
+		}
+	}
+
+	expectedPseudonym := pseudonym(input, "key1")
+	if !strings.HasPrefix(first, ">"+expectedPseudonym+";") {
+		t.Errorf("Expected headers to start with the pseudonym %q, got:\n%s", expectedPseudonym, first)
+	}
+
+	data, err := os.ReadFile(labelMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if rows[0] != "label\tpseudonym" || rows[1] != input+"\t"+expectedPseudonym {
+		t.Errorf("Unexpected label map:\n%s", data)
+	}
+}
+
+func TestLabelMapNotWrittenByDefault(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runWithArgs([]string{"seqhasher", "--anonymize-labels", "--hash-key", "key", input}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected no files besides the input, got %d entries", len(entries))
+	}
+}
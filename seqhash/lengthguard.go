@@ -0,0 +1,137 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Bytes of a header kept for error messages
+const guardHeaderLength = 200
+
+// Kinds of input lines
+const (
+	guardOther     = iota // Before the first record, or a FASTA comment
+	guardHeader           // '>' or '@' line
+	guardSequence         // Sequence line (also stray lines between FASTQ records)
+	guardSeparator        // FASTQ '+' line
+	guardQuality          // FASTQ quality lines
+)
+
+// lengthGuard fails the input as soon as the sequence of a record exceeds the limit (--max-seq-length),
+// before the parser, which keeps whole records in memory, has read the rest of it.
+// It counts the bytes of the sequence lines of each record (without line breaks).
+// For FASTQ, the line layout is followed as the parser does it, so that quality lines
+// starting with '@' are not mistaken for headers.
+type lengthGuard struct {
+	r     io.Reader
+	limit int64
+	err   error
+
+	format    byte // '>' or '@' (0 before the first header)
+	lineStart bool
+	line      int   // Kind of the current line
+	quality   bool  // In the quality lines of a FASTQ record
+	seqLen    int64 // Sequence bytes of the current record
+	qualLen   int64 // Quality bytes of the current record
+	records   int64
+	header    []byte // Start of the header of the current record
+	offset    int64  // Input bytes scanned
+	cr        bool   // The scanned part of the line ends with '\r' (not counted yet)
+}
+
+func newLengthGuard(r io.Reader, limit int64) *lengthGuard {
+	return &lengthGuard{r: r, limit: limit, lineStart: true}
+}
+
+func (g *lengthGuard) Read(p []byte) (int, error) {
+	if g.err != nil {
+		return 0, g.err
+	}
+	n, err := g.r.Read(p)
+	start := g.offset
+	if g.err = g.scan(p[:n]); g.err != nil {
+		// The bytes before the excess are passed on (the parser fails on empty input),
+		// and the error comes with the next read
+		if passed := int(g.offset - start); passed > 0 {
+			return passed, nil
+		}
+		return 0, g.err
+	}
+	return n, err
+}
+
+// scan follows the lines of a chunk of the input
+func (g *lengthGuard) scan(b []byte) error {
+	for len(b) > 0 {
+		if g.lineStart {
+			g.startLine(b[0])
+		}
+		segment, rest, complete := bytes.Cut(b, []byte("\n"))
+		// A '\r' is only a line break when followed by '\n', which may come with the next read
+		pending := 0
+		if g.cr && (len(segment) > 0 || !complete) {
+			pending = 1
+		}
+		g.cr = false
+		if trimmed := bytes.TrimSuffix(segment, []byte("\r")); len(trimmed) < len(segment) {
+			segment, g.cr = trimmed, !complete
+		}
+		switch g.line {
+		case guardHeader:
+			if room := guardHeaderLength - len(g.header); room > 0 {
+				g.header = append(g.header, segment[:min(room, len(segment))]...)
+			}
+		case guardSequence:
+			if excess := g.seqLen + int64(pending+len(segment)) - g.limit; excess > 0 {
+				g.offset += int64(len(segment)) - excess
+				return g.exceeded()
+			}
+			g.seqLen += int64(pending + len(segment))
+		case guardQuality:
+			g.qualLen += int64(pending + len(segment))
+		}
+		g.offset += int64(len(b) - len(rest))
+		g.lineStart = complete
+		if complete && g.quality && g.qualLen >= g.seqLen {
+			g.quality = false // The next line is a header
+		}
+		b = rest
+	}
+	return nil
+}
+
+// startLine determines the kind of a line from its first byte
+func (g *lengthGuard) startLine(c byte) {
+	if g.format == 0 && (c == '>' || c == '@') {
+		g.format = c
+	}
+	switch {
+	case g.format == 0:
+		g.line = guardOther
+	case g.format == '>' && c == ';':
+		g.line = guardOther // Comment (--keep-comments)
+	case g.format == '@' && g.quality:
+		g.line = guardQuality
+	case c == g.format:
+		g.line = guardHeader
+		g.records++
+		g.seqLen, g.qualLen, g.header = 0, 0, g.header[:0]
+	case g.format == '@' && c == '+' && g.records > 0:
+		g.line = guardSeparator
+		g.quality = true
+	default:
+		g.line = guardSequence
+	}
+}
+
+func (g *lengthGuard) exceeded() error {
+	id, _ := splitComment(bytes.TrimLeft(g.header, ">@"))
+	return fmt.Errorf("The sequence of record %d (%q) exceeds --max-seq-length of %d bytes (at byte offset %d of the input); "+
+		"the input may be corrupted (e.g., records without headers)", g.records, id, g.limit, g.offset)
+}
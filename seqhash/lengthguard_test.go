@@ -0,0 +1,85 @@
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestMaxSeqLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		limit   int64
+		records int    // Records written when the input is accepted
+		wantErr string // Start of the error message
+	}{
+		{
+			name:    "FASTA within the limit",
+			input:   ">seq1\nACTG\nACTG\n>seq2\nACTGACTG\r\n",
+			limit:   8,
+			records: 2,
+		},
+		{
+			name:    "FASTA record over the limit",
+			input:   ">seq1\nACTG\n>seq2 lost headers\nACTG\nACTG\nA\n>seq3\nACTG\n",
+			limit:   8,
+			wantErr: `The sequence of record 2 ("seq2") exceeds --max-seq-length of 8 bytes (at byte offset 40 of the input)`,
+		},
+		{
+			name:    "FASTQ quality starting with '@'",
+			input:   "@seq1\nACTGACTG\n+seq1\n@@@@\n@@@@\n@seq2\nACTG\n+\nIIII\n",
+			limit:   8,
+			records: 2,
+		},
+		{
+			name:    "FASTQ record over the limit",
+			input:   "@seq1\nACTG\n+\n@III\n@seq2\nACTGACTGA\n+\nIIIIIIIII\n",
+			limit:   8,
+			wantErr: `The sequence of record 2 ("seq2") exceeds --max-seq-length of 8 bytes (at byte offset 32 of the input)`,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, oneByte := range []bool{false, true} {
+			runTest(t, fmt.Sprintf("%s (one byte per read: %v)", tt.name, oneByte), func(t *testing.T) {
+				// One byte at a time, lines are split across reads
+				var input io.Reader = strings.NewReader(tt.input)
+				if oneByte {
+					input = iotest.OneByteReader(input)
+				}
+				output := &bytes.Buffer{}
+				cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, MaxSeqLength: tt.limit}
+				err := processSequences(input, output, cfg)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("processSequences() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("processSequences() error = %v", err)
+				}
+				if records := strings.Count(output.String(), ";seq"); records != tt.records {
+					t.Errorf("Got %d records, want %d:\n%s", records, tt.records, output.String())
+				}
+			})
+		}
+	}
+}
+
+func TestLengthGuardStopsReading(t *testing.T) {
+	// The error comes before the rest of the record is read
+	data := ">seq1\n" + strings.Repeat("ACTG\n", 1000)
+	source := strings.NewReader(data)
+	guard := newLengthGuard(source, 100)
+	if _, err := io.Copy(io.Discard, iotest.OneByteReader(guard)); err == nil {
+		t.Fatal("Expected an error for the oversized record")
+	}
+	if read := int64(len(data)) - int64(source.Len()); read > 200 {
+		t.Errorf("Read %d bytes of the input, want at most 200", read)
+	}
+}
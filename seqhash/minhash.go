@@ -0,0 +1,108 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Banded MinHash (--clustered-hash): the MinHash sketch of a sequence (the minimum of each of
+// lshBands*lshRows hash functions over its k-mers) is cut into bands of lshRows minimums,
+// and each band is hashed into a band key. Two sequences with k-mer Jaccard similarity J
+// share at least one band key with probability 1-(1-J^r)^b, i.e., almost surely above
+// J = 0.7, and rarely below J = 0.3 (the threshold is about (1/b)^(1/r) = 0.5).
+const (
+	lshBands          = 16
+	lshRows           = 4
+	defaultClusterK   = 16
+	clusterBucketSize = 16 // Hex digits of a cluster bucket
+)
+
+// lshBandKeys returns the band keys of the MinHash sketch of a sequence.
+// A sequence shorter than k is taken as a single k-mer; an empty one has no keys.
+func lshBandKeys(seq []byte, k int) []uint64 {
+	if len(seq) == 0 {
+		return nil
+	}
+	k = min(k, len(seq))
+	var sketch [lshBands * lshRows]uint64
+	for i := range sketch {
+		sketch[i] = ^uint64(0)
+	}
+	for start := 0; start+k <= len(seq); start++ {
+		h := xxhash.Sum64(seq[start : start+k])
+		// The hash functions are derived from a single k-mer hash by seeded mixing
+		for i := range sketch {
+			if v := mix64(h ^ (uint64(i+1) * 0x9e3779b97f4a7c15)); v < sketch[i] {
+				sketch[i] = v
+			}
+		}
+	}
+
+	keys := make([]uint64, lshBands)
+	band := make([]byte, 8*(lshRows+1))
+	for b := range keys {
+		binary.LittleEndian.PutUint64(band, uint64(b)) // Equal minimums in different bands give different keys
+		for r := 0; r < lshRows; r++ {
+			binary.LittleEndian.PutUint64(band[8*(r+1):], sketch[b*lshRows+r])
+		}
+		keys[b] = xxhash.Sum64(band)
+	}
+	return keys
+}
+
+// mix64 is the finalizer of SplitMix64
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// lshClusters assigns sequences to buckets by their band keys: a sequence joins the bucket
+// of the first earlier sequence that shares any of its band keys, or starts a new one,
+// named by its first band key. Records arrive in input order (also with --threads),
+// so the buckets do not depend on the number of threads.
+// The band keys of all buckets are kept in memory (lshBands keys per distinct sketch).
+type lshClusters struct {
+	buckets map[uint64]string // Band key -> bucket
+}
+
+// newLSHClusters returns nil without --clustered-hash
+func newLSHClusters(cfg Config) *lshClusters {
+	if !cfg.ClusteredHash {
+		return nil
+	}
+	return &lshClusters{buckets: make(map[uint64]string)}
+}
+
+// bucket returns the bucket of a sequence by its band keys (empty for an empty sequence)
+func (c *lshClusters) bucket(keys []uint64) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	bucket := ""
+	for _, key := range keys {
+		if b, ok := c.buckets[key]; ok {
+			bucket = b
+			break
+		}
+	}
+	if bucket == "" {
+		bucket = fmt.Sprintf("%0*x", clusterBucketSize, keys[0])
+	}
+	// Band keys of the sequence lead to its bucket (the first sequence of a key keeps it)
+	for _, key := range keys {
+		if _, ok := c.buckets[key]; !ok {
+			c.buckets[key] = bucket
+		}
+	}
+	return bucket
+}
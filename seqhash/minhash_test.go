@@ -0,0 +1,78 @@
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func randomDNA(rng *rand.Rand, n int) []byte {
+	seq := make([]byte, n)
+	for i := range seq {
+		seq[i] = "ACGT"[rng.Intn(4)]
+	}
+	return seq
+}
+
+func TestClusteredHash(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	seq := randomDNA(rng, 200)
+	variant := bytes.Clone(seq)
+	variant[100] = map[byte]byte{'A': 'C', 'C': 'G', 'G': 'T', 'T': 'A'}[variant[100]] // A single substitution
+	other := randomDNA(rng, 200)
+	input := fmt.Sprintf(">seq1\n%s\n>variant\n%s\n>other\n%s\n>seq1_lowercase\n%s\n",
+		seq, variant, other, bytes.ToLower(seq))
+
+	var expected []string
+	for _, threads := range []int{1, 4} {
+		runTest(t, fmt.Sprintf("Threads %d", threads), func(t *testing.T) {
+			cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, ClusteredHash: true, ClusterK: defaultClusterK, Threads: threads}
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			var buckets []string
+			for _, header := range fastaIDs(t, output.Bytes()) {
+				_, bucket, found := strings.Cut(header, ";cluster=")
+				if !found || len(bucket) != clusterBucketSize {
+					t.Fatalf("Expected a cluster annotation in %q", header)
+				}
+				buckets = append(buckets, bucket)
+			}
+			if len(buckets) != 4 {
+				t.Fatalf("Expected 4 records, got %d", len(buckets))
+			}
+			if buckets[0] != buckets[1] || buckets[0] != buckets[3] {
+				t.Errorf("Expected near-identical sequences in one bucket, got %v", buckets)
+			}
+			if buckets[2] == buckets[0] {
+				t.Errorf("Expected a different sequence in another bucket, got %v", buckets)
+			}
+			// Buckets don't depend on the number of threads
+			if expected == nil {
+				expected = buckets
+			} else if !slices.Equal(buckets, expected) {
+				t.Errorf("Expected buckets %v, got %v", expected, buckets)
+			}
+		})
+	}
+}
+
+func TestLSHBandKeys(t *testing.T) {
+	if keys := lshBandKeys(nil, defaultClusterK); keys != nil {
+		t.Errorf("Expected no band keys for an empty sequence, got %v", keys)
+	}
+	short := lshBandKeys([]byte("ACGT"), defaultClusterK)
+	if len(short) != lshBands {
+		t.Fatalf("Expected %d band keys for a sequence shorter than k, got %d", lshBands, len(short))
+	}
+	if !slices.Equal(short, lshBandKeys([]byte("ACGT"), defaultClusterK)) {
+		t.Error("Expected equal band keys for equal sequences")
+	}
+	if slices.Equal(short, lshBandKeys([]byte("ACGA"), defaultClusterK)) {
+		t.Error("Expected different band keys for different sequences")
+	}
+}
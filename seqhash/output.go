@@ -0,0 +1,426 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+var supportedOutFormats = []string{"fasta", "json", "ndjson", "tsv", "csv", "protobuf"}
+
+// Encodings of the sequence in JSON output (--encode-sequence)
+var supportedSequenceEncodings = []string{"none", "base64"}
+
+// fastaWriter writes records in their input format (FASTA or FASTQ), or only headers
+type fastaWriter struct {
+	sinkStream
+	headersOnly bool
+	delimiter   []byte // Written between consecutive records (--record-delimiter)
+	written     bool
+}
+
+// WriteRecord outputs a record (its Name already holds the rewritten header)
+func (fw *fastaWriter) WriteRecord(r Record) error {
+	record := r.fastx
+	if fw.written && len(fw.delimiter) > 0 {
+		if _, err := fw.w.Write(fw.delimiter); err != nil {
+			return fmt.Errorf("Error writing record delimiter: %v", err)
+		}
+	}
+	fw.written = true
+	var inner [][]byte
+	if r.comments != nil {
+		if err := fw.comments(r.comments.leading); err != nil {
+			return err
+		}
+		inner = r.comments.inner
+	}
+	if fw.headersOnly || r.noSeq {
+		marker := ""
+		if !fw.headersOnly {
+			marker = ">" // A FASTA record without sequence lines
+		}
+		if _, err := fmt.Fprintf(fw.w, "%s%s\n", marker, record.Name); err != nil {
+			return fmt.Errorf("Error writing header: %v", err)
+		}
+		return fw.comments(inner)
+	}
+	formatted := record.Format(0)
+	if len(inner) > 0 {
+		// Comments between the header and the sequence (only FASTA input has comments)
+		formatted = fmt.Appendf(nil, ">%s\n%s\n%s\n", record.Name, bytes.Join(inner, []byte("\n")), record.Seq.Seq)
+	}
+	if _, err := fw.w.Write(formatted); err != nil {
+		return fmt.Errorf("Error writing record: %v", err)
+	}
+	return nil
+}
+
+// comments writes FASTA comment lines verbatim
+// (those of a record are written by WriteRecord, the trailing ones after the last record)
+func (fw *fastaWriter) comments(lines [][]byte) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(fw.w, "%s\n", line); err != nil {
+			return fmt.Errorf("Error writing comment: %v", err)
+		}
+	}
+	return nil
+}
+
+// section writes a FASTA comment line (';' followed by the title);
+// the record delimiter goes before the section rather than after it
+func (fw *fastaWriter) section(title string) error {
+	if fw.written && len(fw.delimiter) > 0 {
+		if _, err := fw.w.Write(fw.delimiter); err != nil {
+			return fmt.Errorf("Error writing record delimiter: %v", err)
+		}
+	}
+	fw.written = false
+	if _, err := fmt.Fprintf(fw.w, "; %s\n", title); err != nil {
+		return fmt.Errorf("Error writing section: %v", err)
+	}
+	return nil
+}
+
+// JSON representation of a record
+type jsonRecord struct {
+	File     string            `json:"file,omitempty"`
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Hashes   map[string]string `json:"hashes"`
+	Sequence *string           `json:"sequence,omitempty"`
+	Encoding string            `json:"sequence_encoding,omitempty"` // "base64" with --encode-sequence base64
+	Quality  string            `json:"quality,omitempty"`
+	Header   string            `json:"header_hash,omitempty"` // Digest of the original header (--dual-hash)
+	Meta     map[string]string `json:"meta,omitempty"`        // Sample sheet columns
+}
+
+// Trailing object of the JSON output (--json-with-summary)
+type jsonSummary struct {
+	Version   string   `json:"version"`
+	File      string   `json:"file,omitempty"`
+	HashTypes []string `json:"hash_types"`
+	Records   int64    `json:"records"`
+	Bases     int64    `json:"bases"`
+
+	Groups []groupSummary   `json:"groups,omitempty"` // With --group-by
+	Strata []stratumSummary `json:"strata,omitempty"` // With --stratified-sample
+}
+
+// newJSONSummary describes a run; it is also the source of the metadata lines of tables
+func newJSONSummary(cfg Config, label string, stats Stats) jsonSummary {
+	return jsonSummary{
+		Version:   version,
+		File:      label,
+		HashTypes: cfg.HashTypes,
+		Records:   stats.Records,
+		Bases:     stats.Bases,
+		Groups:    stats.groups,
+		Strata:    stats.strata,
+	}
+}
+
+// jsonWriter streams records as a JSON array (one object per line),
+// or as JSON Lines (NDJSON) when lines is set.
+// Records are never kept in memory, so memory use does not depend on the input size;
+// with a record count before the array (countHeader), they are spooled to a temporary file.
+type jsonWriter struct {
+	sinkStream
+	cfg         Config
+	lines       bool   // NDJSON
+	countHeader bool   // Write {"record_count": N, "records": [...]} (--output-record-count-header)
+	label       string // File label (empty if omitted)
+	written     int64
+
+	spoolFile *os.File      // Records written before their count is known
+	spool     *bufio.Writer // Buffer over spoolFile
+}
+
+func (jw *jsonWriter) open() error {
+	opening := "[\n"
+	switch {
+	case jw.countHeader:
+		opening = fmt.Sprintf("{\"record_count\": %d, \"records\": [\n", jw.written)
+	case jw.cfg.JsonSummary:
+		opening = "{\"records\": [\n"
+	}
+	_, err := io.WriteString(jw.w, opening)
+	return err
+}
+
+// recordStream returns where records are written: the output, or the spool if the count goes first
+func (jw *jsonWriter) recordStream() (io.Writer, error) {
+	if !jw.countHeader {
+		return jw.w, nil
+	}
+	if jw.spoolFile == nil {
+		spoolFile, err := os.CreateTemp(jw.cfg.TmpDir, "seqhasher-spool-*")
+		if err != nil {
+			return nil, fmt.Errorf("Error creating temporary file: %v", err)
+		}
+		jw.spoolFile, jw.spool = spoolFile, bufio.NewWriter(spoolFile)
+	}
+	return jw.spool, nil
+}
+
+// unspool copies the spooled records to the output
+func (jw *jsonWriter) unspool() error {
+	if jw.spoolFile == nil {
+		return nil
+	}
+	if err := jw.spool.Flush(); err != nil {
+		return fmt.Errorf("Error writing temporary file: %v", err)
+	}
+	if _, err := jw.spoolFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("Error reading temporary file: %v", err)
+	}
+	_, err := io.Copy(jw.w, jw.spoolFile)
+	return err
+}
+
+// Close removes the spool; the output stream itself stays open
+func (jw *jsonWriter) Close() error {
+	if jw.spoolFile != nil {
+		jw.spoolFile.Close()
+		os.Remove(jw.spoolFile.Name())
+	}
+	return nil
+}
+
+func (jw *jsonWriter) WriteRecord(r Record) error {
+	record, h := r.fastx, r.hashed
+	jr := jsonRecord{
+		File:   jw.label,
+		ID:     string(record.ID),
+		Name:   string(h.name),
+		Hashes: make(map[string]string, len(h.hashes)),
+		Header: h.headerHash,
+	}
+	for i, hashType := range jw.cfg.HashTypes {
+		jr.Hashes[hashType] = h.hashes[i]
+	}
+	if !jw.cfg.HeadersOnly {
+		sequence := string(record.Seq.Seq)
+		if jw.cfg.EncodeSequence == "base64" {
+			// Standard alphabet with padding, so that any bytes survive JSON transport
+			sequence = base64.StdEncoding.EncodeToString(record.Seq.Seq)
+			jr.Encoding = "base64"
+		}
+		jr.Sequence = &sequence
+		jr.Quality = string(record.Seq.Qual)
+	}
+	if meta := jw.cfg.meta; meta != nil && len(meta.columns) > 0 {
+		jr.Meta = make(map[string]string, len(meta.columns))
+		for i, column := range meta.columns {
+			jr.Meta[column] = meta.values[i]
+		}
+	}
+
+	data, err := json.Marshal(jr)
+	if err != nil {
+		return fmt.Errorf("Error encoding record: %v", err)
+	}
+
+	w, err := jw.recordStream()
+	if err != nil {
+		return err
+	}
+	if !jw.lines {
+		if jw.written == 0 {
+			if !jw.countHeader {
+				if err := jw.open(); err != nil {
+					return fmt.Errorf("Error writing record: %v", err)
+				}
+			}
+		} else if _, err := io.WriteString(w, ",\n"); err != nil {
+			return fmt.Errorf("Error writing record: %v", err)
+		}
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("Error writing record: %v", err)
+	}
+	if jw.lines {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return fmt.Errorf("Error writing record: %v", err)
+		}
+	}
+	jw.written++
+	return nil
+}
+
+func (jw *jsonWriter) finish(stats Stats) error {
+	if jw.lines {
+		return nil
+	}
+	if jw.written == 0 || jw.countHeader {
+		if err := jw.open(); err != nil {
+			return err
+		}
+	}
+	if err := jw.unspool(); err != nil {
+		return err
+	}
+	if jw.written > 0 {
+		if _, err := io.WriteString(jw.w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	if !jw.cfg.JsonSummary {
+		closing := "]\n"
+		if jw.countHeader {
+			closing = "]}\n"
+		}
+		_, err := io.WriteString(jw.w, closing)
+		return err
+	}
+
+	summary, err := json.Marshal(newJSONSummary(jw.cfg, jw.label, stats))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(jw.w, "], \"summary\": %s}\n", summary)
+	return err
+}
+
+// abort leaves the JSON unterminated and appends a comment line,
+// so the partial output can't be mistaken for a complete document
+func (jw *jsonWriter) abort(err error) {
+	if jw.countHeader {
+		// The records written so far (and their count)
+		if jw.open() != nil || jw.unspool() != nil {
+			return
+		}
+	}
+	fmt.Fprintf(jw.w, "\n// seqhasher: incomplete output (%v)\n", err)
+}
+
+// TSV has no quoting, so separators within values are replaced by spaces
+var tsvEscaper = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+// Version of the layout of the metadata lines of tabular outputs
+const tableMetadataSchema = 1
+
+// tableWriter writes one row per record (file, hashes, ID, and sample metadata)
+// as tab- or comma-separated values, preceded by '#' metadata lines and a row of column names
+type tableWriter struct {
+	sinkStream
+	csv      *csv.Writer // nil for TSV
+	columns  []outputField
+	metadata []string // Lines written before the column names (nil with --no-metadata)
+	started  bool
+}
+
+func newTableWriter(stream sinkStream, cfg Config, label string) *tableWriter {
+	tw := &tableWriter{sinkStream: stream, columns: tabularFields(outputFields(cfg))}
+	if cfg.OutFormat == "csv" {
+		tw.csv = csv.NewWriter(stream.w)
+	}
+	if !cfg.NoMetadata {
+		tw.metadata = tableMetadata(cfg, tw.columns, label)
+	}
+	return tw
+}
+
+// tableMetadata describes the table in '#' lines, which R (comment.char = "#")
+// and pandas (comment = "#") skip. The values come from the same sources as
+// the JSON summary (version, hash types, input), the audit log (effective options,
+// including defaults), and --explain-output (column descriptions).
+func tableMetadata(cfg Config, columns []outputField, label string) []string {
+	summary := newJSONSummary(cfg, label, Stats{}) // The counts are not known before the records
+	lines := []string{
+		fmt.Sprintf("# seqhasher_table_schema: %d", tableMetadataSchema),
+		"# version: " + summary.Version,
+		"# hash_types: " + strings.Join(summary.HashTypes, ","),
+		"# normalization: " + sequenceSource(cfg),
+	}
+	if summary.File != "" {
+		lines = append(lines, "# input: "+summary.File)
+	}
+	names := make([]string, 0, len(cfg.effective))
+	for name := range cfg.effective {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := cfg.effective[name]
+		if cfg.AnonymizeLabels {
+			// Values may hold the raw label (--name) or paths next to the input
+			value = redactedValue
+		}
+		lines = append(lines, fmt.Sprintf("# option: %s=%s", name, value))
+	}
+	for _, c := range columns {
+		lines = append(lines, fmt.Sprintf("# column: %s: %s", strings.TrimPrefix(c.name, "meta:"), c.source))
+	}
+	for i, line := range lines {
+		lines[i] = tsvEscaper.Replace(line)
+	}
+	return lines
+}
+
+func (tw *tableWriter) writeRow(values []string) error {
+	if tw.csv != nil {
+		tw.csv.Write(values)
+		// Flushed after every row, so that index offsets stay exact
+		tw.csv.Flush()
+		return tw.csv.Error()
+	}
+	for i, v := range values {
+		values[i] = tsvEscaper.Replace(v)
+	}
+	_, err := io.WriteString(tw.w, strings.Join(values, "\t")+"\n")
+	return err
+}
+
+func (tw *tableWriter) writeColumnNames() error {
+	for _, line := range tw.metadata {
+		if _, err := io.WriteString(tw.w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	names := make([]string, len(tw.columns))
+	for i, c := range tw.columns {
+		names[i] = strings.TrimPrefix(c.name, "meta:")
+	}
+	tw.started = true
+	return tw.writeRow(names)
+}
+
+func (tw *tableWriter) WriteRecord(r Record) error {
+	h := r.hashed
+	if !tw.started {
+		if err := tw.writeColumnNames(); err != nil {
+			return fmt.Errorf("Error writing record: %v", err)
+		}
+	}
+	values := make([]string, len(tw.columns))
+	for i, c := range tw.columns {
+		values[i] = c.value(h)
+	}
+	if err := tw.writeRow(values); err != nil {
+		return fmt.Errorf("Error writing record: %v", err)
+	}
+	return nil
+}
+
+func (tw *tableWriter) finish(Stats) error {
+	if !tw.started {
+		return tw.writeColumnNames()
+	}
+	return nil
+}
+
+func (tw *tableWriter) abort(error) {}
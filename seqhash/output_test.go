@@ -0,0 +1,414 @@
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/shenwei356/bio/seq"
+)
+
+func TestJSONOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		records int
+	}{
+		{"Zero records", "", 0},
+		{"One record", ">seq1\nACTG\n", 1},
+		{"Many records", testSequences, 3},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := Config{HashTypes: []string{"sha1", "md5"}, OutFormat: "json", InputFileName: "test.fasta"}
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+
+			var records []jsonRecord
+			if err := json.Unmarshal(output.Bytes(), &records); err != nil {
+				t.Fatalf("Output is not a valid JSON array: %v\n%s", err, output.String())
+			}
+			if len(records) != tt.records {
+				t.Fatalf("Expected %d records, got %d", tt.records, len(records))
+			}
+			if tt.records > 0 {
+				first := records[0]
+				if first.File != "test.fasta" || first.ID != "seq1" || *first.Sequence != "ACTG" ||
+					first.Hashes["sha1"] != "65c89f59d38cdbf90dfaf0b0a6884829df8396b0" ||
+					first.Hashes["md5"] != "86bfb9f78dd8b6cd35962bb7324fdbf8" {
+					t.Errorf("Unexpected first record: %+v", first)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONOutputWithSummary(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Zero records", ""},
+		{"Many records", testSequences},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := Config{HashTypes: []string{"sha1"}, OutFormat: "json", JsonSummary: true, HeadersOnly: true, InputFileName: "test.fasta"}
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+
+			var doc struct {
+				Records []jsonRecord `json:"records"`
+				Summary jsonSummary  `json:"summary"`
+			}
+			if err := json.Unmarshal(output.Bytes(), &doc); err != nil {
+				t.Fatalf("Output is not valid JSON: %v\n%s", err, output.String())
+			}
+			expected := strings.Count(tt.input, ">")
+			if len(doc.Records) != expected || doc.Summary.Records != int64(expected) {
+				t.Errorf("Expected %d records and matching summary, got %d records, summary %+v",
+					expected, len(doc.Records), doc.Summary)
+			}
+			for _, r := range doc.Records {
+				if r.Sequence != nil {
+					t.Errorf("Expected no sequence with --headersonly, got %q", *r.Sequence)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONOutputWithRecordCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		summary bool
+		records int
+	}{
+		{"Zero records", "", false, 0},
+		{"Many records", testSequences, false, 3},
+		{"Many records with summary", testSequences, true, 3},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			cfg := Config{HashTypes: []string{"sha1"}, OutFormat: "json", RecordCountHeader: true, JsonSummary: tt.summary,
+				TmpDir: tmpDir, InputFileName: "test.fasta"}
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+
+			// The count comes before the records
+			if prefix := `{"record_count": ` + strconv.Itoa(tt.records); !strings.HasPrefix(output.String(), prefix) {
+				t.Errorf("Expected the output to start with %q, got:\n%s", prefix, output.String())
+			}
+			var doc struct {
+				RecordCount *int         `json:"record_count"`
+				Records     []jsonRecord `json:"records"`
+				Summary     *jsonSummary `json:"summary"`
+			}
+			if err := json.Unmarshal(output.Bytes(), &doc); err != nil {
+				t.Fatalf("Output is not valid JSON: %v\n%s", err, output.String())
+			}
+			if doc.RecordCount == nil || *doc.RecordCount != tt.records || len(doc.Records) != tt.records {
+				t.Errorf("Expected a count of %d and %d records, got %v and %d", tt.records, tt.records, doc.RecordCount, len(doc.Records))
+			}
+			if tt.records > 0 && (doc.Records[0].ID != "seq1" || doc.Records[tt.records-1].ID != "seq2") {
+				t.Errorf("Unexpected records: %+v", doc.Records)
+			}
+			if (doc.Summary != nil) != tt.summary {
+				t.Errorf("Expected a summary: %v, got %+v", tt.summary, doc.Summary)
+			}
+
+			// The spool is removed
+			if entries, _ := os.ReadDir(tmpDir); len(entries) != 0 {
+				t.Errorf("Expected no temporary files left, got %d", len(entries))
+			}
+		})
+	}
+}
+
+func TestRecordCountOfStreamedFormats(t *testing.T) {
+	// The option has no effect on streamed formats
+	for _, format := range []string{"ndjson", "tsv", "fasta"} {
+		runTest(t, format, func(t *testing.T) {
+			cfg := Config{HashTypes: []string{"sha1"}, OutFormat: format, InputFileName: "test.fasta"}
+			expected := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(testSequences), expected, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			cfg.RecordCountHeader = true
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(testSequences), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if output.String() != expected.String() {
+				t.Errorf("Expected unchanged output:\n%s\ngot:\n%s", expected.String(), output.String())
+			}
+		})
+	}
+}
+
+func TestNDJSONOutput(t *testing.T) {
+	cfg := Config{HashTypes: []string{"xxhash"}, OutFormat: "ndjson", NoFileName: true, InputFileName: "test.fasta"}
+	output := &bytes.Buffer{}
+	if err := processSequences(strings.NewReader(testSequences), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+
+	scanner := bufio.NewScanner(output)
+	var ids []string
+	for scanner.Scan() {
+		var r jsonRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("Line is not valid JSON: %v\n%s", err, scanner.Text())
+		}
+		if r.File != "" {
+			t.Errorf("Expected no file label with --nofilename, got %q", r.File)
+		}
+		ids = append(ids, r.ID)
+	}
+	if strings.Join(ids, ",") != "seq1,seq1_lowercase,seq2" {
+		t.Errorf("Unexpected record IDs: %v", ids)
+	}
+}
+
+func TestPartialJSONOutput(t *testing.T) {
+	interrupted.Store(true)
+	defer interrupted.Store(false)
+
+	cfg := Config{HashTypes: []string{"sha1"}, OutFormat: "json", KeepPartial: true, InputFileName: "test.fasta"}
+	output := &bytes.Buffer{}
+	err := processSequences(strings.NewReader(testSequences), output, cfg)
+	if err != errInterrupted {
+		t.Fatalf("Expected interruption error, got %v", err)
+	}
+	if !strings.HasSuffix(output.String(), "// seqhasher: incomplete output (Interrupted)\n") {
+		t.Errorf("Expected partial output to end with a comment, got %q", output.String())
+	}
+	if json.Valid(output.Bytes()) {
+		t.Errorf("Partial output must not be valid JSON")
+	}
+}
+
+func TestPartialOutputRemoval(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "bad.fastq")
+	if err := os.WriteFile(input, []byte("@seq1\nACTG\n+\nDF\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keep := range []bool{false, true} {
+		output := filepath.Join(tmpDir, "out.json")
+		args := []string{"seqhasher", "--out-format", "json", input, output}
+		if keep {
+			args = []string{"seqhasher", "--out-format", "json", "--keep-partial", input, output}
+		}
+		if _, err := runWithArgs(args); err == nil {
+			t.Fatalf("Expected an error for a malformed FASTQ file")
+		}
+		if _, err := os.Stat(output); (err == nil) != keep {
+			t.Errorf("keep-partial=%v: output exists = %v", keep, err == nil)
+		}
+		os.Remove(output)
+	}
+}
+
+func TestBase64Sequence(t *testing.T) {
+	// Disable sequence validation, as run() does
+	seq.ValidateSeq = false
+
+	// Bytes that JSON tools may mangle: a smart quote and a control character
+	input := ">odd\nAC“T\x01G\n>plain\nACTG\n"
+	want := map[string]string{"odd": "AC“T\x01G", "plain": "ACTG"}
+
+	for _, outFormat := range []string{"ndjson", "json"} {
+		runTest(t, outFormat, func(t *testing.T) {
+			cfg := Config{HashTypes: []string{"sha1"}, OutFormat: outFormat, NoFileName: true, SeqBytes: "any", EncodeSequence: "base64"}
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+
+			var records []jsonRecord
+			if outFormat == "json" {
+				if err := json.Unmarshal(output.Bytes(), &records); err != nil {
+					t.Fatalf("Invalid JSON output: %v\n%s", err, output.String())
+				}
+			} else {
+				scanner := bufio.NewScanner(output)
+				for scanner.Scan() {
+					var r jsonRecord
+					if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+						t.Fatalf("Line is not valid JSON: %v\n%s", err, scanner.Text())
+					}
+					records = append(records, r)
+				}
+			}
+
+			if len(records) != len(want) {
+				t.Fatalf("Got %d records, want %d", len(records), len(want))
+			}
+			for _, r := range records {
+				if r.Encoding != "base64" || r.Sequence == nil {
+					t.Fatalf("Record %s: sequence_encoding = %q, sequence = %v", r.ID, r.Encoding, r.Sequence)
+				}
+				decoded, err := base64.StdEncoding.DecodeString(*r.Sequence)
+				if err != nil {
+					t.Fatalf("Record %s: invalid base64 %q: %v", r.ID, *r.Sequence, err)
+				}
+				if string(decoded) != want[r.ID] {
+					t.Errorf("Record %s: decoded sequence %q, want %q", r.ID, decoded, want[r.ID])
+				}
+			}
+		})
+	}
+}
+
+func TestTableMetadata(t *testing.T) {
+	args := []string{"seqhasher", "--out-format", "tsv", "--hash", "sha1,md5", "--casesensitive", "--dedup", "--hash-key", "secret", testFastaPath}
+	output, err := runWithArgs(args)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	head := []string{
+		"# seqhasher_table_schema: 1",
+		"# version: " + version,
+		"# hash_types: sha1,md5",
+		"# normalization: sequence without whitespace",
+		"# input: " + testFastaPath,
+	}
+	// The effective value of every option, also of the defaults (e.g., the sequence byte policy)
+	options := []string{
+		"# option: casesensitive=true",
+		"# option: dedup=true",
+		"# option: hash=sha1,md5",
+		"# option: hash-key=REDACTED",
+		"# option: on-error=fail",
+		"# option: out-format=tsv",
+		"# option: seq-bytes=iupac",
+		"# option: trim-ns=false",
+	}
+	tail := []string{
+		"# column: file: input file name",
+		"# column: sha1: sha1 digest of the sequence",
+		"# column: md5: md5 digest of the sequence",
+		"# column: id: original ID (blank IDs replaced by seq_<hash prefix>)",
+		"# column: comment: text after the ID (appended to the header after a space)",
+		"# column: read: read number from a CASAVA 1.8+ comment (1:N:0:<index>) or a /1 ID suffix",
+		"# column: barcode: index sequence from a CASAVA 1.8+ comment or a #<index>/1 ID suffix",
+		"file\tsha1\tmd5\tid\tcomment\tread\tbarcode",
+	}
+
+	cfg, err := ParseArgs(args[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(output, "\n")
+	block := len(head) + len(cfg.effective) + len(tail)
+	if len(lines) < block {
+		t.Fatalf("Got %d lines, want a metadata block of %d lines:\n%s", len(lines), block, output)
+	}
+	optionLines := lines[len(head) : block-len(tail)]
+	if got := strings.Join(lines[:len(head)], "\n"); got != strings.Join(head, "\n") {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, strings.Join(head, "\n"))
+	}
+	if got := strings.Join(lines[block-len(tail):block], "\n"); got != strings.Join(tail, "\n") {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, strings.Join(tail, "\n"))
+	}
+	for _, option := range options {
+		if !slices.Contains(optionLines, option) {
+			t.Errorf("Missing %q in the option lines:\n%s", option, strings.Join(optionLines, "\n"))
+		}
+	}
+	for _, line := range optionLines {
+		if !strings.HasPrefix(line, "# option: ") || strings.Contains(line, "secret") {
+			t.Errorf("Unexpected option line %q", line)
+		}
+	}
+
+	// Only tabular outputs have metadata lines
+	for _, format := range []string{"fasta", "ndjson", "json"} {
+		output, err := runWithArgs([]string{"seqhasher", "--out-format", format, testFastaPath})
+		if err != nil {
+			t.Fatalf("run() error = %v", err)
+		}
+		if strings.Contains(output, "seqhasher_table_schema") {
+			t.Errorf("Unexpected metadata in %s output:\n%s", format, output)
+		}
+	}
+
+	output, err = runWithArgs([]string{"seqhasher", "--out-format", "csv", "--no-metadata", testFastaPath})
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !strings.HasPrefix(output, "file,sha1,id,") {
+		t.Errorf("Expected the column names in the first line with --no-metadata, got:\n%s", output)
+	}
+}
+
+func TestRecordDelimiter(t *testing.T) {
+	input := ">a\nAC\n>b\nGTT\n>c\nCCCC\n"
+	sha1 := getHashFunc("sha1")
+	a, b, c := sha1([]byte("AC"))+";a", sha1([]byte("GTT"))+";b", sha1([]byte("CCCC"))+";c"
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected string
+	}{
+		{"Blank line", Config{RecordDelimiter: "\n"}, ">" + a + "\nAC\n\n>" + b + "\nGTT\n\n>" + c + "\nCCCC\n"},
+		{"Custom marker", Config{RecordDelimiter: "//\n"}, ">" + a + "\nAC\n//\n>" + b + "\nGTT\n//\n>" + c + "\nCCCC\n"},
+		{"Headers only", Config{RecordDelimiter: "\n", HeadersOnly: true}, a + "\n\n" + b + "\n\n" + c + "\n"},
+		{"Length bins", Config{RecordDelimiter: "\n", LengthBin: 3},
+			"; length-bin: 0-2\n>" + a + "\nAC\n\n; length-bin: 3-5\n>" + b + "\nGTT\n\n>" + c + "\nCCCC\n"},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			tt.cfg.HashTypes = []string{"sha1"}
+			tt.cfg.NoFileName = true
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(input), output, tt.cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Got output:\n%q\nwant:\n%q", output.String(), tt.expected)
+			}
+		})
+	}
+
+	// Offsets of the index and spot checks skip the delimiters
+	indexPath := filepath.Join(t.TempDir(), "out.idx")
+	cfg := Config{HashTypes: []string{"sha1"}, InputFileName: "test.fasta", RecordDelimiter: "//\n", IndexFileName: indexPath, SpotCheck: 1}
+	output := &bytes.Buffer{}
+	if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	content, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		parts := strings.Split(line, "\t")
+		offset, _ := strconv.Atoi(parts[2])
+		length, _ := strconv.Atoi(parts[3])
+		record := output.String()[offset : offset+length]
+		if !strings.HasPrefix(record, ">") || strings.Contains(record, "//") {
+			t.Errorf("Index entry %q points at %q", line, record)
+		}
+	}
+}
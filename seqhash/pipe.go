@@ -0,0 +1,54 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// pipedOutput feeds the output into the stdin of an external command (--pipe-to),
+// whose stdout goes to the final destination
+type pipedOutput struct {
+	command string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+}
+
+// startPipe runs the command with the shell (so that arguments and quoting work as in a terminal)
+func startPipe(command string, dest io.Writer) (*pipedOutput, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = dest
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Error starting command %q: %v", command, err)
+	}
+	return &pipedOutput{command: command, cmd: cmd, stdin: stdin}, nil
+}
+
+func (p *pipedOutput) Write(b []byte) (int, error) {
+	n, err := p.stdin.Write(b)
+	if err != nil {
+		// Usually the command exited early; its exit status is reported by Close
+		return n, fmt.Errorf("Error writing to command %q: %v", p.command, err)
+	}
+	return n, nil
+}
+
+// Close signals the end of input to the command and waits for it to finish
+func (p *pipedOutput) Close() error {
+	p.stdin.Close()
+	if err := p.cmd.Wait(); err != nil {
+		return fmt.Errorf("Command %q failed: %v", p.command, err)
+	}
+	return nil
+}
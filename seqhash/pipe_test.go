@@ -0,0 +1,64 @@
+package seqhash
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPipeTo(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("No shell available")
+	}
+
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+	direct := filepath.Join(tmpDir, "direct.fasta")
+	piped := filepath.Join(tmpDir, "piped.fasta")
+
+	if _, err := runWithArgs([]string{"seqhasher", input, direct}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if _, err := runWithArgs([]string{"seqhasher", "--pipe-to", "cat", input, piped}); err != nil {
+		t.Fatalf("run() with --pipe-to error = %v", err)
+	}
+	want, _ := os.ReadFile(direct)
+	got, _ := os.ReadFile(piped)
+	if len(want) == 0 || string(got) != string(want) {
+		t.Errorf("Piped output differs:\nGot:\n%s\nWant:\n%s", got, want)
+	}
+
+	// Output to stdout also goes through the command
+	output, err := runWithArgs([]string{"seqhasher", "--pipe-to", "cat", input})
+	if err != nil || output != string(want) {
+		t.Errorf("Piped stdout = %q, %v", output, err)
+	}
+}
+
+func TestPipeToFailure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("No shell available")
+	}
+
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "in.fasta")
+	if err := os.WriteFile(input, []byte(testSequences), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, command := range []string{"exit 3", "nonexistent-command-for-seqhasher-test"} {
+		output := filepath.Join(tmpDir, "out.fasta")
+		_, err := runWithArgs([]string{"seqhasher", "--pipe-to", command, input, output})
+		if err == nil || !strings.Contains(strings.ToLower(err.Error()), "command") {
+			t.Errorf("%s: expected a command error, got %v", command, err)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("%s: expected the incomplete output to be removed", command)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Position of a record in the (decompressed) input: line number (1-based) and byte offset of its header
+type recordPosition struct {
+	line   int64
+	offset int64
+}
+
+// Kinds of FASTQ lines, as seen by positionTracker
+const (
+	lineOther = iota // E.g., blank lines between records
+	lineHeader
+	lineSequence
+	lineSeparator // '+' line
+	lineQuality
+)
+
+// positionTracker counts the lines of the input as it is read by the parser,
+// and records where each record starts (--debug-positions). Header lines are
+// recognized by '>' in FASTA; in FASTQ, by '@' after the quality of the previous
+// record is complete, so that quality lines starting with '@' are not mistaken for headers.
+// The parser reads ahead (possibly in another goroutine), so positions are queued
+// until their records are taken.
+type positionTracker struct {
+	r io.Reader
+
+	line, offset int64
+	lineStart    bool
+	format       byte // '>' or '@' (0 before the first record)
+	kind         int  // Kind of the current FASTQ line
+	expect       int  // Kind of the next FASTQ line (lineSequence also allows a separator)
+	lineLength   int
+	seqLength    int // FASTQ sequence and quality lengths of the current record
+	qualLength   int
+
+	mu    sync.Mutex
+	queue []recordPosition
+	taken int64 // Number of records taken
+}
+
+func newPositionTracker(r io.Reader) *positionTracker {
+	return &positionTracker{r: r, line: 1, lineStart: true, expect: lineHeader}
+}
+
+func (t *positionTracker) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	for _, c := range p[:n] {
+		if t.lineStart {
+			t.lineStart = false
+			t.startLine(c)
+		}
+		switch c {
+		case '\n':
+			t.endLine()
+			t.line++
+			t.lineStart = true
+		case '\r':
+		default:
+			t.lineLength++
+		}
+		t.offset++
+	}
+	return n, err
+}
+
+// startLine determines the kind of a line from its first byte
+func (t *positionTracker) startLine(c byte) {
+	if t.format == 0 && (c == '>' || c == '@') {
+		t.format = c
+	}
+	t.kind = lineOther
+	switch t.format {
+	case '>':
+		if c == '>' {
+			t.push()
+		}
+	case '@':
+		switch {
+		case t.expect == lineHeader && c == '@':
+			t.kind = lineHeader
+			t.push()
+		case t.expect == lineSequence && c == '+':
+			t.kind = lineSeparator
+		case t.expect == lineSequence || t.expect == lineQuality:
+			t.kind = t.expect
+		}
+	}
+}
+
+func (t *positionTracker) endLine() {
+	switch t.kind {
+	case lineHeader:
+		t.expect, t.seqLength, t.qualLength = lineSequence, 0, 0
+	case lineSequence:
+		t.seqLength += t.lineLength
+	case lineSeparator:
+		t.expect = lineQuality // Also for an empty sequence, whose quality line is empty
+	case lineQuality:
+		t.qualLength += t.lineLength
+		if t.qualLength >= t.seqLength {
+			t.expect = lineHeader
+		}
+	}
+	t.lineLength = 0
+}
+
+// push queues the position of the header that starts at the current line
+func (t *positionTracker) push() {
+	t.mu.Lock()
+	t.queue = append(t.queue, recordPosition{line: t.line, offset: t.offset})
+	t.mu.Unlock()
+}
+
+// take returns the number (1-based) and the position of the next record
+func (t *positionTracker) take() (int64, recordPosition, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.taken++
+	if len(t.queue) == 0 {
+		return t.taken, recordPosition{}, false
+	}
+	pos := t.queue[0]
+	t.queue = t.queue[1:]
+	return t.taken, pos, true
+}
+
+// describeFailure locates a record that could not be read (the one after the last taken)
+func (t *positionTracker) describeFailure() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) == 0 {
+		return fmt.Sprintf("after record %d, near line %d", t.taken, t.line)
+	}
+	return fmt.Sprintf("record %d, header at line %d", t.taken+1, t.queue[0].line)
+}
+
+// positionsWriter writes the --debug-positions sidecar: a TSV with the number (1-based),
+// header line, byte offset, and ID of each input record, including records that are not output
+type positionsWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func newPositionsWriter(fileName string) (*positionsWriter, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	pw := &positionsWriter{file: file, writer: bufio.NewWriter(file)}
+	_, err = fmt.Fprintln(pw.writer, "record\tline\toffset\tid")
+	return pw, err
+}
+
+// add writes the position of a record (line and offset are empty if unknown)
+func (pw *positionsWriter) add(record int64, pos recordPosition, known bool, id []byte) error {
+	if !known {
+		_, err := fmt.Fprintf(pw.writer, "%d\t\t\t%s\n", record, id)
+		return err
+	}
+	_, err := fmt.Fprintf(pw.writer, "%d\t%d\t%d\t%s\n", record, pos.line, pos.offset, id)
+	return err
+}
+
+// Close flushes the sidecar and closes the file (subsequent calls are no-ops)
+func (pw *positionsWriter) Close() error {
+	if pw.file == nil {
+		return nil
+	}
+	err := pw.writer.Flush()
+	if cerr := pw.file.Close(); err == nil {
+		err = cerr
+	}
+	pw.file = nil
+	return err
+}
@@ -0,0 +1,71 @@
+package seqhash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugPositions(t *testing.T) {
+	fasta, err := os.ReadFile("../test/test.fasta")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		cfg      Config
+		expected string
+	}{
+		{"Test records", string(fasta), Config{},
+			"record\tline\toffset\tid\n" +
+				"1\t1\t0\tseq1\n" +
+				"2\t3\t11\tseq1_lowercase\n" +
+				"3\t5\t32\tseq2\n"},
+		{"Test records, multithreaded", string(fasta), Config{Threads: 2},
+			"record\tline\toffset\tid\n" +
+				"1\t1\t0\tseq1\n" +
+				"2\t3\t11\tseq1_lowercase\n" +
+				"3\t5\t32\tseq2\n"},
+		{"Excluded and duplicate records are listed", string(fasta), Config{Dedup: true, includeIDs: map[string]struct{}{"seq2": {}}},
+			"record\tline\toffset\tid\n" +
+				"1\t1\t0\tseq1\n" +
+				"2\t3\t11\tseq1_lowercase\n" +
+				"3\t5\t32\tseq2\n"},
+		// Quality lines starting with '@' and wrapped or empty records
+		{"FASTQ", "@r1\nAC\nGT\n+\n@I\nII\n\n@r2\n\n+\n\n@r3\r\nA\r\n+\r\n@\r\n", Config{},
+			"record\tline\toffset\tid\n" +
+				"1\t1\t0\tr1\n" +
+				"2\t8\t19\tr2\n" +
+				"3\t12\t27\tr3\n"},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1"}
+			cfg.DebugPositions = filepath.Join(t.TempDir(), "positions.tsv")
+			if err := processSequences(strings.NewReader(tt.input), &strings.Builder{}, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			got, err := os.ReadFile(cfg.DebugPositions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Got:\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+
+	runTest(t, "Malformed record", func(t *testing.T) {
+		cfg := Config{HashTypes: []string{"sha1"}, DebugPositions: filepath.Join(t.TempDir(), "positions.tsv")}
+		input := "@a\nAC\n+\nII\n@b\nAC\nII\n"
+		err := processSequences(strings.NewReader(input), &strings.Builder{}, cfg)
+		if err == nil || !strings.HasSuffix(err.Error(), "(record 2, header at line 5)") {
+			t.Errorf("Expected the position of the malformed record, got %v", err)
+		}
+	})
+}
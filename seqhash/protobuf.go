@@ -0,0 +1,50 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/vmikk/seqhasher/recordpb"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// The protobuf output (--out-format protobuf) follows the messages of recordpb/record.proto;
+// the types in package recordpb are generated from it (go generate ./recordpb)
+
+// protobufWriter streams records as length-delimited Record messages:
+// each message is preceded by its size as a varint
+type protobufWriter struct {
+	sinkStream
+	headersOnly bool
+	label       string       // File label (empty if omitted)
+	buf         bytes.Buffer // Reused between records
+}
+
+func (pw *protobufWriter) WriteRecord(r Record) error {
+	pr := &recordpb.Record{
+		Id:       string(r.ID),
+		Filename: pw.label,
+		Hashes:   make([]*recordpb.Hash, len(r.HashTypes)),
+	}
+	for i, hashType := range r.HashTypes {
+		pr.Hashes[i] = &recordpb.Hash{Type: hashType, Digest: r.Hashes[i]}
+	}
+	if !pw.headersOnly {
+		pr.Sequence, pr.Quality = r.Sequence, r.Quality
+	}
+
+	// The size and the message are written at once, so that index offsets point at the size
+	pw.buf.Reset()
+	if _, err := protodelim.MarshalTo(&pw.buf, pr); err != nil {
+		return fmt.Errorf("Error encoding record: %v", err)
+	}
+	if _, err := pw.w.Write(pw.buf.Bytes()); err != nil {
+		return fmt.Errorf("Error writing record: %v", err)
+	}
+	return nil
+}
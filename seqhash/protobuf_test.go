@@ -0,0 +1,88 @@
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vmikk/seqhasher/recordpb"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// decodeProtoRecords reads a stream of length-delimited Record messages with the protobuf runtime
+func decodeProtoRecords(t *testing.T, stream []byte) []*recordpb.Record {
+	t.Helper()
+	var records []*recordpb.Record
+	r := bufio.NewReader(bytes.NewReader(stream))
+	for {
+		record := &recordpb.Record{}
+		err := protodelim.UnmarshalFrom(r, record)
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Error decoding record %d: %v", len(records), err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestProtobufOutput(t *testing.T) {
+	sha1, xxhash := getHashFunc("sha1"), getHashFunc("xxhash")
+	hashes := func(seq string) []*recordpb.Hash {
+		return []*recordpb.Hash{{Type: "sha1", Digest: sha1([]byte(seq))}, {Type: "xxhash", Digest: xxhash([]byte(seq))}}
+	}
+	// The second sequence is long enough for a multi-byte size
+	long := strings.Repeat("ACGT", 50)
+
+	tests := []struct {
+		name     string
+		input    string
+		cfg      Config
+		expected []*recordpb.Record
+	}{
+		{"FASTA", ">a desc\nacgt\n>b\n" + long + "\n", Config{},
+			[]*recordpb.Record{
+				{Id: "a", Filename: "input.fasta", Hashes: hashes("ACGT"), Sequence: []byte("ACGT")},
+				{Id: "b", Filename: "input.fasta", Hashes: hashes(long), Sequence: []byte(long)},
+			}},
+		{"FASTQ", "@r1\nACGT\n+\nIIII\n", Config{NoFileName: true},
+			[]*recordpb.Record{{Id: "r1", Hashes: hashes("ACGT"), Sequence: []byte("ACGT"), Quality: []byte("IIII")}}},
+		{"Headers only", ">a\nACGT\n", Config{HeadersOnly: true},
+			[]*recordpb.Record{{Id: "a", Filename: "input.fasta", Hashes: hashes("ACGT")}}},
+		{"Deduplicated", ">a\nACGT\n>b\nACGT\n", Config{Dedup: true, NoFileName: true},
+			[]*recordpb.Record{{Id: "a", Hashes: hashes("ACGT"), Sequence: []byte("ACGT")}}},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			tt.cfg.HashTypes = []string{"sha1", "xxhash"}
+			tt.cfg.OutFormat = "protobuf"
+			tt.cfg.InputFileName = "input.fasta"
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), output, tt.cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			records := decodeProtoRecords(t, output.Bytes())
+			if len(records) != len(tt.expected) {
+				t.Fatalf("Got %d records, want %d", len(records), len(tt.expected))
+			}
+			for i, record := range records {
+				if !proto.Equal(record, tt.expected[i]) {
+					t.Errorf("Got record %v, want %v", record, tt.expected[i])
+				}
+			}
+		})
+	}
+
+	runTest(t, "Empty input", func(t *testing.T) {
+		output := &bytes.Buffer{}
+		cfg := Config{HashTypes: []string{"sha1"}, OutFormat: "protobuf"}
+		if err := processSequences(strings.NewReader(""), output, cfg); err != nil || output.Len() != 0 {
+			t.Errorf("Expected no output, got %d bytes (%v)", output.Len(), err)
+		}
+	})
+}
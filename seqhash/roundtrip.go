@@ -0,0 +1,125 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+)
+
+// Bytes shown on each side of the first difference in round-trip validation errors
+const roundtripContext = 16
+
+// originalRecord holds copies of the sequence and qualities of an input record,
+// as read and before normalization (--validate-roundtrip)
+type originalRecord struct {
+	seq  []byte
+	qual []byte // nil for FASTA
+}
+
+// roundtripValidator verifies every output record (--validate-roundtrip): the bytes written
+// for the record (before compression) are parsed back, and the written sequence and qualities
+// must equal those of the input record after the documented transformations only
+// (whitespace removal and, unless --casesensitive, uppercasing; on request, trimming of
+// terminal Ns and collapsing of homopolymers). Unlike spot checks, this does not rely
+// on the digests, so any change of the sequence content is detected, even if it was hashed.
+type roundtripValidator struct {
+	validated int64
+	cfg       Config
+}
+
+// newRoundtripValidator returns nil if round-trip validation was not requested
+func newRoundtripValidator(cfg Config) *roundtripValidator {
+	if !cfg.ValidateRoundtrip {
+		return nil
+	}
+	return &roundtripValidator{cfg: cfg}
+}
+
+// expected returns the sequence and qualities that are to be written for the original record.
+// The transformations are implemented independently of the normalization of the hashed sequences.
+func (v *roundtripValidator) expected(original *originalRecord) ([]byte, []byte) {
+	seq := bytes.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, original.seq)
+	if !v.cfg.CaseSensitive {
+		seq = bytes.ToUpper(seq)
+	}
+	qual := original.qual
+
+	if v.cfg.EmitTrimmed {
+		start, end := 0, len(seq)
+		for start < end && (seq[start] == 'N' || seq[start] == 'n') {
+			start++
+		}
+		for end > start && (seq[end-1] == 'N' || seq[end-1] == 'n') {
+			end--
+		}
+		if len(qual) == len(seq) {
+			qual = qual[start:end]
+		}
+		seq = seq[start:end]
+	}
+	if v.cfg.EmitCollapsed {
+		var collapsedQual []byte
+		seq, collapsedQual = collapseRuns(seq, qual)
+		if collapsedQual != nil {
+			qual = collapsedQual
+		}
+	}
+	return seq, qual
+}
+
+// verify checks the captured bytes of a record (at the given index and byte offset of the output)
+// against its original sequence and qualities
+func (v *roundtripValidator) verify(written []byte, index, offset int64, id []byte, original *originalRecord) error {
+	fail := func(format string, args ...any) error {
+		return fmt.Errorf("Round-trip validation failed (record index %d, byte offset %d, ID %q): %s",
+			index, offset, id, fmt.Sprintf(format, args...))
+	}
+
+	if v.cfg.KeepComments {
+		written = withoutInnerComments(written)
+	}
+	lines := bytes.SplitN(written, []byte("\n"), 5)
+	if len(lines) < 3 || len(lines[0]) == 0 || (lines[0][0] != '>' && lines[0][0] != '@') {
+		return fail("the written record is malformed (%q)", truncateSequence(written, 200))
+	}
+	seq, qual := v.expected(original)
+	if !bytes.Equal(lines[1], seq) {
+		return fail("the written sequence differs from the input\n%s", hexDifference(seq, lines[1]))
+	}
+	if lines[0][0] == '@' {
+		if len(lines) < 5 || !bytes.Equal(lines[2], []byte("+")) {
+			return fail("the written FASTQ record is malformed (%q)", truncateSequence(written, 200))
+		}
+		if !bytes.Equal(lines[3], qual) {
+			return fail("the written quality scores differ from the input\n%s", hexDifference(qual, lines[3]))
+		}
+	}
+	v.validated++
+	return nil
+}
+
+// hexDifference hex-dumps the expected and the written bytes around their first difference
+func hexDifference(expected, written []byte) string {
+	pos := 0
+	for pos < len(expected) && pos < len(written) && expected[pos] == written[pos] {
+		pos++
+	}
+	dump := func(b []byte) string {
+		start, end := max(pos-roundtripContext, 0), min(pos+roundtripContext, len(b))
+		if start >= end {
+			return fmt.Sprintf("(%d bytes)", len(b))
+		}
+		return fmt.Sprintf("bytes %d-%d of %d: % x", start+1, end, len(b), b[start:end])
+	}
+	return fmt.Sprintf("  first difference at byte %d (1-based)\n  expected %s\n  written  %s", pos+1, dump(expected), dump(written))
+}
@@ -0,0 +1,111 @@
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoundtripCorpus(t *testing.T) {
+	inputs, err := filepath.Glob("../test/*.fast*")
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("No test inputs found: %v", err)
+	}
+	configs := map[string]Config{
+		"Default":           {},
+		"Case-sensitive":    {CaseSensitive: true},
+		"Trimmed Ns":        {TrimNs: true, EmitTrimmed: true},
+		"Collapsed":         {CollapseHomopolymers: true, EmitCollapsed: true},
+		"Deduplicated":      {Dedup: true, SizeOut: true, ReverseOutput: true},
+		"Threads":           {Threads: 4, SpotCheck: 1},
+		"Record delimiters": {RecordDelimiter: "\n", LengthBin: 10},
+	}
+	for name, cfg := range configs {
+		for _, input := range inputs {
+			cfg := cfg
+			cfg.HashTypes = []string{"sha1", "xxhash"}
+			cfg.OutFormat = "fasta"
+			cfg.InputFileName = input
+			cfg.ValidateRoundtrip = true
+			if _, err := processFile(io.Discard, cfg); err != nil {
+				t.Errorf("%s, %s: %v", name, input, err)
+			}
+		}
+	}
+}
+
+// caseChangingWriter lowercases the n-th write (1-based), which does not change
+// the digests of the written sequence, as it is uppercased before hashing
+type caseChangingWriter struct {
+	w     io.Writer
+	n     int
+	calls int
+}
+
+func (c *caseChangingWriter) Write(p []byte) (int, error) {
+	c.calls++
+	if c.calls == c.n {
+		header, seq, _ := bytes.Cut(p, []byte("\n"))
+		p = append(append(bytes.Clone(header), '\n'), bytes.ToLower(seq)...)
+	}
+	return c.w.Write(p)
+}
+
+func TestRoundtripDetectsCorruption(t *testing.T) {
+	// Records are corrupted between the sink and the (tapped) output stream
+	run := func(input string, cfg Config, corrupt func(io.Writer) io.Writer) error {
+		cfg.HashTypes = []string{"sha1"}
+		cfg.NoFileName = true
+		writer := bufio.NewWriter(io.Discard)
+		counter := &countingWriter{w: writer}
+		_, err := processStream(context.Background(), strings.NewReader(input), cfg, counter, func(cfg Config, label string) OutputSink {
+			return newOutputSink(corrupt(counter), writer, cfg, label)
+		})
+		return err
+	}
+
+	runTest(t, "Sequence", func(t *testing.T) {
+		err := run(randomRecords(10, 50), Config{ValidateRoundtrip: true}, func(w io.Writer) io.Writer { return &corruptingWriter{w: w, n: 4} })
+		if err == nil || !strings.HasPrefix(err.Error(), `Round-trip validation failed (record index 3, byte offset 291, ID "seq"): the written sequence differs from the input`) ||
+			!strings.Contains(err.Error(), "first difference at byte 50 (1-based)") {
+			t.Fatalf("Expected a sequence mismatch at record index 3, got %v", err)
+		}
+	})
+
+	runTest(t, "Qualities", func(t *testing.T) {
+		input := "@a\nACTG\n+\nIIII\n@b\nACTG\n+\nIIII\n"
+		err := run(input, Config{ValidateRoundtrip: true}, func(w io.Writer) io.Writer { return &corruptingWriter{w: w, n: 2} })
+		if err == nil || !strings.Contains(err.Error(), `(record index 1, byte offset 56, ID "b"): the written quality scores differ from the input`) ||
+			!strings.Contains(err.Error(), "expected bytes 1-4 of 4: 49 49 49 49\n  written  bytes 1-4 of 4: 49 49 49 4b") {
+			t.Fatalf("Expected a quality mismatch with a hex dump, got %v", err)
+		}
+	})
+
+	runTest(t, "Case change missed by spot checks", func(t *testing.T) {
+		input := ">a\nACTG\n>b\nACTG\n"
+		corrupt := func(w io.Writer) io.Writer { return &caseChangingWriter{w: w, n: 2} }
+		if err := run(input, Config{SpotCheck: 1}, corrupt); err != nil {
+			t.Fatalf("Expected spot checks to accept the lowercased sequence, got %v", err)
+		}
+		if err := run(input, Config{SpotCheck: 1, ValidateRoundtrip: true}, corrupt); err == nil || !strings.Contains(err.Error(), "the written sequence differs") {
+			t.Fatalf("Expected the lowercased sequence to fail validation, got %v", err)
+		}
+	})
+
+	runTest(t, "Original case", func(t *testing.T) {
+		// With --casesensitive, the written sequence must equal the original bytes
+		input := ">a\nAcTg\n"
+		cfg := Config{CaseSensitive: true, ValidateRoundtrip: true}
+		if err := run(input, cfg, func(w io.Writer) io.Writer { return w }); err != nil {
+			t.Fatalf("Expected a mixed-case sequence to pass, got %v", err)
+		}
+		err := run(input, cfg, func(w io.Writer) io.Writer { return &caseChangingWriter{w: w, n: 1} })
+		if err == nil || !strings.Contains(err.Error(), "first difference at byte 1") {
+			t.Fatalf("Expected a case mismatch at the first base, got %v", err)
+		}
+	})
+}
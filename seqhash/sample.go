@@ -0,0 +1,129 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// stratum is a rule of --stratified-sample: the unique sequences with a total abundance
+// of at least minSize (0 for the 'rest' stratum) are kept with the given fraction
+type stratum struct {
+	minSize  int64
+	fraction float64
+}
+
+func (s stratum) String() string {
+	if s.minSize == 0 {
+		return "rest"
+	}
+	return fmt.Sprintf("size>=%d", s.minSize)
+}
+
+// parseStrata parses a --stratified-sample specification (e.g., 'size>=100:1.0,size>=10:0.1,rest:0.01').
+// Thresholds must decrease, and the last stratum must be 'rest', so that every sequence falls into one stratum.
+func parseStrata(spec string) ([]stratum, error) {
+	var strata []stratum
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		rule, fraction, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("Invalid stratified sample stratum: %q. Expected 'size>=<n>:<fraction>' or 'rest:<fraction>'", item)
+		}
+		if len(strata) > 0 && strata[len(strata)-1].minSize == 0 {
+			return nil, fmt.Errorf("Invalid stratified sample: 'rest' must be the last stratum, got %q after it", item)
+		}
+
+		var s stratum
+		var err error
+		if s.fraction, err = strconv.ParseFloat(fraction, 64); err != nil || !(s.fraction >= 0 && s.fraction <= 1) {
+			return nil, fmt.Errorf("Invalid stratified sample fraction in %q. Must be a number between 0 and 1", item)
+		}
+		switch {
+		case rule == "rest":
+		case strings.HasPrefix(rule, "size>="):
+			s.minSize, err = strconv.ParseInt(strings.TrimPrefix(rule, "size>="), 10, 64)
+			if err != nil || s.minSize < 1 {
+				return nil, fmt.Errorf("Invalid stratified sample threshold in %q. Must be a positive integer", item)
+			}
+			if len(strata) > 0 && s.minSize >= strata[len(strata)-1].minSize {
+				return nil, fmt.Errorf("Invalid stratified sample: thresholds must decrease, got %s after %s", s, strata[len(strata)-1])
+			}
+		default:
+			return nil, fmt.Errorf("Invalid stratified sample stratum: %q. Expected 'size>=<n>:<fraction>' or 'rest:<fraction>'", item)
+		}
+		strata = append(strata, s)
+	}
+	if strata[len(strata)-1].minSize != 0 {
+		return nil, fmt.Errorf("Invalid stratified sample: the last stratum must be 'rest:<fraction>' (use 'rest:0' to drop the remaining sequences)")
+	}
+	return strata, nil
+}
+
+// sampledDigest is the deterministic selection rule of hash-based sampling: a record is kept
+// if the xxHash of its digest, scaled to [0, 1), is below the fraction. The same digest is always
+// kept or dropped at a given fraction, and digests kept at a fraction are also kept at larger ones.
+func sampledDigest(digest string, fraction float64) bool {
+	if fraction >= 1 {
+		return true
+	}
+	// 53 bits are exactly representable as a float64
+	return float64(xxhash.Sum64String(digest)>>11)/(1<<53) < fraction
+}
+
+// Summary of a --stratified-sample stratum
+type stratumSummary struct {
+	Stratum  string  `json:"stratum"`
+	Fraction float64 `json:"fraction"`
+	Input    int64   `json:"input"` // Unique sequences in the stratum
+	Kept     int64   `json:"kept"`
+}
+
+// stratifiedSampler assigns unique sequences to strata and counts them
+type stratifiedSampler struct {
+	strata  []stratum
+	summary []stratumSummary
+}
+
+// newStratifiedSampler returns nil if sampling was not requested
+func newStratifiedSampler(cfg Config) *stratifiedSampler {
+	if cfg.strata == nil {
+		return nil
+	}
+	s := &stratifiedSampler{strata: cfg.strata}
+	for _, st := range cfg.strata {
+		s.summary = append(s.summary, stratumSummary{Stratum: st.String(), Fraction: st.fraction})
+	}
+	return s
+}
+
+// keep reports whether a unique sequence with the total abundance and the digest is sampled
+func (s *stratifiedSampler) keep(size int64, digest string) bool {
+	i := 0
+	for s.strata[i].minSize > 0 && size < s.strata[i].minSize {
+		i++
+	}
+	s.summary[i].Input++
+	if !sampledDigest(digest, s.strata[i].fraction) {
+		return false
+	}
+	s.summary[i].Kept++
+	return true
+}
+
+// report describes the sampled strata, one line each
+func (s *stratifiedSampler) report() []string {
+	lines := make([]string, 0, len(s.summary))
+	for _, st := range s.summary {
+		lines = append(lines, fmt.Sprintf("Stratified sample %s (fraction %g): kept %d of %d unique sequence(s)",
+			st.Stratum, st.Fraction, st.Kept, st.Input))
+	}
+	return lines
+}
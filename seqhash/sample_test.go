@@ -0,0 +1,145 @@
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParseStrata(t *testing.T) {
+	strata, err := parseStrata("size>=100:1.0, size>=10:0.1,rest:0.01")
+	expected := []stratum{{100, 1}, {10, 0.1}, {0, 0.01}}
+	if err != nil || fmt.Sprint(strata) != fmt.Sprint(expected) || strata[2].fraction != 0.01 {
+		t.Errorf("parseStrata() = %v, %v, want %v", strata, err, expected)
+	}
+
+	errors := map[string]string{
+		"size>=100:1":                     "Invalid stratified sample: the last stratum must be 'rest:<fraction>' (use 'rest:0' to drop the remaining sequences)",
+		"rest:0.5,size>=10:1":             `Invalid stratified sample: 'rest' must be the last stratum, got "size>=10:1" after it`,
+		"size>=10:1,size>=10:0.5,rest:0":  "Invalid stratified sample: thresholds must decrease, got size>=10 after size>=10",
+		"size>=10:1,size>=100:0.5,rest:0": "Invalid stratified sample: thresholds must decrease, got size>=100 after size>=10",
+		"size>=0:1,rest:0":                `Invalid stratified sample threshold in "size>=0:1". Must be a positive integer`,
+		"size>=ten:1,rest:0":              `Invalid stratified sample threshold in "size>=ten:1". Must be a positive integer`,
+		"size>=10:1.5,rest:0":             `Invalid stratified sample fraction in "size>=10:1.5". Must be a number between 0 and 1`,
+		"rest:NaN":                        `Invalid stratified sample fraction in "rest:NaN". Must be a number between 0 and 1`,
+		"size>10:1,rest:0":                `Invalid stratified sample stratum: "size>10:1". Expected 'size>=<n>:<fraction>' or 'rest:<fraction>'`,
+		"rest":                            `Invalid stratified sample stratum: "rest". Expected 'size>=<n>:<fraction>' or 'rest:<fraction>'`,
+		"":                                `Invalid stratified sample stratum: "". Expected 'size>=<n>:<fraction>' or 'rest:<fraction>'`,
+	}
+	for spec, message := range errors {
+		if _, err := parseStrata(spec); err == nil || err.Error() != message {
+			t.Errorf("parseStrata(%q) error = %v, want %q", spec, err, message)
+		}
+	}
+}
+
+// abundanceRecords returns records of random unique sequences ('u<i>') with the abundances
+func abundanceRecords(seed int64, sizes []int64) (string, map[string]string) {
+	rng := rand.New(rand.NewSource(seed))
+	var b strings.Builder
+	sequences := make(map[string]string)
+	for i, size := range sizes {
+		seq := make([]byte, 40)
+		for j := range seq {
+			seq[j] = "ACGT"[rng.Intn(4)]
+		}
+		id := fmt.Sprintf("u%d", i)
+		sequences[id] = string(seq)
+		fmt.Fprintf(&b, ">%s;size=%d\n%s\n", id, size, seq)
+	}
+	return b.String(), sequences
+}
+
+// sampledIDs runs a stratified sample and returns the IDs of the output records and the summary
+func sampledIDs(t *testing.T, input string, spec string) (map[string]bool, []stratumSummary) {
+	t.Helper()
+	strata, err := parseStrata(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, HeadersOnly: true, Dedup: true, SizeIn: true, strata: strata}
+	output := &bytes.Buffer{}
+	stats, err := processRecords(strings.NewReader(input), output, cfg)
+	if err != nil {
+		t.Fatalf("processRecords() error = %v", err)
+	}
+	ids := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		ids[strings.Split(line, ";")[1]] = true
+	}
+	return ids, stats.strata
+}
+
+func TestStratifiedSample(t *testing.T) {
+	// Abundances at the boundaries of the strata, then a long-tailed distribution
+	sizes := []int64{100, 99, 10, 9, 1}
+	for i := 0; len(sizes) < 5000; i++ {
+		sizes = append(sizes, 1+int64(1000/(1+i%400)))
+	}
+	input, sequences := abundanceRecords(1, sizes)
+	spec := "size>=100:1,size>=10:0.1,rest:0.01"
+	ids, summary := sampledIDs(t, input, spec)
+
+	sha1 := getHashFunc("sha1")
+	var input100, input10, inputRest int64
+	for i, size := range sizes {
+		id := fmt.Sprintf("u%d", i)
+		var expected bool
+		switch {
+		case size >= 100:
+			input100++
+			expected = true
+		case size >= 10:
+			input10++
+			expected = sampledDigest(sha1([]byte(sequences[id])), 0.1)
+		default:
+			inputRest++
+			expected = sampledDigest(sha1([]byte(sequences[id])), 0.01)
+		}
+		if ids[id] != expected {
+			t.Errorf("Record %s (size=%d): kept = %v, want %v", id, size, ids[id], expected)
+		}
+	}
+
+	if len(summary) != 3 || summary[0].Input != input100 || summary[1].Input != input10 || summary[2].Input != inputRest {
+		t.Fatalf("Unexpected strata %+v (input %d, %d, %d)", summary, input100, input10, inputRest)
+	}
+	if summary[0].Kept != input100 || summary[0].Kept+summary[1].Kept+summary[2].Kept != int64(len(ids)) {
+		t.Errorf("Unexpected kept counts %+v of %d output records", summary, len(ids))
+	}
+	// The kept fractions approximate the requested ones
+	for _, s := range summary[1:] {
+		if kept := float64(s.Kept) / float64(s.Input); kept < s.Fraction/2 || kept > s.Fraction*2 {
+			t.Errorf("Stratum %s: kept %d of %d, far from the fraction %g", s.Stratum, s.Kept, s.Input, s.Fraction)
+		}
+	}
+
+	runTest(t, "Abundances of duplicates are summed", func(t *testing.T) {
+		// 60 + 40 records of the same sequence fall into the top stratum
+		ids, summary := sampledIDs(t, ">a;size=60\nACGT\n>b;size=40\nACGT\n", "size>=100:1,rest:0")
+		if !ids["a"] || summary[0].Input != 1 || summary[1].Input != 0 {
+			t.Errorf("Got %v, %+v", ids, summary)
+		}
+	})
+
+	runTest(t, "Replicate datasets", func(t *testing.T) {
+		// The same sequences, in reverse order and among others, are sampled alike
+		var replicate strings.Builder
+		records := strings.SplitAfter(input, "\n")
+		for i := len(records) - 3; i >= 0; i -= 2 {
+			replicate.WriteString(records[i] + records[i+1])
+		}
+		others, _ := abundanceRecords(2, sizes[:1000])
+		replicate.WriteString(strings.ReplaceAll(others, ">u", ">v"))
+
+		replicateIDs, _ := sampledIDs(t, replicate.String(), spec)
+		for i := range sizes {
+			id := fmt.Sprintf("u%d", i)
+			if ids[id] != replicateIDs[id] {
+				t.Errorf("Record %s: kept = %v in the dataset, %v in its replicate", id, ids[id], replicateIDs[id])
+			}
+		}
+	})
+}
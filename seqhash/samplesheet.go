@@ -0,0 +1,103 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// How inputs are matched against the first column of the sample sheet
+var supportedJoinKeys = []string{"path", "basename", "name-label"}
+
+// What to do with inputs that are not listed in the sample sheet
+var supportedSheetMissing = []string{"warn", "fail", "skip-columns"}
+
+// Metadata attached to every record of an input
+type sampleMeta struct {
+	columns []string // Sample sheet columns (except the key column)
+	values  []string // Values of the matching row (empty if the input is not listed)
+}
+
+// value returns the metadata value of a column, and whether the column exists
+func (m *sampleMeta) value(column string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	for i, c := range m.columns {
+		if c == column {
+			return m.values[i], true
+		}
+	}
+	return "", false
+}
+
+// joinKey transforms a sample sheet key or an input name for matching
+func joinKey(name, joinOn string) string {
+	switch joinOn {
+	case "path":
+		return filepath.Clean(name)
+	case "basename":
+		return filepath.Base(name)
+	}
+	return name
+}
+
+// loadSampleMeta reads the sample sheet (CSV with a header row; the first column
+// identifies the input file) and returns the metadata of the current input
+func loadSampleMeta(cfg Config) (*sampleMeta, error) {
+	f, err := os.Open(cfg.SampleSheet)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening sample sheet: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading sample sheet %s: %v", cfg.SampleSheet, err)
+	}
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return nil, fmt.Errorf("Sample sheet %s must have a header row with the file column and at least one metadata column", cfg.SampleSheet)
+	}
+
+	meta := &sampleMeta{columns: rows[0][1:]}
+	for i, c := range meta.columns {
+		meta.columns[i] = strings.TrimSpace(c)
+	}
+
+	// Duplicate keys are reported even if they do not concern the current input
+	rowsByKey := make(map[string][]string, len(rows)-1)
+	for line, row := range rows[1:] {
+		key := joinKey(strings.TrimSpace(row[0]), cfg.JoinOn)
+		if _, ok := rowsByKey[key]; ok {
+			return nil, fmt.Errorf("Duplicate key %q in sample sheet %s (line %d)", key, cfg.SampleSheet, line+2)
+		}
+		rowsByKey[key] = row[1:]
+	}
+
+	name := cfg.InputFileName
+	if cfg.JoinOn == "name-label" {
+		name = fileLabel(&cfg)
+	}
+	if row, ok := rowsByKey[joinKey(name, cfg.JoinOn)]; ok {
+		meta.values = row
+		return meta, nil
+	}
+
+	switch cfg.SheetMissing {
+	case "fail":
+		return nil, fmt.Errorf("Input %s is not listed in sample sheet %s", name, cfg.SampleSheet)
+	case "skip-columns":
+		return &sampleMeta{}, nil
+	}
+	log.Printf("Warning: input %s is not listed in sample sheet %s, metadata columns are left empty", name, cfg.SampleSheet)
+	meta.values = make([]string, len(meta.columns))
+	return meta, nil
+}
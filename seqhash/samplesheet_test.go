@@ -0,0 +1,180 @@
+package seqhash
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSampleSheet creates two inputs and a sample sheet listing only the first one
+func writeSampleSheet(t *testing.T) (dir, matched, unmatched, sheet string) {
+	t.Helper()
+	dir = t.TempDir()
+	matched = filepath.Join(dir, "a.fasta")
+	unmatched = filepath.Join(dir, "b.fasta")
+	sheet = filepath.Join(dir, "samples.csv")
+	for _, f := range []string{matched, unmatched} {
+		if err := os.WriteFile(f, []byte(">seq1\nACTG\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content := "file,sample,project,condition\n" + matched + ",S1,P1,control\n"
+	if err := os.WriteFile(sheet, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, matched, unmatched, sheet
+}
+
+func TestSampleSheetPolicies(t *testing.T) {
+	_, matched, unmatched, sheet := writeSampleSheet(t)
+
+	tests := []struct {
+		policy        string
+		wantErr       bool
+		unmatchedRows []string // TSV output of the unmatched input
+	}{
+		{"warn", false, []string{"file\tsha1\tid\tcomment\tread\tbarcode\tsample\tproject\tcondition", unmatched + "\t65c89f59d38cdbf90dfaf0b0a6884829df8396b0\tseq1\t\t\t\t\t\t"}},
+		{"skip-columns", false, []string{"file\tsha1\tid\tcomment\tread\tbarcode", unmatched + "\t65c89f59d38cdbf90dfaf0b0a6884829df8396b0\tseq1\t\t\t"}},
+		{"fail", true, nil},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.policy, func(t *testing.T) {
+			args := []string{"seqhasher", "--out-format", "tsv", "--no-metadata", "--sample-sheet", sheet, "--sheet-missing", tt.policy}
+
+			// The listed input gets its metadata under every policy
+			output, err := runWithArgs(append(args, matched))
+			if err != nil {
+				t.Fatalf("run() error for the listed input = %v", err)
+			}
+			expected := "file\tsha1\tid\tcomment\tread\tbarcode\tsample\tproject\tcondition\n" +
+				matched + "\t65c89f59d38cdbf90dfaf0b0a6884829df8396b0\tseq1\t\t\t\tS1\tP1\tcontrol\n"
+			if output != expected {
+				t.Errorf("Unexpected output for the listed input:\nGot:\n%s\nWant:\n%s", output, expected)
+			}
+
+			output, err = runWithArgs(append(args, unmatched))
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "is not listed in sample sheet") {
+					t.Errorf("Expected an error for the unlisted input, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() error for the unlisted input = %v", err)
+			}
+			if expected := strings.Join(tt.unmatchedRows, "\n") + "\n"; output != expected {
+				t.Errorf("Unexpected output for the unlisted input:\nGot:\n%q\nWant:\n%q", output, expected)
+			}
+		})
+	}
+}
+
+func TestSampleSheetJoinOn(t *testing.T) {
+	dir, matched, _, _ := writeSampleSheet(t)
+	sheet := filepath.Join(dir, "by_name.csv")
+	if err := os.WriteFile(sheet, []byte("file,sample\na.fasta,S1\nlabel1,S2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		sample string
+	}{
+		{"Base name", []string{"--join-on", "basename"}, "S1"},
+		{"Name label", []string{"--join-on", "name-label", "--name", "label1"}, "S2"},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			args := append([]string{"seqhasher", "--out-format", "ndjson", "--sample-sheet", sheet, "--sheet-missing", "fail"}, tt.args...)
+			output, err := runWithArgs(append(args, matched))
+			if err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			var r jsonRecord
+			if err := json.Unmarshal([]byte(output), &r); err != nil {
+				t.Fatalf("Invalid NDJSON: %v\n%s", err, output)
+			}
+			if r.Meta["sample"] != tt.sample {
+				t.Errorf("Expected sample %s, got %v", tt.sample, r.Meta)
+			}
+		})
+	}
+}
+
+func TestSampleSheetDuplicateKeys(t *testing.T) {
+	dir, matched, _, _ := writeSampleSheet(t)
+	sheet := filepath.Join(dir, "dup.csv")
+	// Keys only clash after taking the base name
+	content := "file,sample\n" + matched + ",S1\nother/a.fasta,S2\n"
+	if err := os.WriteFile(sheet, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runWithArgs([]string{"seqhasher", "--sample-sheet", sheet, matched}); err != nil {
+		t.Errorf("Unexpected error with distinct paths: %v", err)
+	}
+	_, err := runWithArgs([]string{"seqhasher", "--sample-sheet", sheet, "--join-on", "basename", matched})
+	if err == nil || !strings.Contains(err.Error(), `Duplicate key "a.fasta"`) {
+		t.Errorf("Expected a duplicate key error, got %v", err)
+	}
+}
+
+func TestHeaderFormat(t *testing.T) {
+	meta := &sampleMeta{columns: []string{"sample"}, values: []string{"S1"}}
+	tests := []struct {
+		name     string
+		format   string
+		cfg      Config
+		expected string
+		wantErr  string
+	}{
+		{"Fields", "{id} {md5} file={file}", Config{}, ">seq1 86bfb9f78dd8b6cd35962bb7324fdbf8 file=test.fasta\nACTG\n", ""},
+		{"Metadata", "{meta:sample}|{id}", Config{SampleSheet: "sheet.csv", meta: meta}, ">S1|seq1\nACTG\n", ""},
+		{"Skipped metadata", "{meta:sample}|{id}", Config{SampleSheet: "sheet.csv", meta: &sampleMeta{}}, ">|seq1\nACTG\n", ""},
+		{"Unknown placeholder", "{id}{sha256}", Config{}, "", "unknown placeholder {sha256}"},
+		{"Unknown column", "{meta:project}", Config{SampleSheet: "sheet.csv", meta: meta}, "", "unknown placeholder {meta:project}"},
+		{"Unterminated placeholder", "{id", Config{}, "", "unterminated placeholder"},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"md5"}
+			cfg.InputFileName = "test.fasta"
+			cfg.HeaderFormat = tt.format
+
+			output := &bytes.Buffer{}
+			err := processSequences(strings.NewReader(">seq1\nACTG\n"), output, cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Got %q, want %q", output.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestCSVOutput(t *testing.T) {
+	cfg := Config{HashTypes: []string{"sha1"}, OutFormat: "csv", NoFileName: true, NoMetadata: true, InputFileName: "test.fasta"}
+	output := &bytes.Buffer{}
+	if err := processSequences(strings.NewReader(">seq1 a, \"quoted\" description\nACTG\n"), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	expected := "sha1,id,comment,read,barcode\n65c89f59d38cdbf90dfaf0b0a6884829df8396b0,seq1,\"a, \"\"quoted\"\" description\",,\n"
+	if output.String() != expected {
+		t.Errorf("Got %q, want %q", output.String(), expected)
+	}
+}
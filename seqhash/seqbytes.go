@@ -0,0 +1,149 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+	"os"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// Policies for the bytes allowed in sequences (--seq-bytes)
+var supportedSeqBytes = []string{"iupac", "ascii", "any"}
+
+// What to do with records that fail validation (--on-error)
+var supportedOnError = []string{"fail", "skip"}
+
+// IUPAC nucleotide codes and gap symbols allowed by --seq-bytes iupac
+const iupacSymbols = "ACGTUNRYKMSWBDHV-.*"
+
+var iupacAllowed = func() (allowed [256]bool) {
+	for _, c := range iupacSymbols {
+		allowed[c] = true
+		allowed[unicode.ToLower(c)] = true
+	}
+	return allowed
+}()
+
+// Sequence byte that is not allowed by the --seq-bytes policy
+type seqByteError struct {
+	id       string
+	position int // 1-based, in the sequence without whitespace
+	seq      []byte
+	policy   string
+}
+
+func (e *seqByteError) Error() string {
+	c := e.seq[e.position-1]
+	character := fmt.Sprintf("0x%02X", c)
+	if c >= 0x80 {
+		// Show the whole UTF-8 character (e.g., a smart quote pasted into the sequence)
+		start := e.position - 1
+		for start > 0 && !utf8.RuneStart(e.seq[start]) && e.position-1-start < utf8.UTFMax {
+			start--
+		}
+		if r, size := utf8.DecodeRune(e.seq[start:]); r != utf8.RuneError {
+			character = fmt.Sprintf("% X (%U %q)", e.seq[start:start+size], r, r)
+		}
+	} else if unicode.IsPrint(rune(c)) {
+		character += fmt.Sprintf(" (%q)", c)
+	}
+	return fmt.Sprintf("Invalid byte in the sequence of record %q at position %d: %s is not allowed by --seq-bytes %s",
+		e.id, e.position, character, e.policy)
+}
+
+// validateSeqBytes checks the normalized sequence against the policy ("" means any)
+func validateSeqBytes(seq []byte, policy string) (position int, ok bool) {
+	for i, c := range seq {
+		switch policy {
+		case "iupac":
+			if !iupacAllowed[c] {
+				return i + 1, false
+			}
+		case "ascii":
+			if c < 0x21 || c > 0x7E {
+				return i + 1, false
+			}
+		}
+	}
+	return 0, true
+}
+
+// countUnicodeSpaces counts the non-ASCII whitespace characters (e.g., U+00A0, no-break space)
+// that whitespace stripping removes from the sequence, and returns the first one
+func countUnicodeSpaces(seq []byte) (n int, first rune) {
+	for i := 0; i < len(seq); {
+		if seq[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(seq[i:])
+		if unicode.IsSpace(r) {
+			if n == 0 {
+				first = r
+			}
+			n++
+		}
+		i += size
+	}
+	return n, first
+}
+
+// rejectsWriter stores the records skipped with --on-error skip, in their input format
+type rejectsWriter struct {
+	f     *os.File
+	count int64
+}
+
+func newRejectsWriter(fileName string) (*rejectsWriter, error) {
+	if fileName == "" {
+		return &rejectsWriter{}, nil
+	}
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening rejects file: %v", err)
+	}
+	return &rejectsWriter{f: f}, nil
+}
+
+func (rw *rejectsWriter) write(record *fastx.Record) error {
+	rw.count++
+	if rw.f == nil {
+		return nil
+	}
+	if _, err := rw.f.Write(record.Format(0)); err != nil {
+		return fmt.Errorf("Error writing rejects file: %v", err)
+	}
+	return nil
+}
+
+// Close closes the rejects file; it can be called again (e.g., deferred after an explicit Close)
+func (rw *rejectsWriter) Close() error {
+	if rw.f == nil {
+		return nil
+	}
+	err := rw.f.Close()
+	rw.f = nil
+	return err
+}
+
+// unicodeName describes a whitespace character for messages
+func unicodeName(r rune) string {
+	switch r {
+	case '\u00A0':
+		return "U+00A0 (no-break space)"
+	case '\u0085':
+		return "U+0085 (next line)"
+	case '\u2007', '\u202F':
+		return fmt.Sprintf("%U (narrow or figure space)", r)
+	case '\u3000':
+		return "U+3000 (ideographic space)"
+	}
+	return fmt.Sprintf("%U", r)
+}
@@ -0,0 +1,139 @@
+package seqhash
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shenwei356/bio/seq"
+)
+
+func TestSeqBytesPolicies(t *testing.T) {
+	// Disable sequence validation, as run() does
+	seq.ValidateSeq = false
+
+	const (
+		nbsp       = ">nbsp\nAC TG\n"
+		smartQuote = ">quote\nAC“TG\n"
+	)
+	digest := getHashFunc("sha1")([]byte("ACTG"))
+
+	tests := []struct {
+		name    string
+		input   string
+		policy  string
+		digest  string // Expected digest (when accepted)
+		wantErr string
+	}{
+		// The no-break space is removed with the other whitespace, so the record passes every policy
+		{"NBSP, iupac", nbsp, "iupac", digest, ""},
+		{"NBSP, ascii", nbsp, "ascii", digest, ""},
+		{"NBSP, any", nbsp, "any", digest, ""},
+		{"Smart quote, iupac", smartQuote, "iupac", "", `record "quote" at position 3: E2 80 9C (U+201C '“') is not allowed by --seq-bytes iupac`},
+		{"Smart quote, ascii", smartQuote, "ascii", "", `record "quote" at position 3: E2 80 9C (U+201C '“') is not allowed by --seq-bytes ascii`},
+		{"Smart quote, any", smartQuote, "any", getHashFunc("sha1")([]byte("AC“TG")), ""},
+		{"Non-IUPAC letter, ascii", ">j\nACJT\n", "ascii", getHashFunc("sha1")([]byte("ACJT")), ""},
+		{"Non-IUPAC letter, iupac", ">j\nACJT\n", "iupac", "", `record "j" at position 3: 0x4A ('J') is not allowed by --seq-bytes iupac`},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, HeadersOnly: true, SeqBytes: tt.policy, OnError: "fail"}
+			output := &bytes.Buffer{}
+			err := processSequences(strings.NewReader(tt.input), output, cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("processSequences() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if got, _, _ := strings.Cut(output.String(), ";"); got != tt.digest {
+				t.Errorf("Got digest %q, want %q", got, tt.digest)
+			}
+		})
+	}
+}
+
+func TestSeqBytesSkip(t *testing.T) {
+	// Disable sequence validation, as run() does
+	seq.ValidateSeq = false
+
+	rejectsPath := filepath.Join(t.TempDir(), "rejects.fasta")
+	input := ">good\nACTG\n>quote\nAC“TG\n>nbsp\nAC TG\n>bad\nAC?G\n"
+
+	for _, policy := range []string{"iupac", "ascii", "any"} {
+		runTest(t, policy, func(t *testing.T) {
+			cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, HeadersOnly: true,
+				SeqBytes: policy, OnError: "skip", RejectsFileName: rejectsPath}
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			rejects, err := os.ReadFile(rejectsPath)
+			if err != nil {
+				t.Fatalf("Failed to read rejects file: %v", err)
+			}
+
+			// Rejected records are written unchanged, and only to the rejects file
+			var wantRejects string
+			kept := 4
+			switch policy {
+			case "iupac":
+				wantRejects = ">quote\nAC“TG\n>bad\nAC?G\n"
+				kept = 2
+			case "ascii":
+				wantRejects = ">quote\nAC“TG\n"
+				kept = 3
+			}
+			if string(rejects) != wantRejects {
+				t.Errorf("Got rejects:\n%q\nWant:\n%q", rejects, wantRejects)
+			}
+			if headers := strings.Count(output.String(), "\n"); headers != kept {
+				t.Errorf("Got %d output records, want %d", headers, kept)
+			}
+		})
+	}
+}
+
+func TestRejectsWriterClose(t *testing.T) {
+	rw, err := newRejectsWriter(filepath.Join(t.TempDir(), "rejects.fasta"))
+	if err != nil {
+		t.Fatalf("newRejectsWriter() error = %v", err)
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	// The deferred Close after an explicit one must not close the file again
+	if err := rw.Close(); err != nil {
+		t.Errorf("Second Close() error = %v, want nil", err)
+	}
+}
+
+func TestVerboseUnicodeSpaces(t *testing.T) {
+	// Disable sequence validation, as run() does
+	seq.ValidateSeq = false
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	input := ">nbsp\nAC T G\n>plain\nACTG\n"
+	for _, verbose := range []bool{false, true} {
+		logs.Reset()
+		cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, HeadersOnly: true, SeqBytes: "iupac", Verbose: verbose}
+		if err := processSequences(strings.NewReader(input), &bytes.Buffer{}, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		reported := strings.Contains(logs.String(), `Record "nbsp": removed 2 non-ASCII whitespace character(s) from the sequence, e.g., U+00A0 (no-break space)`) &&
+			strings.Contains(logs.String(), "Removed 2 non-ASCII whitespace character(s) from the sequences of 1 record(s)")
+		if reported != verbose {
+			t.Errorf("verbose=%v: got log output:\n%s", verbose, logs.String())
+		}
+	}
+}
@@ -0,0 +1,1825 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-faster/city"
+	"github.com/spaolacci/murmur3"
+	"github.com/zeebo/blake3"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/will-rowe/nthash"
+)
+
+const (
+	version             = "1.1.1" // Version of the program
+	defaultHashType     = "sha1"  // Default hash type
+	defaultIDHashLength = 8       // Number of hash characters in synthesized IDs
+)
+
+var supportedHashTypes = []string{"sha1", "sha3", "md5", "xxhash", "cityhash", "murmur3", "nthash", "blake3"}
+
+// Config holds the options of a run; the fields are set by the command-line flags of similar names
+// (e.g., HashTypes by --hash). ParseArgs returns a Config with the defaults of the command line.
+type Config struct {
+	HeadersOnly          bool
+	HashTypes            []string
+	NoFileName           bool
+	CaseSensitive        bool
+	InputFileName        string
+	OutputFileName       string
+	NameOverride         string
+	StdinName            string
+	ShowVersion          bool
+	AuditLog             string
+	Strict               bool
+	ExplainOutput        bool
+	StdinCommands        bool
+	VerifyInput          string
+	VerifyChecksum       bool
+	Compare              bool
+	OutputDir            string
+	Inputs               []string // Multi-input runs (--output-dir)
+	FailFast             bool
+	RunReport            string
+	OutFormat            string
+	HeaderFormat         string
+	SeqkitCompat         bool
+	DropComment          bool
+	IlluminaID           bool
+	DualHash             bool
+	DupReferences        bool
+	AnonymizeLabels      bool
+	HashKey              string
+	LabelMapOut          string
+	SampleSheet          string
+	JoinOn               string
+	SheetMissing         string
+	meta                 *sampleMeta // Sample sheet metadata of the input (loaded before processing)
+	JsonSummary          bool
+	RecordCountHeader    bool
+	ClusteredHash        bool
+	ClusterK             int
+	AutoHash             bool
+	AutoHashThreshold    int
+	EncodeSequence       string
+	KeepPartial          bool
+	Preflight            bool
+	Dedup                bool
+	ClustersFile         string
+	TopFile              string
+	TopN                 int
+	WithSequences        bool
+	SeqLimit             int
+	TrimNs               bool
+	EmitTrimmed          bool
+	CollapseHomopolymers bool
+	EmitCollapsed        bool
+	BothStrands          bool
+	LengthPrefix         bool
+	NoMetadata           bool
+	RecordDelimiter      string
+	options              map[string]string // Flags given on the command line (secrets redacted)
+	TreeChunk            int
+	TreeChunksFile       string
+	Ends                 int
+	Window               int
+	Step                 int
+	MinLen               int
+	IncludeIDFile        string
+	includeIDs           map[string]struct{} // Loaded from includeIDFile
+	PassthroughExcluded  bool
+	FanoutThreshold      int
+	FanoutWorkers        int
+	Threads              int
+	SeqBytes             string
+	OnError              string
+	RejectsFileName      string
+	Verbose              bool
+	MinimalUniquePrefix  bool
+	TmpDir               string
+	BigRecordThreshold   int
+	RecordTimeout        time.Duration
+	MaxSeqLength         int64
+	NWildcardDedup       bool
+	DedupReport          string
+	DedupOutput          string
+	DedupStats           bool
+	SizeIn               bool
+	SizeOut              bool
+	ReverseOutput        bool
+	LengthBin            int
+	StratifiedSample     string
+	strata               []stratum         // Parsed --stratified-sample
+	watchdog             *watchdogReader   // Set when the input is already watched (--record-timeout)
+	effective            map[string]string // Values of all flags, including defaults (secrets redacted)
+	args                 []string          // Options and arguments the run was parsed from
+	StripAnnotations     bool
+	DebugPositions       string
+	SpotCheck            int
+	ValidateRoundtrip    bool
+	KeepComments         bool
+	SizeRegexp           string
+	GroupBy              string
+	GroupReport          string
+	GroupUnique          bool
+	MaxGroups            int
+	MaxMemory            int64
+	Compress             string
+	PipeTo               string
+	XZLevel              int
+	IndexFileName        string
+	SynthesizeIDs        bool
+	IDIsHash             bool
+	CollisionWarn        bool
+	CollisionThreshold   float64
+	IDHashLength         int
+}
+
+// Set when the process is interrupted (SIGINT or SIGTERM),
+// so that processing stops gracefully after the current record
+var interrupted atomic.Bool
+
+var errInterrupted = errors.New("Interrupted")
+
+// Stats holds the summary counts of a processing run
+type Stats struct {
+	Records       int64 // Hashed records
+	Bases         int64
+	Excluded      int64            // Records excluded by the filters (--include-id, --min-len)
+	PassedThrough int64            // Excluded records written unchanged (--passthrough-excluded)
+	groups        []groupSummary   // With --group-by
+	strata        []stratumSummary // With --stratified-sample
+	Digests       int64            // Hashed records or windows (--window)
+}
+
+// Run runs the command line of the program (os.Args), writing the output to w
+// unless an output file is given. Errors that end the Run with a specific exit status
+// are reported by ExitStatus.
+func Run(w io.Writer) (err error) {
+
+	// Disable sequence validation
+	seq.ValidateSeq = false
+
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "inspect":
+			return runInspect(w, os.Args[2:])
+		case "db":
+			return runDB(w, os.Args[2:])
+		case "doctor":
+			// Takes the same options as a regular run
+			os.Args = append(os.Args[:1:1], os.Args[2:]...)
+			cfg, err := parseFlags()
+			if err != nil {
+				return err
+			}
+			return runDoctor(w, cfg)
+		}
+	}
+
+	cfg, err := parseFlags()
+	if err != nil {
+		return err
+	}
+
+	if cfg.ShowVersion {
+		fmt.Fprintf(w, "SeqHasher %s\n", version)
+		return nil
+	}
+
+	if cfg.ExplainOutput {
+		if cfg.SampleSheet != "" {
+			if cfg.meta, err = loadSampleMeta(cfg); err != nil {
+				return err
+			}
+		}
+		return explainOutput(w, cfg)
+	}
+
+	if cfg.StdinCommands {
+		return runCommands(os.Stdin, w)
+	}
+
+	if cfg.InputFileName == "" {
+		printUsage(w)
+		return nil
+	}
+
+	if cfg.Compare {
+		return compareFiles(w, cfg.InputFileName, cfg.OutputFileName, cfg)
+	}
+
+	if cfg.OutputDir != "" {
+		return runBatch(cfg)
+	}
+
+	_, err = processFile(w, cfg)
+	return err
+}
+
+// processFile hashes the records of the input file (cfg.InputFileName)
+// into the output file, or into w if there is no output file name
+func processFile(w io.Writer, cfg Config) (stats Stats, err error) {
+	if cfg.Preflight {
+		if err := runDoctor(os.Stderr, cfg); err != nil {
+			return stats, err
+		}
+	}
+	if cfg.VerifyChecksum {
+		if err := verifyInputChecksum(cfg); err != nil {
+			return stats, err
+		}
+	}
+
+	// Audit record is written after the output is closed (deferred first, runs last)
+	var audit *auditRecord
+	if cfg.AuditLog != "" {
+		audit = newAuditRecord(cfg)
+		// Stdout is hashed as written, after compression (or the --pipe-to command)
+		w = io.MultiWriter(w, audit.Output.hash)
+		defer func() {
+			if aerr := audit.write(cfg.AuditLog, stats, err); aerr != nil {
+				if cfg.Strict {
+					if err == nil {
+						err = fmt.Errorf("Error writing audit log: %v", aerr)
+					}
+				} else {
+					log.Printf("Warning: failed to write audit log: %v", aerr)
+				}
+			}
+		}()
+	}
+
+	var inputTap io.Writer // Raw bytes of stdin, for the audit log
+	if audit != nil {
+		inputTap = audit.Input.hash
+	}
+	input, watchdog, err := openInput(cfg.InputFileName, cfg.RecordTimeout, inputTap)
+	if err != nil {
+		return stats, fmt.Errorf("Error opening input: %v", err)
+	}
+	defer input.Close()
+	cfg.watchdog = watchdog // The input is already watched, from its first byte
+
+	output := w
+	compression := outputCompression(cfg)
+	if cfg.OutputFileName != "" && cfg.OutputFileName != "-" {
+		outputFile, oerr := getCompressedOutput(cfg.OutputFileName, compression, cfg.XZLevel)
+		if oerr != nil {
+			return stats, fmt.Errorf("Error opening output: %v", oerr)
+		}
+		defer func() {
+			// Closing finalizes compressed streams, so its errors matter
+			if cerr := outputFile.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("Error closing output: %v", cerr)
+			}
+			// Remove incomplete output unless asked to keep it
+			if err != nil && !cfg.KeepPartial {
+				os.Remove(cfg.OutputFileName)
+			}
+		}()
+		output = outputFile
+	} else if compression != "none" {
+		compressor, cerr := newCompressor(w, compression, cfg.XZLevel)
+		if cerr != nil {
+			return stats, fmt.Errorf("Error opening output: %v", cerr)
+		}
+		defer func() {
+			if cerr := compressor.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("Error closing output: %v", cerr)
+			}
+		}()
+		output = compressor
+	}
+
+	// Deferred after the output, so the command finishes before the output is closed
+	if cfg.PipeTo != "" {
+		piped, perr := startPipe(cfg.PipeTo, output)
+		if perr != nil {
+			return stats, perr
+		}
+		defer func() {
+			if perr := piped.Close(); perr != nil && err == nil {
+				err = perr
+			}
+		}()
+		output = piped
+	}
+
+	stopSignals := handleInterrupts()
+	defer stopSignals()
+
+	return processRecords(input, output, cfg)
+}
+
+// handleInterrupts starts catching SIGINT and SIGTERM and returns a function to stop it.
+// After the first signal, default handling is restored, so a second one kills the process.
+func handleInterrupts() (stop func()) {
+	interrupted.Store(false)
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			interrupted.Store(true)
+			signal.Stop(signals)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+func parseFlags() (Config, error) {
+	flag.Usage = func() {
+		printUsage(os.Stderr)
+	}
+	return parseArgs(flag.CommandLine, os.Args[1:])
+}
+
+// ParseArgs parses the options and arguments of a run (without the program name) into a Config.
+// With no arguments, it returns the defaults of the command line.
+func ParseArgs(args []string) (Config, error) {
+	fs := flag.NewFlagSet("seqhasher", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return parseArgs(fs, args)
+}
+
+// parseArgs parses the options and arguments of a run, and validates them with prepareConfig
+func parseArgs(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := Config{}
+
+	fs.BoolVar(&cfg.HeadersOnly, "headersonly", false, "Output only headers")
+	fs.BoolVar(&cfg.HeadersOnly, "o", false, "Output only headers (shorthand)")
+
+	var hashTypesString string
+	fs.StringVar(&hashTypesString, "hash", defaultHashType, "Hash type(s) (comma-separated: sha1, sha3, md5, xxhash, cityhash, murmur3, nthash, blake3)")
+	fs.StringVar(&hashTypesString, "H", defaultHashType, "Hash type(s) (shorthand)")
+	fs.BoolVar(&cfg.AutoHash, "auto-hash", false, "Choose the hash type per record: "+autoHashShort+" for sequences up to --auto-hash-threshold bases, "+autoHashLong+" for longer ones (written as ';hash-type=<type>')")
+	fs.IntVar(&cfg.AutoHashThreshold, "auto-hash-threshold", defaultAutoHashThreshold, "Longest sequence (in bases) hashed with "+autoHashShort+" by --auto-hash")
+
+	fs.BoolVar(&cfg.NoFileName, "nofilename", false, "Do not include file name in output")
+	fs.BoolVar(&cfg.NoFileName, "n", false, "Do not include file name in output (shorthand)")
+
+	fs.BoolVar(&cfg.CaseSensitive, "casesensitive", false, "Case-sensitive hashing")
+	fs.BoolVar(&cfg.CaseSensitive, "c", false, "Case-sensitive hashing (shorthand)")
+
+	fs.StringVar(&cfg.NameOverride, "name", "", "Override input file name in output")
+	fs.StringVar(&cfg.NameOverride, "f", "", "Override input file name in output (shorthand)")
+	fs.StringVar(&cfg.StdinName, "stdin-name", "", "Label used in place of the file name when reading from stdin")
+
+	fs.BoolVar(&cfg.ShowVersion, "version", false, "Show version information")
+	fs.BoolVar(&cfg.ShowVersion, "v", false, "Show version information (shorthand)")
+
+	fs.BoolVar(&cfg.SynthesizeIDs, "synthesize-ids", false, "Replace all sequence IDs with hash-derived ones (seq_<hash prefix>)")
+	fs.BoolVar(&cfg.IDIsHash, "id-is-hash", false, "Use the first hash as the whole header (>hash), dropping the file name and the original header")
+	fs.StringVar(&cfg.SeqBytes, "seq-bytes", "iupac", "Bytes allowed in sequences after whitespace removal ("+strings.Join(supportedSeqBytes, ", ")+")")
+	fs.StringVar(&cfg.OnError, "on-error", "fail", "What to do with records rejected by --seq-bytes ("+strings.Join(supportedOnError, ", ")+")")
+	fs.StringVar(&cfg.RejectsFileName, "rejects", "", "Write the records skipped with --on-error skip to this file")
+	fs.BoolVar(&cfg.Verbose, "verbose", false, "Report details of the processing (e.g., non-ASCII whitespace removed from sequences)")
+	fs.BoolVar(&cfg.MinimalUniquePrefix, "minimal-unique-prefix", false, "Shorten the first hash to the shortest prefix that is unique within the input (reads the input twice)")
+	fs.StringVar(&cfg.TmpDir, "tmp-dir", "", "Directory for temporary files (default: $TMPDIR or /tmp)")
+	fs.IntVar(&cfg.IDHashLength, "id-hash-length", defaultIDHashLength, "Number of hash characters used in synthesized IDs")
+	fs.BoolVar(&cfg.CollisionWarn, "warn-on-short-hash-collision-risk", false, "After processing, warn if the estimated probability of a hash collision exceeds --collision-warn-threshold")
+	fs.Float64Var(&cfg.CollisionThreshold, "collision-warn-threshold", defaultCollisionThreshold, "Collision probability above which --warn-on-short-hash-collision-risk warns")
+
+	fs.StringVar(&cfg.IndexFileName, "index", "", "Write an index with the byte offset and length of each output record")
+
+	fs.StringVar(&cfg.OutFormat, "out-format", "fasta", "Output format ("+strings.Join(supportedOutFormats, ", ")+")")
+	fs.StringVar(&cfg.RecordDelimiter, "record-delimiter", "", "Text written between consecutive FASTA/FASTQ records (escapes are decoded, e.g., '\\n' for a blank line)")
+	fs.BoolVar(&cfg.NoMetadata, "no-metadata", false, "Omit the leading '#' lines with the version, options, and column descriptions from TSV and CSV output")
+	fs.BoolVar(&cfg.ReverseOutput, "reverse-output", false, "Write the records in reverse input order (all records are kept in memory until the end of the input)")
+	fs.IntVar(&cfg.LengthBin, "group-by-length", 0, "Write the records in sections of sequence length bins of this size, each preceded by a '; length-bin: X-Y' comment line (0 disables)")
+	fs.StringVar(&cfg.HeaderFormat, "header-format", "", "Template of the output header (placeholders: {file}, {id}, {<hash type>}, {comment}, {read}, {barcode}, {meta:<column>})")
+	fs.BoolVar(&cfg.AnonymizeLabels, "anonymize-labels", false, "Replace the file name (or --name) in all outputs with a keyed pseudonym (requires --hash-key)")
+	fs.StringVar(&cfg.HashKey, "hash-key", "", "Secret key for --anonymize-labels")
+	fs.StringVar(&cfg.LabelMapOut, "label-map-out", "", "Append the true label and its pseudonym to a TSV file (with --anonymize-labels)")
+	fs.BoolVar(&cfg.DropComment, "drop-comment", false, "Remove the comment (text after the ID, e.g., '1:N:0:ACGT') from the output header")
+	fs.BoolVar(&cfg.DualHash, "dual-hash", false, "Also hash the complete original header, and label the hashes of the sequence and of the header ('seqhash=<digest>;hdrhash=<digest>')")
+	fs.BoolVar(&cfg.IlluminaID, "illumina-id", false, "Normalize Illumina read names, so that paired reads share the ID: remove the /1 or /2 suffix and the comment")
+	fs.BoolVar(&cfg.SeqkitCompat, "seqkit-compat", false, "Keep the original ID first and append hashes as ';key=value' annotations (seqkit-compatible)")
+	fs.StringVar(&cfg.SampleSheet, "sample-sheet", "", "CSV file with per-input metadata (first column identifies the input file)")
+	fs.StringVar(&cfg.JoinOn, "join-on", "path", "How inputs are matched to the sample sheet ("+strings.Join(supportedJoinKeys, ", ")+")")
+	fs.StringVar(&cfg.SheetMissing, "sheet-missing", "warn", "What to do if the input is not in the sample sheet ("+strings.Join(supportedSheetMissing, ", ")+")")
+	fs.BoolVar(&cfg.JsonSummary, "json-with-summary", false, "Wrap JSON output into an object with a trailing summary")
+	fs.BoolVar(&cfg.RecordCountHeader, "output-record-count-header", false, "Write the number of records before them: {\"record_count\": N, \"records\": [...]} (JSON array output only; other formats are streamed)")
+	fs.StringVar(&cfg.EncodeSequence, "encode-sequence", "none", "Encoding of the sequence in JSON output ("+strings.Join(supportedSequenceEncodings, ", ")+")")
+	fs.BoolVar(&cfg.KeepPartial, "keep-partial", false, "Keep the output file if processing fails")
+	fs.BoolVar(&cfg.Preflight, "preflight", false, "Check input, output, and resources before processing (see 'seqhasher doctor')")
+
+	fs.StringVar(&cfg.Compress, "compress", "", "Output compression ("+strings.Join(supportedCompressions(), ", ")+"; default: by output file extension)")
+	fs.StringVar(&cfg.PipeTo, "pipe-to", "", "Pipe the output through a shell command (e.g., 'gzip -9'), whose output goes to the output file")
+
+	var xzOutput bool
+	fs.BoolVar(&xzOutput, "xz-output", false, "Compress output with xz (same as --compress xz)")
+	var bzip2Output bool
+	fs.BoolVar(&bzip2Output, "bzip2-output", false, "Compress output with bzip2 (same as --compress bzip2)")
+	fs.IntVar(&cfg.XZLevel, "xz-level", defaultXZLevel, "Compression level for xz output (0-9)")
+
+	fs.StringVar(&cfg.ClustersFile, "clusters", "", "Write a TSV with the groups of identical sequences (digest, size, representative ID)")
+	fs.StringVar(&cfg.TopFile, "top", "", "Write a TSV with the most abundant sequences (see --top-n)")
+	fs.IntVar(&cfg.TopN, "top-n", defaultTopN, "Number of sequences in the --top report")
+	fs.BoolVar(&cfg.WithSequences, "with-sequences", false, "Add the length and the normalized sequence of the representative to --clusters and --top reports")
+	fs.IntVar(&cfg.SeqLimit, "seq-limit", 0, "Truncate sequences in reports to this length, marked with '"+truncationMarker+"' (0 means no limit)")
+	fs.BoolVar(&cfg.TrimNs, "trim-ns", false, "Remove leading and trailing runs of N before hashing")
+	fs.BoolVar(&cfg.CollapseHomopolymers, "collapse-homopolymers", false, "Collapse runs of identical bases (e.g., 'AAAC' to 'AC') before hashing")
+	fs.IntVar(&cfg.MinLen, "min-len", 0, "Hash only records with sequences of at least this length (0 for all)")
+	fs.StringVar(&cfg.IncludeIDFile, "include-id", "", "Hash only the records whose IDs are listed in this file (one per line)")
+	fs.BoolVar(&cfg.PassthroughExcluded, "passthrough-excluded", false, "Write the records excluded by --min-len or --include-id unchanged, instead of dropping them")
+	fs.IntVar(&cfg.Window, "window", 0, "Hash windows of this many bases as separate records (0 hashes whole sequences)")
+	fs.IntVar(&cfg.Step, "step", 0, "Distance between the starts of --window windows (default: the window size, i.e., tiling)")
+	fs.IntVar(&cfg.Ends, "ends", 0, "Hash only the first and the last N bases of each sequence, concatenated (0 hashes whole sequences)")
+	fs.BoolVar(&cfg.BothStrands, "both-strands", false, "Hash both strands (the sequence and its reverse complement, in lexicographic order, joined with '|'), so that either orientation gets the same hash")
+	fs.BoolVar(&cfg.LengthPrefix, "length-prefix", false, "Prepend the length of the hashed data (8 bytes, big-endian) before hashing, so that concatenations of different sequences can't give the same hashed data")
+	fs.IntVar(&cfg.TreeChunk, "tree-hash", 0, "Hash chunks of N bytes of each sequence, and use the digest of the chunk digests as the hash (0 hashes whole sequences)")
+	fs.StringVar(&cfg.TreeChunksFile, "tree-hash-chunks", "", "Write the byte range and digest of each --tree-hash chunk to a TSV file")
+	fs.BoolVar(&cfg.EmitTrimmed, "emit-trimmed", false, "Output the sequences trimmed with --trim-ns (by default, sequences are output untrimmed)")
+	fs.BoolVar(&cfg.EmitCollapsed, "emit-collapsed", false, "Output the sequences collapsed with --collapse-homopolymers (by default, sequences are output as-is)")
+	fs.BoolVar(&cfg.Dedup, "dedup", false, "Output only the first record of each unique sequence")
+	fs.BoolVar(&cfg.NWildcardDedup, "n-wildcard-dedup", false, "Deduplicate, treating all ambiguity codes (N, R, Y, ...) as the same symbol")
+	fs.BoolVar(&cfg.DedupStats, "dedup-stats", false, "Report how many records were deduplicated by packed sequence and by digest")
+	fs.BoolVar(&cfg.ClusteredHash, "clustered-hash", false, "Annotate records with a coarse bucket of near-identical sequences (';cluster=<bucket>'), by banded MinHash of their k-mers")
+	fs.IntVar(&cfg.ClusterK, "cluster-kmer", defaultClusterK, "Length of the k-mers of --clustered-hash")
+	fs.BoolVar(&cfg.DupReferences, "output-no-sequence-for-duplicates", false, "Write later records of a sequence as header lines only, referencing the first record (';dup-of=<ID>')")
+	fs.StringVar(&cfg.DedupOutput, "dedup-output", "", "Also write the first record of each unique sequence to this file, while the main output keeps all records")
+	fs.StringVar(&cfg.DedupReport, "dedup-report", "", "Write the number of records and unique sequences, and the distribution of duplicates, to a TSV file")
+	fs.BoolVar(&cfg.SizeIn, "sizein", false, "Take abundance annotations (e.g., ';size=N') into account in --clusters and --top reports")
+	fs.BoolVar(&cfg.StripAnnotations, "strip-annotations", false, "Drop the trailing ';key=value' annotations (e.g., ';size=12;sample=A') of the input headers")
+	fs.BoolVar(&cfg.SizeOut, "sizeout", false, "With --dedup, annotate the unique records with their total abundance (';size=N'), keeping the first-seen order")
+	fs.StringVar(&cfg.StratifiedSample, "stratified-sample", "", "With --dedup, keep a digest-based fraction of the unique sequences of each abundance stratum (e.g., 'size>=100:1,size>=10:0.1,rest:0.01')")
+	fs.StringVar(&cfg.GroupBy, "group-by", "", "Regular expression whose first capture group, applied to the header, defines the group of the record")
+	fs.StringVar(&cfg.GroupReport, "group-report", "", "Write per-group record counts and bases (--group-by) as TSV")
+	fs.BoolVar(&cfg.GroupUnique, "group-unique", false, "Count unique digests per group (estimated past --max-memory)")
+	fs.IntVar(&cfg.MaxGroups, "max-groups", defaultMaxGroups, "Maximum number of --group-by groups")
+	var maxMemoryString string
+	fs.StringVar(&maxMemoryString, "max-memory", "", "Memory for exact unique-digest counts (e.g., 512M, 2G; default, unlimited)")
+	fs.StringVar(&cfg.SizeRegexp, "size-regexp", "", "Regular expression with a capture group extracting the abundance from the header (implies --sizein; default: '"+defaultSizePattern+"')")
+
+	fs.IntVar(&cfg.Threads, "threads", 1, "Number of goroutines hashing records concurrently (output order is preserved)")
+	fs.DurationVar(&cfg.RecordTimeout, "record-timeout", 0, "Fail if no new record arrives within this time (e.g., 30s; 0 waits forever)")
+	var maxSeqLengthString string
+	fs.StringVar(&maxSeqLengthString, "max-seq-length", "", "Fail as soon as the sequence of a record exceeds this size (e.g., 100M; default, unlimited)")
+	fs.IntVar(&cfg.BigRecordThreshold, "big-record-threshold", defaultBigRecordThreshold, "With --threads, hash records of at least this length in a separate lane (0 disables)")
+	fs.IntVar(&cfg.FanoutThreshold, "fanout-threshold", defaultFanoutThreshold, "Compute multiple hashes concurrently for sequences of at least this length (0 disables)")
+	fs.IntVar(&cfg.FanoutWorkers, "fanout-workers", 0, "Goroutines computing hashes concurrently (default: number of hash types minus one, limited by CPUs)")
+
+	fs.BoolVar(&cfg.Compare, "compare", false, "Compare the sequence sets of two files (given instead of input and output)")
+
+	fs.StringVar(&cfg.OutputDir, "output-dir", "", "Process all given files (arguments are inputs only) and write the outputs into this directory")
+	fs.BoolVar(&cfg.FailFast, "fail-fast", false, "With --output-dir, stop at the first input that fails")
+	fs.StringVar(&cfg.RunReport, "run-report", "", "With --output-dir, write the outcome of each input as JSON")
+
+	fs.StringVar(&cfg.VerifyInput, "verify-input", "", "Verify the input file against an MD5 or SHA-256 checksum file (md5sum/sha256sum format) before processing")
+	fs.BoolVar(&cfg.VerifyChecksum, "verify-input-checksum", false, "Verify the input file against its checksum sidecar (<input>.sha256 or <input>.md5) before processing")
+
+	fs.IntVar(&cfg.SpotCheck, "spot-check", 0, "Verify every N-th written record: re-parse it and recompute its digests from the written sequence (0 disables)")
+	fs.BoolVar(&cfg.KeepComments, "keep-comments", false, "Pass the ';'-prefixed comment lines of FASTA input through to the output, along with their records")
+	fs.BoolVar(&cfg.ValidateRoundtrip, "validate-roundtrip", false, "Verify that the written sequence and qualities of every record equal the input ones, up to the documented normalization")
+	fs.StringVar(&cfg.DebugPositions, "debug-positions", "", "Write the number, header line, and byte offset of each input record to a TSV file (for locating malformed records)")
+
+	fs.BoolVar(&cfg.ExplainOutput, "explain-output", false, "Describe the output fields for the given options and exit")
+	fs.BoolVar(&cfg.StdinCommands, "stdin-commands", false, "Run as a coprocess: read JSON commands from stdin and write a JSON result line for each to stdout")
+
+	fs.StringVar(&cfg.AuditLog, "audit-log", "", "Append a JSON record of the run to the file (default: $"+auditLogEnv+")")
+	fs.BoolVar(&cfg.Strict, "strict", false, "Treat audit log write failures as errors")
+	var strictFlags bool
+	fs.BoolVar(&strictFlags, "strict-flags", false, "Fail on any unknown option, also after the input and output files, suggesting the closest valid one")
+
+	if err := checkFlags(fs, args, strictFlagsRequested(args)); err != nil {
+		return Config{}, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg.InputFileName = fs.Arg(0)
+	cfg.OutputFileName = fs.Arg(1)
+	if cfg.OutputDir != "" {
+		if err := batchInputs(&cfg, fs.Args()); err != nil {
+			return Config{}, err
+		}
+	} else if cfg.FailFast || cfg.RunReport != "" {
+		return Config{}, fmt.Errorf("--fail-fast and --run-report require --output-dir")
+	}
+	if cfg.StdinCommands && fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("--stdin-commands takes no input or output files (they are given in the commands)")
+	}
+	if cfg.VerifyInput != "" {
+		cfg.VerifyChecksum = true
+	}
+	if cfg.VerifyChecksum && cfg.InputFileName == "-" {
+		return Config{}, fmt.Errorf("--verify-input and --verify-input-checksum require an input file (stdin can't be verified before processing)")
+	}
+
+	if cfg.AuditLog == "" {
+		cfg.AuditLog = os.Getenv(auditLogEnv)
+	}
+
+	for _, shortcut := range []struct {
+		method    string
+		requested bool
+	}{{"xz", xzOutput}, {"bzip2", bzip2Output}} {
+		if !shortcut.requested {
+			continue
+		}
+		if cfg.Compress != "" && cfg.Compress != shortcut.method {
+			return Config{}, fmt.Errorf("--%s-output conflicts with --compress %s", shortcut.method, cfg.Compress)
+		}
+		cfg.Compress = shortcut.method
+	}
+	if cfg.RecordDelimiter != "" {
+		delimiter, err := strconv.Unquote(`"` + cfg.RecordDelimiter + `"`)
+		if err != nil {
+			return Config{}, fmt.Errorf("Invalid record delimiter: %s. Use escapes for special characters (e.g., \\n, \\t, \\\")", cfg.RecordDelimiter)
+		}
+		cfg.RecordDelimiter = delimiter
+	}
+	if maxMemoryString != "" {
+		maxMemory, err := parseByteSize(maxMemoryString)
+		if err != nil {
+			return Config{}, fmt.Errorf("Invalid memory size: %s. Use bytes or a number with K, M, G, or T (e.g., 512M)", maxMemoryString)
+		}
+		cfg.MaxMemory = maxMemory
+	}
+	if maxSeqLengthString != "" {
+		maxSeqLength, err := parseByteSize(maxSeqLengthString)
+		if err != nil || maxSeqLength < 1 {
+			return Config{}, fmt.Errorf("Invalid maximum sequence length: %s. Use bytes or a number with K, M, G, or T (e.g., 100M)", maxSeqLengthString)
+		}
+		cfg.MaxSeqLength = maxSeqLength
+	}
+
+	cfg.options = explicitOptions(fs)
+	cfg.effective = effectiveOptions(fs)
+	cfg.args = args
+
+	cfg.HashTypes = strings.Split(hashTypesString, ",")
+	if cfg.AutoHash {
+		_, hash := cfg.options["hash"]
+		_, h := cfg.options["H"]
+		if hash || h {
+			return Config{}, fmt.Errorf("--auto-hash can't be used with --hash (the hash type is chosen per record)")
+		}
+	}
+
+	return prepareConfig(cfg)
+}
+
+// prepareConfig checks the options of a run for invalid values and conflicts,
+// and derives the settings implied by them (e.g., --nofilename by --id-is-hash).
+// It is also applied to the Config passed to Process.
+func prepareConfig(cfg Config) (Config, error) {
+	if !isSupported(cfg.OutFormat, supportedOutFormats) {
+		return Config{}, fmt.Errorf("Invalid output format: %s. Supported formats are: %s", cfg.OutFormat, strings.Join(supportedOutFormats, ", "))
+	}
+
+	if cfg.SeqkitCompat && cfg.HeaderFormat != "" {
+		return Config{}, fmt.Errorf("--seqkit-compat and --header-format can't be used together")
+	}
+	if cfg.IDIsHash {
+		if cfg.HeaderFormat != "" || cfg.SeqkitCompat || cfg.SynthesizeIDs {
+			return Config{}, fmt.Errorf("--id-is-hash can't be used with --header-format, --seqkit-compat, or --synthesize-ids")
+		}
+		cfg.NoFileName = true
+	}
+	if cfg.IlluminaID {
+		if cfg.IDIsHash || cfg.SynthesizeIDs {
+			return Config{}, fmt.Errorf("--illumina-id can't be used with --id-is-hash or --synthesize-ids (the IDs are replaced)")
+		}
+		cfg.DropComment = true
+	}
+
+	if !isSupported(cfg.EncodeSequence, supportedSequenceEncodings) {
+		return Config{}, fmt.Errorf("Invalid sequence encoding: %s. Supported encodings are: %s", cfg.EncodeSequence, strings.Join(supportedSequenceEncodings, ", "))
+	}
+	if cfg.EncodeSequence != "none" && cfg.OutFormat != "json" && cfg.OutFormat != "ndjson" {
+		return Config{}, fmt.Errorf("--encode-sequence %s requires --out-format json or ndjson", cfg.EncodeSequence)
+	}
+	if cfg.RecordCountHeader && cfg.OutFormat == "json" && cfg.IndexFileName != "" {
+		return Config{}, fmt.Errorf("--output-record-count-header can't be used with --index (the records are spooled and written after their count)")
+	}
+
+	if !isSupported(cfg.JoinOn, supportedJoinKeys) {
+		return Config{}, fmt.Errorf("Invalid join key: %s. Supported keys are: %s", cfg.JoinOn, strings.Join(supportedJoinKeys, ", "))
+	}
+	if !isSupported(cfg.SheetMissing, supportedSheetMissing) {
+		return Config{}, fmt.Errorf("Invalid sheet-missing policy: %s. Supported policies are: %s", cfg.SheetMissing, strings.Join(supportedSheetMissing, ", "))
+	}
+
+	if c := findCodec(cfg.Compress); c != nil && !c.compiledIn() {
+		return Config{}, fmt.Errorf("Invalid compression: %v", c.errNotCompiledIn())
+	}
+	if cfg.Compress != "" && !isSupported(cfg.Compress, supportedCompressions()) {
+		return Config{}, fmt.Errorf("Invalid compression: %s. Supported methods are: %s", cfg.Compress, strings.Join(supportedCompressions(), ", "))
+	}
+	if cfg.XZLevel < 0 || cfg.XZLevel > 9 {
+		return Config{}, fmt.Errorf("Invalid xz compression level: %d. Must be between 0 and 9", cfg.XZLevel)
+	}
+
+	if cfg.TopN <= 0 {
+		return Config{}, fmt.Errorf("Invalid number of top sequences: %d. Must be a positive number", cfg.TopN)
+	}
+	if cfg.SeqLimit < 0 {
+		return Config{}, fmt.Errorf("Invalid sequence limit: %d. Can't be negative", cfg.SeqLimit)
+	}
+	if cfg.WithSequences && cfg.ClustersFile == "" && cfg.TopFile == "" {
+		return Config{}, fmt.Errorf("--with-sequences requires --clusters or --top")
+	}
+
+	if cfg.Window < 0 || cfg.Step < 0 {
+		return Config{}, fmt.Errorf("Invalid window: --window and --step must not be negative")
+	}
+	if cfg.Step > 0 && cfg.Window == 0 {
+		return Config{}, fmt.Errorf("--step requires --window")
+	}
+	if cfg.RecordDelimiter != "" && cfg.OutFormat != "fasta" {
+		return Config{}, fmt.Errorf("--record-delimiter requires --out-format fasta")
+	}
+	if cfg.LengthBin < 0 {
+		return Config{}, fmt.Errorf("Invalid length bin size: %d. Must not be negative", cfg.LengthBin)
+	}
+	if cfg.LengthBin > 0 && cfg.OutFormat != "fasta" {
+		return Config{}, fmt.Errorf("--group-by-length requires --out-format fasta (sections are separated by FASTA comment lines)")
+	}
+	if cfg.SpotCheck < 0 {
+		return Config{}, fmt.Errorf("Invalid spot check interval: %d. Must not be negative", cfg.SpotCheck)
+	}
+	if cfg.SpotCheck > 0 && (cfg.OutFormat != "fasta" || cfg.HeadersOnly) {
+		return Config{}, fmt.Errorf("--spot-check requires FASTA/FASTQ output with sequences (--out-format fasta, without --headersonly)")
+	}
+	if cfg.KeepComments && cfg.OutFormat != "fasta" {
+		return Config{}, fmt.Errorf("--keep-comments requires --out-format fasta (comment lines are only written to FASTA output)")
+	}
+	if cfg.ValidateRoundtrip && (cfg.OutFormat != "fasta" || cfg.HeadersOnly) {
+		return Config{}, fmt.Errorf("--validate-roundtrip requires FASTA/FASTQ output with sequences (--out-format fasta, without --headersonly)")
+	}
+	if cfg.ValidateRoundtrip && cfg.Window > 0 {
+		return Config{}, fmt.Errorf("--validate-roundtrip can't be used with --window (windows are not input records)")
+	}
+	if cfg.Ends < 0 {
+		return Config{}, fmt.Errorf("Invalid number of end bases: %d. Must not be negative", cfg.Ends)
+	}
+	if cfg.Ends > 0 && cfg.Window > 0 {
+		return Config{}, fmt.Errorf("--ends can't be used with --window")
+	}
+	if cfg.TreeChunk < 0 {
+		return Config{}, fmt.Errorf("Invalid tree hash chunk size: %d. Must not be negative", cfg.TreeChunk)
+	}
+	if cfg.TreeChunksFile != "" && cfg.TreeChunk == 0 {
+		return Config{}, fmt.Errorf("--tree-hash-chunks requires --tree-hash")
+	}
+	if cfg.TreeChunk > 0 && (cfg.Window > 0 || cfg.Ends > 0 || cfg.BothStrands || cfg.TrimNs || cfg.LengthPrefix || cfg.CollapseHomopolymers) {
+		return Config{}, fmt.Errorf("--tree-hash can't be used with --window, --ends, --both-strands, --trim-ns, --length-prefix, or --collapse-homopolymers (chunks are ranges of the normalized sequence)")
+	}
+	if cfg.CollapseHomopolymers && cfg.Window > 0 {
+		return Config{}, fmt.Errorf("--collapse-homopolymers can't be used with --window (window positions refer to the uncollapsed sequence)")
+	}
+
+	if cfg.MinLen < 0 {
+		return Config{}, fmt.Errorf("Invalid minimum length: %d. Must not be negative", cfg.MinLen)
+	}
+	if cfg.PassthroughExcluded {
+		switch {
+		case cfg.MinLen == 0 && cfg.IncludeIDFile == "":
+			return Config{}, fmt.Errorf("--passthrough-excluded requires --min-len or --include-id")
+		case cfg.Dedup || cfg.NWildcardDedup:
+			return Config{}, fmt.Errorf("--passthrough-excluded can't be used with --dedup or --n-wildcard-dedup (passed-through records would not be deduplicated)")
+		case cfg.OutFormat != "fasta":
+			return Config{}, fmt.Errorf("--passthrough-excluded requires --out-format fasta (records can't be passed through unchanged to %s output)", cfg.OutFormat)
+		}
+	}
+
+	if cfg.EmitTrimmed && !cfg.TrimNs {
+		return Config{}, fmt.Errorf("--emit-trimmed requires --trim-ns")
+	}
+	if cfg.EmitCollapsed && !cfg.CollapseHomopolymers {
+		return Config{}, fmt.Errorf("--emit-collapsed requires --collapse-homopolymers")
+	}
+
+	if cfg.ClusterK < 1 {
+		return Config{}, fmt.Errorf("Invalid cluster k-mer length: %d. Must be a positive number", cfg.ClusterK)
+	}
+	if cfg.ClusteredHash && cfg.Window > 0 {
+		return Config{}, fmt.Errorf("--clustered-hash can't be used with --window (windows are hashed instead of whole sequences)")
+	}
+	if cfg.DupReferences {
+		switch {
+		case cfg.Dedup || cfg.NWildcardDedup:
+			return Config{}, fmt.Errorf("--output-no-sequence-for-duplicates can't be used with --dedup or --n-wildcard-dedup (duplicates are dropped)")
+		case cfg.OutFormat != "fasta":
+			return Config{}, fmt.Errorf("--output-no-sequence-for-duplicates requires --out-format fasta")
+		case cfg.Window > 0:
+			return Config{}, fmt.Errorf("--output-no-sequence-for-duplicates can't be used with --window (window IDs are annotated)")
+		}
+	}
+	if cfg.DedupOutput != "" && (cfg.Dedup || cfg.NWildcardDedup) {
+		return Config{}, fmt.Errorf("--dedup-output can't be used with --dedup or --n-wildcard-dedup (the main output would be deduplicated as well)")
+	}
+	if cfg.DedupReport != "" && !cfg.Dedup && !cfg.NWildcardDedup {
+		return Config{}, fmt.Errorf("--dedup-report requires --dedup or --n-wildcard-dedup")
+	}
+	if cfg.DedupStats && !cfg.Dedup && !cfg.NWildcardDedup {
+		return Config{}, fmt.Errorf("--dedup-stats requires --dedup or --n-wildcard-dedup")
+	}
+	if cfg.SizeOut && !cfg.Dedup && !cfg.NWildcardDedup {
+		return Config{}, fmt.Errorf("--sizeout requires --dedup or --n-wildcard-dedup")
+	}
+	if cfg.StratifiedSample != "" {
+		if !cfg.Dedup && !cfg.NWildcardDedup {
+			return Config{}, fmt.Errorf("--stratified-sample requires --dedup or --n-wildcard-dedup (strata are defined by the abundances of unique sequences)")
+		}
+		strata, err := parseStrata(cfg.StratifiedSample)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.strata = strata
+	}
+
+	if cfg.AnonymizeLabels && cfg.HashKey == "" {
+		return Config{}, fmt.Errorf("--anonymize-labels requires --hash-key")
+	}
+	if cfg.LabelMapOut != "" && !cfg.AnonymizeLabels {
+		return Config{}, fmt.Errorf("--label-map-out requires --anonymize-labels")
+	}
+
+	if cfg.SizeRegexp != "" {
+		if _, err := newAbundanceParser(cfg.SizeRegexp); err != nil {
+			return Config{}, err
+		}
+		cfg.SizeIn = true
+	}
+
+	if cfg.GroupBy != "" {
+		if _, err := compileGroupPattern(cfg.GroupBy); err != nil {
+			return Config{}, err
+		}
+		if cfg.GroupReport == "" && !cfg.JsonSummary {
+			return Config{}, fmt.Errorf("--group-by requires --group-report or --json-with-summary")
+		}
+	} else if cfg.GroupReport != "" || cfg.GroupUnique {
+		return Config{}, fmt.Errorf("--group-report and --group-unique require --group-by")
+	}
+	if cfg.MaxGroups < 1 {
+		return Config{}, fmt.Errorf("Invalid maximum number of groups: %d. Must be a positive number", cfg.MaxGroups)
+	}
+
+	if !isSupported(cfg.SeqBytes, supportedSeqBytes) {
+		return Config{}, fmt.Errorf("Invalid sequence byte policy: %s. Supported policies are: %s", cfg.SeqBytes, strings.Join(supportedSeqBytes, ", "))
+	}
+	if !isSupported(cfg.OnError, supportedOnError) {
+		return Config{}, fmt.Errorf("Invalid error policy: %s. Supported policies are: %s", cfg.OnError, strings.Join(supportedOnError, ", "))
+	}
+	if cfg.RejectsFileName != "" && cfg.OnError != "skip" {
+		return Config{}, fmt.Errorf("--rejects requires --on-error skip")
+	}
+
+	if cfg.Threads < 1 {
+		return Config{}, fmt.Errorf("Invalid number of threads: %d. Must be a positive number", cfg.Threads)
+	}
+	if cfg.BigRecordThreshold < 0 {
+		return Config{}, fmt.Errorf("Invalid big record threshold: %d. Must not be negative", cfg.BigRecordThreshold)
+	}
+
+	if cfg.RecordTimeout < 0 {
+		return Config{}, fmt.Errorf("Invalid record timeout: %v. Must not be negative", cfg.RecordTimeout)
+	}
+
+	if cfg.FanoutThreshold < 0 || cfg.FanoutWorkers < 0 {
+		return Config{}, fmt.Errorf("Invalid fan-out settings: threshold and workers can't be negative")
+	}
+
+	if cfg.TmpDir != "" {
+		if info, err := os.Stat(cfg.TmpDir); err != nil {
+			return Config{}, fmt.Errorf("Invalid temporary directory: %v", err)
+		} else if !info.IsDir() {
+			return Config{}, fmt.Errorf("Invalid temporary directory: %s is not a directory", cfg.TmpDir)
+		}
+	}
+
+	if cfg.CollisionThreshold <= 0 || cfg.CollisionThreshold >= 1 {
+		return Config{}, fmt.Errorf("Invalid collision warning threshold: %g. Must be between 0 and 1", cfg.CollisionThreshold)
+	}
+
+	if cfg.IDHashLength <= 0 {
+		return Config{}, fmt.Errorf("Invalid ID hash length: %d. Must be a positive number", cfg.IDHashLength)
+	}
+
+	if len(cfg.HashTypes) == 0 && !cfg.AutoHash {
+		return Config{}, fmt.Errorf("No hash type given. Supported types are: %s", strings.Join(supportedHashTypes, ", "))
+	}
+	for _, ht := range cfg.HashTypes {
+		if !isValidHashType(strings.TrimSpace(ht)) {
+			return Config{}, fmt.Errorf("Invalid hash type: %s. Supported types are: %s", ht, strings.Join(supportedHashTypes, ", "))
+		}
+		if cfg.TreeChunk > 0 && !isSupported(ht, treeHashTypes) {
+			return Config{}, fmt.Errorf("--tree-hash requires cryptographic hash types (%s), got %s", strings.Join(treeHashTypes, ", "), ht)
+		}
+	}
+	if cfg.AutoHash {
+		switch {
+		case cfg.AutoHashThreshold < 0:
+			return Config{}, fmt.Errorf("Invalid auto-hash threshold: %d. Must not be negative", cfg.AutoHashThreshold)
+		case cfg.OutFormat != "fasta":
+			return Config{}, fmt.Errorf("--auto-hash requires --out-format fasta (other formats name the digests by a single hash type)")
+		case cfg.SeqkitCompat || cfg.HeaderFormat != "":
+			return Config{}, fmt.Errorf("--auto-hash can't be used with --seqkit-compat or --header-format (they name the digests by a single hash type)")
+		case cfg.TreeChunk > 0 || cfg.Window > 0:
+			return Config{}, fmt.Errorf("--auto-hash can't be used with --tree-hash or --window")
+		}
+		cfg.HashTypes = []string{autoHashShort} // The type of the digests of short sequences, e.g., for --dual-hash
+	}
+	if cfg.DualHash {
+		switch {
+		case len(cfg.HashTypes) != 1:
+			return Config{}, fmt.Errorf("--dual-hash requires a single hash type (the header is hashed with it)")
+		case cfg.HashTypes[0] == "nthash":
+			return Config{}, fmt.Errorf("--dual-hash can't be used with --hash nthash (ntHash only hashes DNA sequences)")
+		case cfg.IDIsHash || cfg.SeqkitCompat:
+			return Config{}, fmt.Errorf("--dual-hash can't be used with --id-is-hash or --seqkit-compat")
+		}
+	}
+
+	return cfg, nil
+}
+
+func isValidHashType(hashType string) bool {
+	return isSupported(hashType, supportedHashTypes)
+}
+
+func isSupported(value string, supported []string) bool {
+	for _, s := range supported {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// getInput opens the input file (or stdin), decompressing it if needed
+func getInput(fileName string) (io.ReadCloser, error) {
+	input, _, err := openInput(fileName, 0, nil)
+	return input, err
+}
+
+// openInput opens the input like getInput; with a timeout (--record-timeout), the raw input
+// is read through the returned watchdog, which then also covers the wait for the first bytes
+// (read to detect the compression) and stalls within compressed streams.
+// The raw bytes are also copied to tap, if given.
+func openInput(fileName string, timeout time.Duration, tap io.Writer) (io.ReadCloser, *watchdogReader, error) {
+	var src io.ReadCloser = os.Stdin
+	if fileName != "" && fileName != "-" {
+		file, err := openWatched(fileName, timeout)
+		if err != nil {
+			return nil, nil, err
+		}
+		src = file
+	}
+	var watchdog *watchdogReader
+	if timeout > 0 {
+		watchdog = newWatchdogReader(src, timeout)
+		src = watchedInput{watchdog, src}
+	}
+	if tap != nil {
+		src = tappedInput{io.TeeReader(src, tap), src}
+	}
+	input, err := decodeInput(src, fileName)
+	if err != nil {
+		src.Close()
+		return nil, nil, err
+	}
+	return input, watchdog, nil
+}
+
+// getOutput creates the output file, compressed according to its extension (e.g., ".xz" or ".bz2")
+func getOutput(fileName string) (io.WriteCloser, error) {
+	return getCompressedOutput(fileName, compressionFromName(fileName), defaultXZLevel)
+}
+
+func printUsage(w io.Writer) {
+	color := newPalette(w)
+	if len(os.Args) > 1 && (os.Args[1] == "-h" || os.Args[1] == "--help") {
+		fmt.Fprintf(w, "\n%s%s%s\n",
+			color.HiGreen("SeqHasher"),
+			color.White(" : "),
+			color.HiMagenta("DNA Sequence Hashing Tool"))
+		fmt.Fprintf(w, "%s  %s\n", color.HiCyan("version:"), color.White(version))
+		fmt.Fprintln(w, color.White("====================================="))
+		fmt.Fprintln(w, color.HiCyan("Usage:"))
+		fmt.Fprintf(w, "  %s\n", color.White("seqhasher [options] <input_file> [output_file]"))
+		fmt.Fprintf(w, "  %s\n", color.White("seqhasher inspect [--json] [--inspect-bytes N] <input_file>..."))
+		fmt.Fprintf(w, "  %s\n", color.White("seqhasher db <add|query|remove|stats> --db <file> [options] [files...]"))
+		fmt.Fprintf(w, "  %s\n", color.White("seqhasher doctor [options] <input_file> [output_file]"))
+		fmt.Fprintln(w, color.HiCyan("\nOverview:"))
+		fmt.Fprintln(w, color.White("  SeqHasher takes DNA sequences from a FASTA/FASTQ file, computes a hash digest for each sequence,"))
+		fmt.Fprintln(w, color.White("  and generates an output file with modified headers."))
+		fmt.Fprintln(w, color.White("  For input/output via stdin/stdout, use '-' instead of the file name."))
+		fmt.Fprintln(w, color.HiCyan("\nOptions:"))
+		fmt.Fprintf(w, "  %s, %s %s\n", color.HiMagenta("-o"), color.HiMagenta("--headersonly"), color.White("  Output only sequence headers, excluding the sequences themselves"))
+		fmt.Fprintf(w, "  %s, %s %s\n", color.HiMagenta("-H"), color.HiMagenta("--hash <type1,type2,...>"), color.White("Hash algorithm(s): sha1 (default), sha3, md5, xxhash, cityhash, murmur3, nthash, blake3"))
+		fmt.Fprintf(w, "  %s, %s %s\n", color.HiMagenta("-c"), color.HiMagenta("--casesensitive"), color.White("Take into account sequence case. By default, sequences are converted to uppercase"))
+		fmt.Fprintf(w, "  %s, %s %s\n", color.HiMagenta("-n"), color.HiMagenta("--nofilename"), color.White("   Omit the file name from the sequence header"))
+		fmt.Fprintf(w, "  %s, %s %s\n", color.HiMagenta("-f"), color.HiMagenta("--name <text>"), color.White("  Replace the input file's name in the header with <text>"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--synthesize-ids"), color.White("   Replace sequence IDs with hash-derived ones (seq_<hash prefix>); blank IDs are always replaced"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--warn-on-short-hash-collision-risk"), color.White("Warn if a hash collision is likely for the number of records"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--collision-warn-threshold <p>"), color.White("Collision probability that triggers the warning (default, 1e-6)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--id-is-hash"), color.White("       Use the first hash as the ID and the whole header (>hash), e.g., for content-addressed stores"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--seq-bytes <policy>"), color.White("Bytes allowed in sequences: iupac (default; IUPAC codes and gaps), ascii (printable), any"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--on-error <policy>"), color.White("Records with disallowed bytes: fail (default) or skip"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--rejects <file>"), color.White("   Write the records skipped with --on-error skip to <file>"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--verbose"), color.White("          Report details, e.g., non-ASCII whitespace (U+00A0, ...) removed from sequences"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--minimal-unique-prefix"), color.White("Shorten the first hash to the shortest prefix unique within the input"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--tmp-dir <dir>"), color.White("    Directory for temporary files (default, $TMPDIR or /tmp); they are removed after the run"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--id-hash-length <n>"), color.White("Number of hash characters in synthesized IDs (default, 8)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--index <file>"), color.White("     Write a TSV index (ID, hashes, byte offset, and length of each output record)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--record-delimiter <text>"), color.White("Write <text> between (not after) FASTA/FASTQ records, e.g., '\\n' for a blank line"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--no-metadata"), color.White("      Omit the leading '#' metadata lines (version, options, columns) from TSV and CSV output"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--reverse-output"), color.White("   Write the records last-to-first (they are kept in memory until the end of the input)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--group-by-length <n>"), color.White("Write the records in sections of length bins of <n> bases, each after a '; length-bin: X-Y' line"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--header-format <template>"), color.White("Header template with {file}, {id}, {<hash type>}, {comment}, {read}, {barcode}, and {meta:<column>} placeholders"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--encode-sequence base64"), color.White("Base64-encode the sequence in JSON output (for binary-safe transport)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--output-record-count-header"), color.White("Write JSON array output as {\"record_count\": N, \"records\": [...]} (no effect on streamed formats)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--stdin-name <text>"), color.White(" Label used in place of the file name for stdin input (file inputs keep their names)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--anonymize-labels"), color.White(" Replace the file name (or --name) in all outputs with a keyed pseudonym"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--hash-key <key>"), color.White("    Secret key for --anonymize-labels"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--label-map-out <file>"), color.White("Append the true label and its pseudonym to a TSV file (keep it private)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--drop-comment"), color.White("     Remove the comment (text after the ID, e.g., '1:N:0:ACGT') from the output header"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--dual-hash"), color.White("        Also hash the original header; write the hashes as seqhash=<digest>;hdrhash=<digest>"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--illumina-id"), color.White("      Normalize Illumina read names to the ID shared by paired reads (no /1, /2 suffix or comment)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--strip-annotations"), color.White("Remove the ';key=value' annotations (e.g., ';size=12') from the output header"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--seqkit-compat"), color.White("     Header as <ID>;sha1=<digest>;file=<name>; <description> (ID stays first, for seqkit)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--sample-sheet <file>"), color.White("CSV with per-input metadata added as extra columns (TSV, CSV, JSON outputs)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--join-on <key>"), color.White("    Match inputs to the first sheet column by: path (default), basename, name-label"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--sheet-missing <policy>"), color.White("Inputs missing from the sheet: warn (default), fail, skip-columns"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--clusters <file>"), color.White(" Write groups of identical sequences (digest, size, representative ID) as TSV"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--top <file>"), color.White("     Write the --top-n (default, 10) most abundant sequences as TSV"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--with-sequences"), color.White("   Add the representative's length and normalized sequence to the reports"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--seq-limit <n>"), color.White("    Truncate sequences in the reports to <n> characters, marked with '…'"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--min-len <n>"), color.White("      Hash only records with sequences of at least <n> bases (others are dropped)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--include-id <file>"), color.White("Hash only the records whose IDs are listed in <file> (one per line)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--passthrough-excluded"), color.White("Write excluded records unchanged in their original positions"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--keep-comments"), color.White("    Pass the ';' comment lines of FASTA input through to the output, along with their records"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--window <n>"), color.White("       Hash windows of <n> bases as separate records (';win=<start>-<end>' appended to the ID)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--step <n>"), color.White("         Start a window every <n> bases (default, the window size: tiling windows)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--ends <n>"), color.White("           Hash only the first and the last <n> bases, concatenated (e.g., to spot adapter or primer artifacts)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--both-strands"), color.White("     Hash both strands, so that a sequence and its reverse complement get the same hash"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--length-prefix"), color.White("    Hash the length of the data (8 bytes) before the data, for use in combined hashes"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--tree-hash <n>"), color.White("    Hash chunks of <n> bytes, and use the digest of the chunk digests (tree root) as the hash"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--tree-hash-chunks <file>"), color.White("Write the byte range and digest of each --tree-hash chunk as TSV"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--trim-ns"), color.White("          Remove leading and trailing runs of N before hashing (internal Ns are kept)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--emit-trimmed"), color.White("     Output the sequences trimmed with --trim-ns"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--collapse-homopolymers"), color.White("Collapse runs of identical bases to a single base before hashing (e.g., 'AAACCCTG' as 'ACTG')"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--emit-collapsed"), color.White("   Output the sequences collapsed with --collapse-homopolymers"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--dedup"), color.White("            Output only the first record of each unique sequence"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--n-wildcard-dedup"), color.White(" Deduplicate, treating ambiguity codes (N, R, Y, ...) at the same positions as equal"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--auto-hash"), color.White("        Hash sequences of up to --auto-hash-threshold bases with sha1 and longer ones with xxhash (';hash-type=<type>')"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--auto-hash-threshold <n>"), color.White("Longest sequence hashed with sha1 by --auto-hash (default, 1000 bases)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--clustered-hash"), color.White("   Annotate records with a bucket of near-identical sequences (';cluster=<bucket>', by banded MinHash)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--cluster-kmer <k>"), color.White(" K-mer length of --clustered-hash (default, 16; lower it for short sequences)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--output-no-sequence-for-duplicates"), color.White("Write later records of a sequence as header lines with ';dup-of=<ID of the first record>'"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--dedup-output <file>"), color.White("Also write the first record of each unique sequence to <file> (the main output keeps all records)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--dedup-report <file>"), color.White("Write how many records collapsed into how many sequences, with the size distribution"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--dedup-stats"), color.White("      Report how many records were deduplicated by packed sequence and by digest"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--sizeout"), color.White("          With --dedup, add the total abundance (;size=N) to the unique records, in first-seen order"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--stratified-sample <spec>"), color.White("With --dedup, keep a deterministic fraction of each abundance stratum (e.g., 'size>=100:1,size>=10:0.1,rest:0.01')"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--sizein"), color.White("           Count records by their abundance annotations (;size=N) in the reports"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--group-by <pattern>"), color.White("Summarize records by the first capture group of the pattern in their headers"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--group-report <file>"), color.White("Write the per-group records and bases (and --group-unique digests) as TSV"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--group-unique"), color.White("     Count unique digests per group (estimated with sketches past --max-memory)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--max-groups <n>"), color.White("   Fail if --group-by finds more than <n> groups (default, 1000)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--max-memory <size>"), color.White("Memory for exact unique-digest counts (e.g., 512M; default, unlimited)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--size-regexp <pattern>"), color.White("Extract abundances with a capture group of the pattern (e.g., ';count=(\\d+)')"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--threads <n>"), color.White("      Number of goroutines hashing records concurrently (default, 1; order is preserved)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--record-timeout <duration>"), color.White("Fail if no new record arrives within <duration> (e.g., 30s)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--max-seq-length <size>"), color.White("Fail as soon as the sequence of a record exceeds <size> (e.g., 100M)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--big-record-threshold <n>"), color.White("Hash records of at least <n> bases in a separate lane (default, 1048576)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--pipe-to <command>"), color.White("Pipe the output through a shell command (e.g., 'gzip -9') before writing it"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--compress <method>"), color.White("Output compression: "+strings.Join(supportedCompressions(), ", ")+" (default, chosen by the output file extension)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--xz-output"), color.White("        Compress output with xz (same as --compress xz)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--bzip2-output"), color.White("     Compress output with bzip2 (same as --compress bzip2)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--xz-level <0-9>"), color.White("   Compression level for xz output (default, 6)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--preflight"), color.White("        Check input, output, free space, and limits before processing (as 'seqhasher doctor')"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--verify-input <file>"), color.White("Verify the input against an MD5 or SHA-256 checksum file before processing"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--verify-input-checksum"), color.White("Verify the input against its sidecar (<input>.sha256 or <input>.md5)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--spot-check <n>"), color.White("   Verify every <n>-th written record by re-parsing it and rehashing the written sequence"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--validate-roundtrip"), color.White("Verify that every written sequence (and quality) equals the input one, up to the documented normalization"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--debug-positions <file>"), color.White("Write the number, header line, and byte offset of each input record as TSV"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--explain-output"), color.White("   Describe each output field and the values emitted for abnormal records, then exit"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--output-dir <dir>"), color.White(" Process all given files and write the outputs (named as the inputs) into <dir>"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--fail-fast"), color.White("        With --output-dir, stop at the first input that fails"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--run-report <file>"), color.White("With --output-dir, write the outcome of each input as JSON"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--stdin-commands"), color.White("   Run as a coprocess: read JSON commands from stdin, write a JSON result line for each to stdout"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--audit-log <file>"), color.White("Append a JSON line describing the run to <file> (or set $SEQHASHER_AUDIT_LOG)"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--strict"), color.White("         Treat audit log write failures as errors instead of warnings"))
+		fmt.Fprintf(w, "      %s %s\n", color.HiMagenta("--strict-flags"), color.White("   Fail on unknown options, also after the input and output files (with a did-you-mean suggestion)"))
+		fmt.Fprintf(w, "  %s, %s %s\n", color.HiMagenta("-v"), color.HiMagenta("--version"), color.White("      Print the version of the program and exit"))
+		fmt.Fprintf(w, "  %s, %s %s\n", color.HiMagenta("-h"), color.HiMagenta("--help"), color.White("         Show this help message and exit"))
+		fmt.Fprintln(w, color.HiCyan("\nArguments:"))
+		fmt.Fprintf(w, "  %s %s\n", color.HiMagenta("<input_file>"), color.White("    Path to the input FASTA/FASTQ file (supports "+strings.Join(codecNames(false), ", ")+" compression)"))
+		fmt.Fprintf(w, "  %s\n", color.White("                 or '-' for standard input (stdin)"))
+		fmt.Fprintf(w, "  %s %s\n", color.HiMagenta("[output_file]"), color.White("   Path to the output file or '-' for standard output (stdout)"))
+		fmt.Fprintln(w, color.White("                   If omitted, output is sent to stdout."))
+		fmt.Fprintln(w, color.HiCyan("\nExamples:"))
+		fmt.Fprintln(w, color.White("  seqhasher input.fasta.gz output.fasta"))
+		fmt.Fprintln(w, color.White("  cat input.fasta | seqhasher --name 'Sample' --hash xxhash - - > output.fasta"))
+		fmt.Fprintln(w, color.White("  seqhasher --headersonly --nofilename --hash sha1,nthash input.fa.gz - > headers.txt"))
+		fmt.Fprintln(w, color.White("\nFor more information, visit the GitHub repository:"))
+		fmt.Fprintln(w, color.White("https://github.com/vmikk/seqhasher"))
+	} else {
+		fmt.Fprintf(w, "SeqHasher v%s\n", version)
+		fmt.Fprintf(w, "Usage: %s [options] <input_file> [output_file]\n", os.Args[0])
+		fmt.Fprintf(w, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(w, "\nSupported hash types: %s\n", strings.Join(supportedHashTypes, ", "))
+		fmt.Fprintf(w, "If input_file is '-' or omitted, reads from stdin.\n")
+		fmt.Fprintf(w, "If output_file is '-' or omitted, writes to stdout.\n")
+		fmt.Fprintf(w, "\nFor more detailed help, use -h or --help.\n")
+	}
+}
+
+// Sequence of a record prepared for the output
+type preparedRecord struct {
+	seq    []byte   // Hashed bytes (normalized and, with --trim-ns, trimmed)
+	hashes []string // Digests, in the order of the requested hash types
+	bases  int      // Length of the normalized sequence
+	err    error    // Sequence rejected by the --seq-bytes policy (the record is left unchanged)
+
+	excluded bool // Excluded by the filters (the record is left unchanged and not hashed)
+
+	windows []seqWindow // With --window, the windows are hashed instead of the whole sequence
+	chunks  []treeChunk // With --tree-hash, the chunks of the first hash type
+	bands   []uint64    // With --clustered-hash, the band keys of the MinHash sketch
+
+	unicodeSpaces int  // Non-ASCII whitespace characters removed by normalization
+	firstSpace    rune // The first of them
+
+	original *originalRecord // With --validate-roundtrip, the sequence and qualities as read
+}
+
+// prepareRecord normalizes the sequence of a record in place and computes its digests
+func prepareRecord(record *fastx.Record, isFastq bool, cfg Config, fanout *hashFanout) preparedRecord {
+	// fastx readers are pooled, so a FASTA record may carry
+	// qualities left over from a previously parsed FASTQ file
+	if !isFastq {
+		record.Seq.Qual = nil
+	}
+
+	raw := record.Seq.Seq
+	seq := normalizeSequence(raw, cfg)
+	if !selectedRecord(record.ID, seq, cfg) {
+		return preparedRecord{excluded: true}
+	}
+	var original *originalRecord
+	if cfg.ValidateRoundtrip {
+		original = &originalRecord{seq: bytes.Clone(raw), qual: bytes.Clone(record.Seq.Qual)}
+	}
+	if position, ok := validateSeqBytes(seq, cfg.SeqBytes); !ok {
+		return preparedRecord{err: &seqByteError{id: string(record.ID), position: position, seq: seq, policy: cfg.SeqBytes}}
+	}
+	unicodeSpaces, firstSpace := countUnicodeSpaces(raw)
+	record.Seq.Seq = seq // Update the sequence in-place
+	bases := len(seq)
+
+	// Terminal N runs are excluded from hashing (and, on request, from the output)
+	offset := 0 // Position of the hashed bases in the output sequence
+	if cfg.TrimNs {
+		start, end := trimNsRange(seq)
+		if cfg.EmitTrimmed {
+			if len(record.Seq.Qual) == len(seq) {
+				record.Seq.Qual = record.Seq.Qual[start:end]
+			}
+			record.Seq.Seq = seq[start:end]
+		} else {
+			offset = start
+		}
+		seq = seq[start:end]
+	}
+	// Homopolymers are collapsed for hashing by fingerprint (and, on request, in the output)
+	if cfg.EmitCollapsed {
+		collapsed, qual := collapseRuns(record.Seq.Seq, record.Seq.Qual)
+		record.Seq.Seq = collapsed
+		if qual != nil {
+			record.Seq.Qual = qual
+		}
+	}
+
+	if cfg.Window > 0 {
+		return preparedRecord{
+			seq:           seq,
+			windows:       hashWindows(seq, offset, cfg, fanout),
+			bases:         bases,
+			unicodeSpaces: unicodeSpaces,
+			firstSpace:    firstSpace,
+		}
+	}
+
+	seq = fingerprint(seq, cfg)
+	var bands []uint64
+	if cfg.ClusteredHash {
+		bands = lshBandKeys(seq, cfg.ClusterK)
+	}
+	if cfg.TreeChunk > 0 {
+		hashes, chunks := treeHashes(seq, cfg)
+		return preparedRecord{
+			seq:           seq,
+			hashes:        hashes,
+			chunks:        chunks,
+			bands:         bands,
+			bases:         bases,
+			unicodeSpaces: unicodeSpaces,
+			firstSpace:    firstSpace,
+			original:      original,
+		}
+	}
+	return preparedRecord{
+		seq:           seq,
+		hashes:        computeHashes(seq, cfg, fanout),
+		bands:         bands,
+		bases:         bases,
+		unicodeSpaces: unicodeSpaces,
+		firstSpace:    firstSpace,
+		original:      original,
+	}
+}
+
+func processSequences(input io.Reader, output io.Writer, cfg Config) error {
+	_, err := processRecords(input, output, cfg)
+	return err
+}
+
+// processRecords hashes all records from input, writes them to output
+// in the output format, and returns the summary counts of the run
+func processRecords(input io.Reader, output io.Writer, cfg Config) (Stats, error) {
+	writer := bufio.NewWriter(output)
+	// Track the output position of each record for the index
+	counter := &countingWriter{w: writer}
+	return processStream(context.Background(), input, cfg, counter, func(cfg Config, label string) OutputSink {
+		return newOutputSink(counter, writer, cfg, label)
+	})
+}
+
+// processStream hashes all records from input and passes them to the sink made by newSink.
+// counter tracks the output position for the index (nil for sinks of other callers).
+func processStream(ctx context.Context, input io.Reader, cfg Config, counter *countingWriter,
+	newSink func(cfg Config, label string) OutputSink) (stats Stats, err error) {
+	// The sink is flushed and closed on all exit paths
+	var sink OutputSink
+	var inputFileName string
+	defer func() {
+		if sink == nil {
+			// Setup failed before any output
+			sink = newSink(cfg, inputFileName)
+			cfg.KeepPartial = false
+		}
+		err = closeSink(sink, cfg, err)
+	}()
+
+	spot := newSpotChecker(cfg)
+	if spot != nil && counter == nil {
+		return stats, fmt.Errorf("--spot-check requires a built-in output format")
+	}
+	roundtrip := newRoundtripValidator(cfg)
+	if roundtrip != nil && counter == nil {
+		return stats, fmt.Errorf("--validate-roundtrip requires a built-in output format")
+	}
+
+	var index *indexWriter
+	if cfg.IndexFileName != "" {
+		if counter == nil {
+			return stats, fmt.Errorf("--index requires a built-in output format")
+		}
+		var err error
+		index, err = newIndexWriter(cfg.IndexFileName)
+		if err != nil {
+			return stats, fmt.Errorf("Error opening index: %v", err)
+		}
+		defer index.Close()
+	}
+
+	if cfg.SampleSheet != "" && cfg.meta == nil {
+		cfg.meta, err = loadSampleMeta(cfg)
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	inputFileName, err = anonymizeLabel(cfg, fileLabel(&cfg))
+	if err != nil {
+		return stats, err
+	}
+	fields := outputFields(cfg)
+	header, err := headerBuilder(cfg, fields)
+	if err != nil {
+		return stats, err
+	}
+
+	sink = newSink(cfg, inputFileName)
+	_, sectioned := sink.(sectioningSink)
+	if cfg.LengthBin > 0 && !sectioned {
+		return stats, fmt.Errorf("--group-by-length requires a built-in output format")
+	}
+	commenting, commented := sink.(commentingSink)
+	if cfg.KeepComments && !commented {
+		return stats, fmt.Errorf("--keep-comments requires a built-in output format")
+	}
+	dedupOut, err := newDedupOutput(cfg, inputFileName)
+	if err != nil {
+		return stats, err
+	}
+	if dedupOut != nil {
+		defer func() {
+			if cerr := dedupOut.Close(cfg, err); err == nil {
+				err = cerr
+			}
+		}()
+	}
+	emitter := &recordEmitter{cfg: cfg, sink: sink, header: header, fileName: inputFileName, label: inputFileName,
+		counter: counter, spot: spot, roundtrip: roundtrip, index: index, dedupOut: dedupOut}
+	if cfg.NoFileName {
+		emitter.label = ""
+	}
+
+	// Stalled input (e.g., a pipe from a hung process) fails the run
+	if cfg.RecordTimeout > 0 && cfg.watchdog == nil {
+		cfg.watchdog = newWatchdogReader(input, cfg.RecordTimeout)
+		defer cfg.watchdog.Close()
+		input = cfg.watchdog
+	}
+
+	// Oversized records (e.g., from a corrupted file) fail before they are read into memory
+	if cfg.MaxSeqLength > 0 {
+		input = newLengthGuard(input, cfg.MaxSeqLength)
+	}
+
+	// Prefix lengths need all digests, so the input is read twice
+	var prefixes map[string]int
+	if cfg.MinimalUniquePrefix {
+		var spool *os.File
+		prefixes, spool, err = uniquePrefixes(input, cfg)
+		if err != nil {
+			return stats, err
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+		input = spool
+	}
+
+	var tracker *positionTracker
+	var positions *positionsWriter
+	if cfg.DebugPositions != "" {
+		tracker = newPositionTracker(input)
+		input = tracker
+		if positions, err = newPositionsWriter(cfg.DebugPositions); err != nil {
+			return stats, fmt.Errorf("Error opening positions file: %v", err)
+		}
+		defer positions.Close()
+	}
+	// Comment lines are removed before parsing (after the positions, which count all input lines)
+	var filter *commentFilter
+	if cfg.KeepComments {
+		filter = newCommentFilter(input)
+		input = filter
+	}
+	var trees *treeChunkWriter
+	if cfg.TreeChunksFile != "" {
+		if trees, err = newTreeChunkWriter(cfg.TreeChunksFile); err != nil {
+			return stats, fmt.Errorf("Error opening tree hash chunks file: %v", err)
+		}
+		defer trees.Close()
+	}
+
+	reader, err := newFastxReader(input)
+	if err != nil {
+		return stats, fmt.Errorf("Failed to create reader: %v", err)
+	}
+	if reader != nil {
+		defer reader.Close()
+	}
+
+	dedup := newDeduplicator(cfg)
+	refs := newDuplicateReferences(cfg)
+	clusters := newLSHClusters(cfg)
+	sampler := newStratifiedSampler(cfg)
+	groups := newGroupCollector(cfg)
+	var sizes *abundanceParser
+	if cfg.SizeIn || cfg.SizeOut {
+		if sizes, err = newAbundanceParser(cfg.SizeRegexp); err != nil {
+			return stats, err
+		}
+	}
+	buffer := newRecordBuffer(cfg, dedup, sampler, sizes)
+	if cfg.IncludeIDFile != "" && cfg.includeIDs == nil {
+		if cfg.includeIDs, err = loadIDList(cfg.IncludeIDFile); err != nil {
+			return stats, fmt.Errorf("Error reading ID list: %v", err)
+		}
+	}
+
+	byGroup, err := newHeaderGroups(cfg)
+	if err != nil {
+		return stats, err
+	}
+	// Records are prepared (normalized and hashed) either one by one,
+	// or concurrently by a pool that returns them in input order
+	var next func() (*fastx.Record, preparedRecord, error)
+	if reader != nil && cfg.Threads > 1 {
+		pool := newRecordPool(reader, cfg)
+		defer pool.Close()
+		next = pool.next
+	} else if reader != nil {
+		fanout := newHashFanout(cfg, 1)
+		defer fanout.Close()
+		next = func() (*fastx.Record, preparedRecord, error) {
+			record, err := reader.Read()
+			if err != nil {
+				return nil, preparedRecord{}, err
+			}
+			cfg.watchdog.recordRead()
+			return record, prepareRecord(record, reader.IsFastq, cfg, fanout), nil
+		}
+	}
+
+	rejects, err := newRejectsWriter(cfg.RejectsFileName)
+	if err != nil {
+		return stats, err
+	}
+	defer rejects.Close()
+	var cleaned struct{ records, characters int }
+	var shortRecords int64 // Records without windows
+
+	for reader != nil { // nil for empty input
+		if interrupted.Load() {
+			return stats, errInterrupted
+		}
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		record, prepared, err := next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			if tracker != nil {
+				return stats, fmt.Errorf("Error reading record: %v (%s)", err, tracker.describeFailure())
+			}
+			return stats, fmt.Errorf("Error reading record: %v", err)
+		}
+		if positions != nil {
+			n, pos, known := tracker.take()
+			if err := positions.add(n, pos, known, record.ID); err != nil {
+				return stats, fmt.Errorf("Error writing positions file: %v", err)
+			}
+		}
+		var comments *recordComments // Written with the record, or dropped with it
+		if filter != nil {
+			comments = filter.take()
+		}
+		if prepared.excluded {
+			stats.Excluded++
+			if cfg.PassthroughExcluded {
+				// Original header and sequence, in the original position
+				if buffer != nil && buffer.ordered {
+					buffer.add(keptRecord{record: record, unique: -1, excluded: true, comments: comments})
+				} else if err := emitter.write(record, nil, comments, false); err != nil {
+					return stats, err
+				}
+				stats.PassedThrough++
+			}
+			continue
+		}
+		if prepared.err != nil {
+			if cfg.OnError != "skip" {
+				return stats, prepared.err
+			}
+			log.Printf("Warning: skipping record: %v", prepared.err)
+			if err := rejects.write(record); err != nil {
+				return stats, err
+			}
+			continue
+		}
+		if prepared.unicodeSpaces > 0 {
+			cleaned.records++
+			cleaned.characters += prepared.unicodeSpaces
+			if cfg.Verbose {
+				log.Printf("Record %q: removed %d non-ASCII whitespace character(s) from the sequence, e.g., %s",
+					record.ID, prepared.unicodeSpaces, unicodeName(prepared.firstSpace))
+			}
+		}
+		stats.Records++
+		stats.Bases += int64(prepared.bases)
+		if trees != nil {
+			if err := trees.add(stats.Records, record.ID, prepared.chunks); err != nil {
+				return stats, fmt.Errorf("Error writing tree hash chunks file: %v", err)
+			}
+		}
+		if cfg.Window > 0 && len(prepared.windows) == 0 {
+			shortRecords++
+		}
+		var hdrHash string
+		if cfg.DualHash {
+			if hdrHash, err = headerHash(cfg, record); err != nil {
+				return stats, err
+			}
+		}
+		for _, unit := range prepared.units(record, cfg.Window > 0) {
+			record, seq, hashes := unit.record, unit.seq, unit.hashes
+			unitComments := comments
+			comments = nil // With --window, only the first window is preceded by the comments
+			stats.Digests++
+			if prefixes != nil && len(hashes) > 0 {
+				if n, ok := prefixes[hashes[0]]; ok {
+					hashes[0] = hashes[0][:n]
+				}
+			}
+
+			// Groups are defined by the original headers
+			if byGroup != nil {
+				digest := ""
+				if len(hashes) > 0 && len(seq) > 0 {
+					digest = hashes[0]
+				}
+				if err := byGroup.add(record.Name, digest, unit.bases, record.ID); err != nil {
+					return stats, err
+				}
+			}
+
+			// Abundances are annotated in the original headers
+			size := int64(1)
+			if cfg.SizeIn {
+				if size, err = sizes.abundance(record.Name); err != nil {
+					return stats, err
+				}
+			}
+
+			renameRecord(record, hashes, cfg)
+
+			// Reports count all records, including duplicates dropped from the output
+			if groups != nil && len(hashes) > 0 {
+				groups.add(hashes[0], record.ID, seq, size)
+			}
+			// Records arrive in input order (also from the pool of --threads),
+			// so the representative of a sequence is its first record in the input
+			first := dedupOut != nil && dedupOut.first(seq, size)
+			var dupOf string
+			if refs != nil {
+				if record.Seq.Qual != nil { // Dropped from FASTA records by prepareRecord
+					return stats, fmt.Errorf("--output-no-sequence-for-duplicates requires FASTA input (FASTQ records can't be written without sequences)")
+				}
+				dupOf = refs.firstID(seq, record.ID)
+			}
+			var hashType string
+			if cfg.AutoHash && len(hashes) > 0 {
+				hashType = autoHashType(len(seq), cfg.AutoHashThreshold)
+			}
+			// Buckets are assigned to all records, including duplicates dropped from the output
+			var cluster string
+			if clusters != nil {
+				cluster = clusters.bucket(unit.bands)
+			}
+			unique := -1
+			if dedup != nil {
+				i, duplicate := dedup.add(seq, size)
+				if duplicate {
+					continue
+				}
+				if buffer != nil && buffer.abundances {
+					unique = i
+				}
+			}
+			k := keptRecord{record: record, hashes: hashes, unique: unique, original: unit.original, comments: unitComments, first: first, header: hdrHash, dupOf: dupOf, cluster: cluster, hashType: hashType}
+			if buffer != nil {
+				buffer.add(k)
+				continue
+			}
+
+			if err := emitter.emit(k, -1); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	if buffer != nil {
+		if err := buffer.flush(ctx, emitter, &stats); err != nil {
+			return stats, err
+		}
+	}
+
+	if filter != nil {
+		if err := commenting.comments(filter.trailing()); err != nil {
+			return stats, &SinkError{Index: emitter.written, Err: err}
+		}
+	}
+
+	if cfg.CollisionWarn {
+		for _, warning := range collisionWarnings(cfg, stats.Digests) {
+			log.Print(warning)
+		}
+	}
+	if shortRecords > 0 {
+		log.Printf("Warning: %d record(s) shorter than --window %d produced no windows", shortRecords, cfg.Window)
+	}
+	if stats.PassedThrough > 0 {
+		log.Printf("Hashed %d record(s); passed through %d excluded record(s) unchanged", stats.Records, stats.PassedThrough)
+	} else if stats.Excluded > 0 {
+		log.Printf("Hashed %d record(s); dropped %d excluded record(s)", stats.Records, stats.Excluded)
+	}
+	if roundtrip != nil {
+		log.Printf("Round-trip validation: the sequences of all %d written record(s) match the input", roundtrip.validated)
+	}
+	if sampler != nil {
+		for _, line := range sampler.report() {
+			log.Print(line)
+		}
+	}
+	if rejects.count > 0 {
+		log.Printf("Warning: skipped %d record(s) with invalid sequence bytes", rejects.count)
+	}
+	if err := rejects.Close(); err != nil {
+		return stats, fmt.Errorf("Error writing rejects file: %v", err)
+	}
+	if cfg.Verbose && cleaned.records > 0 {
+		log.Printf("Removed %d non-ASCII whitespace character(s) from the sequences of %d record(s)", cleaned.characters, cleaned.records)
+	}
+
+	if byGroup != nil {
+		stats.groups = byGroup.summaries()
+	}
+	if s, ok := sink.(finishingSink); ok {
+		if err := s.finish(stats); err != nil {
+			return stats, fmt.Errorf("Error writing output: %v", err)
+		}
+	}
+	if dedupOut != nil {
+		if err := dedupOut.finish(stats); err != nil {
+			return stats, err
+		}
+		log.Printf("Wrote %d of %d hashed record(s) with unique sequences to %s", dedupOut.written, stats.Digests, cfg.DedupOutput)
+	}
+
+	if groups != nil {
+		if err := groups.writeReports(cfg); err != nil {
+			return stats, err
+		}
+	}
+
+	if byGroup != nil && cfg.GroupReport != "" {
+		if err := byGroup.writeReport(cfg.GroupReport); err != nil {
+			return stats, fmt.Errorf("Error writing group report: %v", err)
+		}
+	}
+
+	if dedup != nil && cfg.DedupStats {
+		log.Print(dedup.stats())
+	}
+	if dedup != nil && cfg.DedupReport != "" {
+		if err := dedup.writeReport(cfg.DedupReport); err != nil {
+			return stats, fmt.Errorf("Error writing dedup report: %v", err)
+		}
+	}
+
+	if index != nil {
+		if err := index.Close(); err != nil {
+			return stats, fmt.Errorf("Error writing index: %v", err)
+		}
+	}
+	if positions != nil {
+		if err := positions.Close(); err != nil {
+			return stats, fmt.Errorf("Error writing positions file: %v", err)
+		}
+	}
+	if trees != nil {
+		if err := trees.Close(); err != nil {
+			return stats, fmt.Errorf("Error writing tree hash chunks file: %v", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// renameRecord rewrites the ID of a hashed record (--illumina-id, --id-is-hash, --synthesize-ids)
+func renameRecord(record *fastx.Record, hashes []string, cfg Config) {
+	// Mates of Illumina read pairs get the same ID; the comment is dropped from the header
+	if cfg.IlluminaID {
+		id := illuminaReadID(record.ID)
+		record.Name = append(append([]byte{}, id...), record.Name[len(record.ID):]...)
+		record.ID = record.Name[:len(id)]
+	}
+
+	// Replace blank (or, on request, all) IDs with hash-derived ones
+	if len(hashes) > 0 && cfg.IDIsHash {
+		record.ID = []byte(hashes[0])
+		record.Name = record.ID
+	} else if len(hashes) > 0 && (cfg.SynthesizeIDs || len(bytes.TrimSpace(record.ID)) == 0) {
+		// Annotations of the ID are kept
+		var notes annotations
+		annotated := record.ID[len(notes.trim(record.ID)):]
+		id := synthesizeID(hashes[0], cfg.IDHashLength)
+		record.Name = append(append(append([]byte{}, id...), annotated...), record.Name[len(record.ID):]...)
+		record.ID = id
+	}
+}
+
+// newFastxReader creates a FASTA/FASTQ reader (nil for an empty input)
+func newFastxReader(input io.Reader) (*fastx.Reader, error) {
+	buffered := bufio.NewReader(input)
+	if _, err := buffered.Peek(1); err == io.EOF {
+		return nil, nil // fastx can't handle inputs without content
+	} else if err != nil {
+		return nil, err // fastx panics on inputs that fail before their first byte
+	}
+	return fastx.NewReaderFromIO(seq.DNA, buffered, fastx.DefaultIDRegexp)
+}
+
+// normalizeSequence prepares the sequence for hashing
+func normalizeSequence(seq []byte, cfg Config) []byte {
+	// Strip all whitespace characters from sequence before processing
+	// (as defined by Unicode's White Space property, which includes
+	// '\t', '\n', '\v', '\f', '\r', ' ', U+0085 (NEL), U+00A0 (NBSP)
+	seq = bytes.Join(bytes.Fields(seq), nil)
+
+	// Convert sequence to uppercase if case-insensitive hashing is enabled
+	if !cfg.CaseSensitive {
+		seq = bytes.ToUpper(seq)
+	}
+	return seq
+}
+
+// trimNsRange returns the bounds of the sequence without leading and trailing runs of N
+func trimNsRange(seq []byte) (start, end int) {
+	start, end = 0, len(seq)
+	for start < end && (seq[start] == 'N' || seq[start] == 'n') {
+		start++
+	}
+	for end > start && (seq[end-1] == 'N' || seq[end-1] == 'n') {
+		end--
+	}
+	return start, end
+}
+
+// fingerprint returns the bytes that are hashed for a (trimmed) sequence
+func fingerprint(seq []byte, cfg Config) []byte {
+	// Homopolymer runs are collapsed first, so that --ends takes the ends of the collapsed sequence
+	if cfg.CollapseHomopolymers {
+		seq = collapseHomopolymers(seq)
+	}
+
+	// Split-end fingerprint (the ends of the reverse complement are the
+	// reverse complement of the ends, so it also works with --both-strands)
+	if cfg.Ends > 0 {
+		seq = sequenceEnds(seq, cfg.Ends)
+	}
+
+	// Orientation-independent fingerprint of both strands
+	if cfg.BothStrands {
+		seq = bothStrands(seq)
+	}
+	return seq
+}
+
+// lengthPrefixed returns the hashed data preceded by its length, as 8 big-endian bytes
+// (--length-prefix). As the length is fixed-size, the data of one sequence can't be
+// a prefix of the data of another, and concatenations (e.g., 'AC'+'TG' and 'A'+'CTG') stay distinct.
+func lengthPrefixed(data []byte) []byte {
+	prefixed := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(prefixed, uint64(len(data)))
+	return append(prefixed, data...)
+}
+
+// sequenceEnds returns the first n and the last n bases of the sequence, concatenated
+// (--ends). Sequences of up to 2n bases, whose ends would overlap, are returned whole.
+func sequenceEnds(seq []byte, n int) []byte {
+	if len(seq) <= 2*n {
+		return seq
+	}
+	return append(seq[:n:n], seq[len(seq)-n:]...)
+}
+
+// fileLabel returns the text used in place of the input file name in headers
+func fileLabel(cfg *Config) string {
+	if cfg.NameOverride != "" {
+		return cfg.NameOverride
+	}
+	if cfg.InputFileName == "-" {
+		if cfg.StdinName != "" {
+			return cfg.StdinName
+		}
+		cfg.NoFileName = true // Skip filename for stdin unless overridden
+	}
+	return cfg.InputFileName
+}
+
+// synthesizeID returns a stable ID ("seq_<hash prefix>") derived from the hash
+func synthesizeID(hash string, length int) []byte {
+	if length <= 0 {
+		length = defaultIDHashLength
+	}
+	if length > len(hash) {
+		length = len(hash)
+	}
+	return append([]byte("seq_"), hash[:length]...)
+}
+
+// Hash algorithm metadata
+type hashAlgorithm struct {
+	width int                          // Length of the hex digest
+	sum   func([]byte) (string, error) // Computes the hex digest of the data
+}
+
+var hashAlgorithms = map[string]hashAlgorithm{
+	"sha1": {40, func(data []byte) (string, error) {
+		hash := sha1.Sum(data)
+		return hex.EncodeToString(hash[:]), nil
+	}},
+	"sha3": {128, func(data []byte) (string, error) {
+		hash := sha3.Sum512(data)
+		return hex.EncodeToString(hash[:]), nil
+	}},
+	"md5": {32, func(data []byte) (string, error) {
+		hash := md5.Sum(data)
+		return hex.EncodeToString(hash[:]), nil
+	}},
+	"xxhash": {16, func(data []byte) (string, error) {
+		return fmt.Sprintf("%016x", xxhash.Sum64(data)), nil
+	}},
+	"cityhash": {32, func(data []byte) (string, error) {
+		hash := city.Hash128(data)
+		return fmt.Sprintf("%016x%016x", hash.High, hash.Low), nil
+	}},
+	"murmur3": {32, func(data []byte) (string, error) {
+		h1, h2 := murmur3.Sum128(data)
+		return fmt.Sprintf("%016x%016x", h1, h2), nil
+	}},
+	"nthash": {16, func(data []byte) (string, error) {
+		hasher, err := nthash.NewHasher(&data, uint(len(data)))
+		if err != nil {
+			return "", fmt.Errorf("Error creating ntHash hasher: %v", err)
+		}
+		hash, ok := hasher.Next(false) // false for non-canonical hash
+		if !ok {
+			return "", fmt.Errorf("ntHash produced no value")
+		}
+		return fmt.Sprintf("%016x", hash), nil
+	}},
+	"blake3": {64, func(data []byte) (string, error) {
+		hash := blake3.Sum256(data)
+		return hex.EncodeToString(hash[:]), nil
+	}},
+}
+
+// getHashFunc returns a function that takes a byte slice and returns a hex string
+// of the hash based on the specified hash type.
+// Abnormal cases are replaced with the values from the hashSentinels table.
+func getHashFunc(hashType string) func([]byte) string {
+	algorithm, ok := hashAlgorithms[hashType]
+	if !ok { // Default to SHA1
+		algorithm = hashAlgorithms[defaultHashType]
+	}
+
+	return func(data []byte) string {
+		if len(data) == 0 {
+			log.Printf("Error: Empty DNA sequence provided, resulting in an empty hash.")
+			return hashSentinels[condEmptySequence]
+		}
+
+		hash, err := algorithm.sum(data)
+		if err != nil {
+			log.Printf("Error computing %s hash: %v", hashType, err)
+			return hashSentinels[condHashFailure]
+		}
+		return hash
+	}
+}
@@ -0,0 +1,48 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"strings"
+	"unicode"
+)
+
+// seqkitHeader builds a header that keeps the original ID as the first token
+// and appends the annotations to it as ";key=value" pairs:
+//
+//	<ID>;<hash type>=<digest>[;<hash type>=<digest>...][;file=<label>][;<annotations>];[ <description>]
+//
+// (e.g., "seq1;sha1=65c8...;file=in.fa; sample A"). Annotations of the input with the same keys are replaced.
+// The annotated token contains no whitespace, so it is the ID for seqkit (default --id-regexp "^(\S+)\s?");
+// "--id-regexp '^([^;\s]+)'" recovers the original ID, and the description is kept after a space.
+func seqkitHeader(cfg Config, r *hashedRecord) []byte {
+	header := append([]byte{}, r.id...)
+	for i, hashType := range cfg.HashTypes {
+		header = append(header, ';')
+		header = append(header, hashType...)
+		header = append(header, '=')
+		header = append(header, r.hashes[i]...)
+	}
+	if !cfg.NoFileName {
+		header = append(header, ";file="...)
+		header = append(header, strings.Map(func(c rune) rune {
+			if unicode.IsSpace(c) || c == ';' {
+				return '_' // Would break the annotation token
+			}
+			return c
+		}, r.label)...)
+	}
+	written := cfg.HashTypes
+	if !cfg.NoFileName {
+		written = append([]string{"file"}, written...)
+	}
+	header = r.annotations.appendTo(header, written...)
+	header = append(header, ';')
+	if cfg.DropComment {
+		return header
+	}
+	return append(header, r.tail...)
+}
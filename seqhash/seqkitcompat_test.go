@@ -0,0 +1,38 @@
+package seqhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Headers documented in README (seqkit-compatible headers)
+func TestSeqkitCompatHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		cfg      Config
+		expected string
+	}{
+		{"ID only", ">seq1\nACTG\n", Config{HashTypes: []string{"sha1"}, NoFileName: true},
+			">seq1;sha1=65c89f59d38cdbf90dfaf0b0a6884829df8396b0;\nACTG\n"},
+		{"Description and file", ">seq1 sample A\nACTG\n", Config{HashTypes: []string{"sha1", "md5"}, NameOverride: "run 1.fa"},
+			">seq1;sha1=65c89f59d38cdbf90dfaf0b0a6884829df8396b0;md5=86bfb9f78dd8b6cd35962bb7324fdbf8;file=run_1.fa; sample A\nACTG\n"},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.SeqkitCompat = true
+			cfg.InputFileName = "test.fasta"
+
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Got %q, want %q", output.String(), tt.expected)
+			}
+		})
+	}
+}
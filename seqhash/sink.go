@@ -0,0 +1,146 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// OutputSink receives the processed records, in output order.
+// Flush and Close are called once when processing ends, also after errors and cancellation.
+type OutputSink interface {
+	WriteRecord(Record) error
+	Flush() error
+	Close() error
+}
+
+// Record is a processed record, as passed to an OutputSink.
+// The byte slices are reused by the reader, so they are only valid during WriteRecord.
+type Record struct {
+	Index     int64    // Position in the output (0-based)
+	File      string   // File label (empty with --nofilename)
+	ID        []byte   // Sequence ID (after --synthesize-ids)
+	Header    []byte   // Header as written (for passed-through records, the original one)
+	HashTypes []string // Requested hash types
+	Hashes    []string // Digests, in the order of HashTypes (nil for passed-through records)
+	Sequence  []byte   // Sequence as written by the built-in formats
+	Quality   []byte   // Quality scores (empty for FASTA)
+
+	fastx    *fastx.Record // Used by the built-in sinks
+	hashed   *hashedRecord
+	comments *recordComments // FASTA comment lines of the record (--keep-comments)
+	noSeq    bool            // Written without the sequence (duplicates with --output-no-sequence-for-duplicates)
+}
+
+// SinkError is a failure of the output sink, which aborts processing
+type SinkError struct {
+	Index int64 // Index of the record that could not be written
+	Err   error
+}
+
+func (e *SinkError) Error() string {
+	return fmt.Sprintf("%v (record index %d)", e.Err, e.Index)
+}
+
+func (e *SinkError) Unwrap() error { return e.Err }
+
+// Process hashes all records from input and passes them to the sink.
+// This is the entry point for programs that import the package: cfg is obtained from ParseArgs
+// (with the defaults of the command line), and the output format (OutFormat) is up to the sink.
+// cfg is checked as the options of the command line are, so invalid values and conflicting
+// options fail before any record is read.
+// Side outputs that depend on the byte layout of the output (--index) are not available.
+// As in the command line, the sequence validation of the bio package is disabled
+// (seq.ValidateSeq = false), so that the bytes of sequences are handled by SeqBytes.
+func Process(ctx context.Context, input io.Reader, sink OutputSink, cfg Config) (Stats, error) {
+	seq.ValidateSeq = false
+
+	cfg, err := prepareConfig(cfg)
+	if err != nil {
+		return Stats{}, closeSink(sink, cfg, err)
+	}
+	return processStream(ctx, input, cfg, nil, func(Config, string) OutputSink { return sink })
+}
+
+// newOutputSink creates the built-in sink of the output format (--out-format).
+// buf is the buffer under w, which is flushed by the sink; the output stream itself stays open.
+func newOutputSink(w io.Writer, buf *bufio.Writer, cfg Config, label string) OutputSink {
+	if cfg.NoFileName {
+		label = ""
+	}
+	stream := sinkStream{w: w, buf: buf}
+	switch cfg.OutFormat {
+	case "json":
+		return &jsonWriter{sinkStream: stream, cfg: cfg, label: label, countHeader: cfg.RecordCountHeader}
+	case "ndjson":
+		return &jsonWriter{sinkStream: stream, cfg: cfg, label: label, lines: true}
+	case "tsv", "csv":
+		return newTableWriter(stream, cfg, label)
+	case "protobuf":
+		return &protobufWriter{sinkStream: stream, headersOnly: cfg.HeadersOnly, label: label}
+	default:
+		return &fastaWriter{sinkStream: stream, headersOnly: cfg.HeadersOnly, delimiter: []byte(cfg.RecordDelimiter)}
+	}
+}
+
+// finishingSink is implemented by sinks that frame their output (e.g., a JSON array)
+type finishingSink interface {
+	// finish completes the output after the last record
+	finish(stats Stats) error
+	// abort marks the output as incomplete (used with --keep-partial)
+	abort(err error)
+}
+
+// sectioningSink is implemented by sinks that can separate groups of records (--group-by-length)
+type sectioningSink interface {
+	// section starts a group of records with a comment line
+	section(title string) error
+}
+
+// commentingSink is implemented by sinks that can write the FASTA comment lines of the input (--keep-comments)
+type commentingSink interface {
+	// comments writes comment lines that follow the last record
+	comments(lines [][]byte) error
+}
+
+// sinkStream is the output stream of the built-in sinks
+type sinkStream struct {
+	w   io.Writer     // Output (counted for the index)
+	buf *bufio.Writer // Buffer under w
+}
+
+func (s sinkStream) Flush() error {
+	if s.buf == nil {
+		return nil
+	}
+	return s.buf.Flush()
+}
+
+// Close leaves the output stream open, as it is owned by the caller
+func (s sinkStream) Close() error { return nil }
+
+// closeSink ends the output: with --keep-partial, a failed output is marked as incomplete
+// before it is flushed and closed. Errors of the sink are only reported if processing succeeded.
+func closeSink(sink OutputSink, cfg Config, err error) error {
+	if err != nil && cfg.KeepPartial {
+		if s, ok := sink.(finishingSink); ok {
+			s.abort(err)
+		}
+	}
+	if flushErr := sink.Flush(); flushErr != nil && err == nil {
+		err = fmt.Errorf("Error writing output: %v", flushErr)
+	}
+	if closeErr := sink.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("Error closing output: %v", closeErr)
+	}
+	return err
+}
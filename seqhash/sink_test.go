@@ -0,0 +1,231 @@
+package seqhash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shenwei356/bio/seq"
+)
+
+// mockSink keeps copies of the records and the order of the calls
+type mockSink struct {
+	records []Record
+	calls   []string
+	failAt  int64 // Index of the record to fail on (-1 for none)
+}
+
+func (m *mockSink) WriteRecord(r Record) error {
+	m.calls = append(m.calls, "write")
+	if r.Index == m.failAt {
+		return errors.New("sink is full")
+	}
+	r.ID = bytes.Clone(r.ID)
+	r.Header = bytes.Clone(r.Header)
+	r.Sequence = bytes.Clone(r.Sequence)
+	r.Quality = bytes.Clone(r.Quality)
+	m.records = append(m.records, r)
+	return nil
+}
+
+func (m *mockSink) Flush() error {
+	m.calls = append(m.calls, "flush")
+	return nil
+}
+
+func (m *mockSink) Close() error {
+	m.calls = append(m.calls, "close")
+	return nil
+}
+
+// Ends of the call sequence, which must be a single Flush followed by Close
+func checkSinkClosed(t *testing.T, m *mockSink) {
+	t.Helper()
+	calls := strings.Join(m.calls, ",")
+	if !strings.HasSuffix(calls, "flush,close") || strings.Count(calls, "flush") != 1 {
+		t.Errorf("Expected Flush and Close once at the end, got calls %s", calls)
+	}
+}
+
+// parsedConfig returns the defaults of the command line with the given options, as passed to Process
+func parsedConfig(t *testing.T, args ...string) Config {
+	t.Helper()
+	cfg, err := ParseArgs(args)
+	if err != nil {
+		t.Fatalf("ParseArgs() error = %v", err)
+	}
+	return cfg
+}
+
+const sinkTestFastq = "@r1 1:N:0:ACGTACGT\nACTG\n+\nIIII\n@r2\nacgtn\n+\nIIII#\n@r3\n\n+\n\n"
+
+func TestCustomSink(t *testing.T) {
+	sink := &mockSink{failAt: -1}
+	cfg := parsedConfig(t, "--hash", "sha1,xxhash", "input.fx")
+	stats, err := Process(context.Background(), strings.NewReader(sinkTestFastq), sink, cfg)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if stats.Records != 3 {
+		t.Errorf("Expected 3 records in stats, got %d", stats.Records)
+	}
+	checkSinkClosed(t, sink)
+
+	if len(sink.records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(sink.records))
+	}
+	r := sink.records[1]
+	if r.Index != 1 || r.File != "input.fx" || string(r.ID) != "r2" || string(r.Sequence) != "ACGTN" || string(r.Quality) != "IIII#" {
+		t.Errorf("Unexpected record: %+v", r)
+	}
+	if strings.Join(r.HashTypes, ",") != "sha1,xxhash" || len(r.Hashes) != 2 || r.Hashes[1] != "db2294387af529a4" {
+		t.Errorf("Unexpected hashes: %v %v", r.HashTypes, r.Hashes)
+	}
+	if !strings.HasPrefix(string(r.Header), "input.fx;") || !strings.HasSuffix(string(r.Header), ";r2") {
+		t.Errorf("Unexpected header: %s", r.Header)
+	}
+}
+
+func TestSinkErrors(t *testing.T) {
+	runTest(t, "Write error aborts with the record index", func(t *testing.T) {
+		sink := &mockSink{failAt: 1}
+		cfg := parsedConfig(t, "input.fx")
+		_, err := Process(context.Background(), strings.NewReader(sinkTestFastq), sink, cfg)
+		var sinkErr *SinkError
+		if !errors.As(err, &sinkErr) || sinkErr.Index != 1 {
+			t.Fatalf("Expected a sink error at record 1, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "sink is full (record index 1)") {
+			t.Errorf("Unexpected error message: %v", err)
+		}
+		if strings.Count(strings.Join(sink.calls, ","), "write") != 2 {
+			t.Errorf("Expected processing to stop after the failed record, got calls %v", sink.calls)
+		}
+		checkSinkClosed(t, sink)
+	})
+
+	runTest(t, "Cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		sink := &mockSink{failAt: -1}
+		_, err := Process(ctx, strings.NewReader(sinkTestFastq), sink, parsedConfig(t))
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected cancellation error, got %v", err)
+		}
+		if len(sink.records) != 0 {
+			t.Errorf("Expected no records after cancellation, got %d", len(sink.records))
+		}
+		checkSinkClosed(t, sink)
+	})
+
+	runTest(t, "Setup error", func(t *testing.T) {
+		sink := &mockSink{failAt: -1}
+		cfg := parsedConfig(t, "--include-id", filepath.Join(t.TempDir(), "missing.txt"))
+		if _, err := Process(context.Background(), strings.NewReader(sinkTestFastq), sink, cfg); err == nil {
+			t.Fatal("Expected an error for a missing ID list")
+		}
+		checkSinkClosed(t, sink)
+	})
+
+	runTest(t, "Index requires a built-in sink", func(t *testing.T) {
+		sink := &mockSink{failAt: -1}
+		cfg := parsedConfig(t, "--index", filepath.Join(t.TempDir(), "out.idx"))
+		_, err := Process(context.Background(), strings.NewReader(sinkTestFastq), sink, cfg)
+		if err == nil || !strings.Contains(err.Error(), "--index") {
+			t.Fatalf("Expected an --index error, got %v", err)
+		}
+		checkSinkClosed(t, sink)
+	})
+}
+
+func TestProcessConfig(t *testing.T) {
+	runTest(t, "Conflicting options", func(t *testing.T) {
+		sink := &mockSink{failAt: -1}
+		cfg := parsedConfig(t)
+		cfg.SizeOut = true
+		_, err := Process(context.Background(), strings.NewReader(sinkTestFastq), sink, cfg)
+		if err == nil || !strings.Contains(err.Error(), "--sizeout requires --dedup") {
+			t.Fatalf("Expected a --sizeout error, got %v", err)
+		}
+		if len(sink.records) != 0 {
+			t.Errorf("Expected no records, got %d", len(sink.records))
+		}
+		checkSinkClosed(t, sink)
+	})
+
+	runTest(t, "Derived options", func(t *testing.T) {
+		// The strata of --stratified-sample and the --nofilename of --id-is-hash are derived as by ParseArgs
+		sink := &mockSink{failAt: -1}
+		cfg := parsedConfig(t, "input.fx")
+		cfg.Dedup, cfg.StratifiedSample, cfg.IDIsHash = true, "rest:1", true
+		stats, err := Process(context.Background(), strings.NewReader(sinkTestFastq), sink, cfg)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		if len(stats.strata) != 1 || len(sink.records) != 3 {
+			t.Fatalf("Expected 3 records in 1 stratum, got %d records in %d strata", len(sink.records), len(stats.strata))
+		}
+		if r := sink.records[0]; r.File != "" || string(r.ID) != r.Hashes[0] {
+			t.Errorf("Expected records named by their hashes only, got %+v", r)
+		}
+	})
+
+	runTest(t, "Sequence bytes", func(t *testing.T) {
+		// Bytes rejected by the validation of the bio package are left to SeqBytes
+		seq.ValidateSeq = true
+		cfg := parsedConfig(t, "--seq-bytes", "any")
+		sink := &mockSink{failAt: -1}
+		if _, err := Process(context.Background(), strings.NewReader(">r1\nAC?G\n"), sink, cfg); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		if len(sink.records) != 1 || string(sink.records[0].Sequence) != "AC?G" {
+			t.Errorf("Unexpected records: %+v", sink.records)
+		}
+	})
+}
+
+// The built-in sinks must keep the output of the writers they replaced (test/sinks)
+func TestBuiltinSinksGolden(t *testing.T) {
+	fasta, err := os.ReadFile("../test/test.fasta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		golden string
+		input  string
+		cfg    Config
+	}{
+		{"fasta", string(fasta), Config{OutFormat: "fasta"}},
+		{"fasta_headers", string(fasta), Config{OutFormat: "fasta", HeadersOnly: true}},
+		{"fastq", sinkTestFastq, Config{OutFormat: "fasta"}},
+		{"json", string(fasta), Config{OutFormat: "json"}},
+		{"json_summary", sinkTestFastq, Config{OutFormat: "json", JsonSummary: true}},
+		{"ndjson", sinkTestFastq, Config{OutFormat: "ndjson"}},
+		{"tsv", sinkTestFastq, Config{OutFormat: "tsv"}},
+		{"csv", string(fasta), Config{OutFormat: "csv", NoFileName: true}},
+	}
+	for _, tt := range tests {
+		runTest(t, tt.golden, func(t *testing.T) {
+			want, err := os.ReadFile(filepath.Join("..", "test", "sinks", tt.golden+".golden"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = bytes.ReplaceAll(want, []byte("{{version}}"), []byte(version))
+
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1", "xxhash"}
+			cfg.InputFileName = "input.fx"
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), output, cfg); err != nil {
+				t.Fatalf("processSequences() error = %v", err)
+			}
+			if !bytes.Equal(output.Bytes(), want) {
+				t.Errorf("Output differs from %s.golden:\n%s\nwant:\n%s", tt.golden, output, want)
+			}
+		})
+	}
+}
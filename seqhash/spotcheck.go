@@ -0,0 +1,82 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// spotChecker verifies every n-th output record (--spot-check): the bytes written
+// for the record (before compression) are parsed back, the header must be the one
+// that was built, and the written sequence must hash to the digests of the record
+type spotChecker struct {
+	every   int64
+	written int64 // Records written so far
+	cfg     Config
+}
+
+// newSpotChecker returns nil if spot checks were not requested
+func newSpotChecker(cfg Config) *spotChecker {
+	if cfg.SpotCheck <= 0 {
+		return nil
+	}
+	return &spotChecker{every: int64(cfg.SpotCheck), cfg: cfg}
+}
+
+// due counts a record, and reports whether it is to be checked
+func (s *spotChecker) due() bool {
+	s.written++
+	return s.written%s.every == 0
+}
+
+// verify checks the captured bytes of a record (at the given index and byte offset of the output)
+// against its header and digests
+func (s *spotChecker) verify(record []byte, index, offset int64, header []byte, hashes []string) error {
+	fail := func(format string, args ...any) error {
+		return fmt.Errorf("Spot check failed (record index %d, byte offset %d, header %q): %s",
+			index, offset, header, fmt.Sprintf(format, args...))
+	}
+
+	if s.cfg.KeepComments {
+		record = withoutInnerComments(record)
+	}
+	lines := bytes.SplitN(record, []byte("\n"), 3)
+	if len(lines) < 3 || len(lines[0]) == 0 || (lines[0][0] != '>' && lines[0][0] != '@') {
+		return fail("the written record is malformed (%q)", truncateSequence(record, 200))
+	}
+	if written := lines[0][1:]; !bytes.Equal(written, header) {
+		return fail("the written header is %q", written)
+	}
+
+	seq := lines[1]
+	if len(seq) == 0 {
+		return nil // Empty sequences have no digests
+	}
+	if s.cfg.Window > 0 {
+		// Windows are written as they were hashed
+		if s.cfg.BothStrands {
+			seq = bothStrands(seq)
+		}
+	} else {
+		if s.cfg.TrimNs {
+			start, end := trimNsRange(seq)
+			seq = seq[start:end]
+		}
+		seq = fingerprint(normalizeSequence(seq, s.cfg), s.cfg)
+	}
+
+	for i, digest := range computeHashes(seq, s.cfg, nil) {
+		expected := hashes[i]
+		if digest == expected || (i == 0 && s.cfg.MinimalUniquePrefix && strings.HasPrefix(digest, expected)) {
+			continue
+		}
+		return fail("the %s digest of the written sequence is %s, but the record was hashed as %s",
+			s.cfg.HashTypes[i], digest, expected)
+	}
+	return nil
+}
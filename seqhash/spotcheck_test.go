@@ -0,0 +1,127 @@
+package seqhash
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpotCheck(t *testing.T) {
+	fasta := randomRecords(20, 300) + ">short\nNNACGTNN\n>empty\n\n>last\nacgtNNacgt\n"
+	fastq := "@r1 1:N:0:ACGT\nNNACGT\n+\nIIIIII\n@r2\nacgt\n+\nIIII\n"
+
+	tests := []struct {
+		name  string
+		input string
+		cfg   Config
+	}{
+		{"FASTA", fasta, Config{}},
+		{"FASTQ", fastq, Config{}},
+		{"Several hash types", fasta, Config{HashTypes: fiveHashTypes}},
+		{"Trimmed Ns, untrimmed output", fasta, Config{TrimNs: true}},
+		{"Trimmed output", fastq, Config{TrimNs: true, EmitTrimmed: true}},
+		{"Ends on both strands", fasta, Config{Ends: 5, BothStrands: true}},
+		{"Windows", fasta, Config{Window: 50, Step: 30, BothStrands: true}},
+		{"Shortest unique prefixes", fasta, Config{MinimalUniquePrefix: true, IDIsHash: true}},
+		{"Case-sensitive, deduplicated", fasta, Config{CaseSensitive: true, Dedup: true, SizeOut: true}},
+		{"Threads", fasta, Config{Threads: 4}},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			if cfg.HashTypes == nil {
+				cfg.HashTypes = []string{"sha1"}
+			}
+			cfg.InputFileName = "test.fasta"
+			expected := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), expected, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg.SpotCheck = 1
+			output := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), output, cfg); err != nil {
+				t.Fatalf("Spot check failed for intact output: %v", err)
+			}
+			if !bytes.Equal(output.Bytes(), expected.Bytes()) {
+				t.Errorf("Spot checks changed the output")
+			}
+		})
+	}
+
+	runTest(t, "Compressed output", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "out.fasta.xz")
+		if _, err := runWithArgs([]string{"seqhasher", "--spot-check", "2", testFastaPath, output}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
+
+// corruptingWriter alters the last byte before the final newline of the n-th write (1-based)
+type corruptingWriter struct {
+	w     io.Writer
+	n     int
+	calls int
+}
+
+func (c *corruptingWriter) Write(p []byte) (int, error) {
+	c.calls++
+	if c.calls == c.n && len(p) > 1 {
+		p = bytes.Clone(p)
+		p[len(p)-2] ^= 0x02 // e.g., A to C
+	}
+	return c.w.Write(p)
+}
+
+func TestSpotCheckDetectsCorruption(t *testing.T) {
+	input := randomRecords(10, 50)
+
+	// Records are corrupted between the sink and the (tapped) output stream
+	run := func(spotCheck, corrupt int) error {
+		cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, SpotCheck: spotCheck}
+		writer := bufio.NewWriter(io.Discard)
+		counter := &countingWriter{w: writer}
+		_, err := processStream(context.Background(), strings.NewReader(input), cfg, counter, func(cfg Config, label string) OutputSink {
+			return newOutputSink(&corruptingWriter{w: counter, n: corrupt}, writer, cfg, label)
+		})
+		return err
+	}
+
+	runTest(t, "Checked record", func(t *testing.T) {
+		err := run(3, 6)
+		if err == nil || !strings.Contains(err.Error(), "Spot check failed (record index 5, byte offset 485") ||
+			!strings.Contains(err.Error(), "the sha1 digest of the written sequence is") {
+			t.Fatalf("Expected a spot check failure at record index 5, got %v", err)
+		}
+	})
+
+	runTest(t, "Unchecked record", func(t *testing.T) {
+		if err := run(3, 5); err != nil {
+			t.Fatalf("Only every third record is checked, got %v", err)
+		}
+	})
+
+	runTest(t, "Corrupted header", func(t *testing.T) {
+		cfg := Config{HashTypes: []string{"sha1"}, SpotCheck: 1}
+		writer := bufio.NewWriter(io.Discard)
+		counter := &countingWriter{w: writer}
+		_, err := processStream(context.Background(), strings.NewReader(">seq1\nACTG\n"), cfg, counter, func(cfg Config, label string) OutputSink {
+			return newOutputSink(&headerCorruptingWriter{w: counter}, writer, cfg, label)
+		})
+		if err == nil || !strings.Contains(err.Error(), "the written header is") {
+			t.Fatalf("Expected a header mismatch, got %v", err)
+		}
+	})
+}
+
+// headerCorruptingWriter replaces the first ';' of each write
+type headerCorruptingWriter struct{ w io.Writer }
+
+func (h *headerCorruptingWriter) Write(p []byte) (int, error) {
+	return h.w.Write(bytes.Replace(p, []byte(";"), []byte(":"), 1))
+}
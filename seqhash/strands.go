@@ -0,0 +1,51 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import "bytes"
+
+// Separator of the two strands in --both-strands fingerprints
+// (not an IUPAC symbol, so the strands can't run into each other)
+const strandSeparator = '|'
+
+// Complements of IUPAC nucleotide codes (in both cases); other bytes are kept as is
+var complements = func() (table [256]byte) {
+	for i := range table {
+		table[i] = byte(i)
+	}
+	for _, pair := range []string{"AT", "CG", "RY", "KM", "BV", "DH", "SS", "WW", "NN"} {
+		for _, p := range []string{pair, string(bytes.ToLower([]byte(pair)))} {
+			table[p[0]], table[p[1]] = p[1], p[0]
+		}
+	}
+	table['U'], table['u'] = 'A', 'a'
+	return table
+}()
+
+func reverseComplement(seq []byte) []byte {
+	rc := make([]byte, len(seq))
+	for i, c := range seq {
+		rc[len(seq)-1-i] = complements[c]
+	}
+	return rc
+}
+
+// bothStrands returns the sequence and its reverse complement, in lexicographic
+// order and joined with the separator (--both-strands). Unlike a canonical strand,
+// the fingerprint keeps both strands, and it is the same for either orientation.
+func bothStrands(seq []byte) []byte {
+	if len(seq) == 0 {
+		return seq // Empty sequences keep their sentinel hashes
+	}
+	first, second := seq, reverseComplement(seq)
+	if bytes.Compare(second, first) < 0 {
+		first, second = second, first
+	}
+	joined := make([]byte, 0, 2*len(seq)+1)
+	joined = append(joined, first...)
+	joined = append(joined, strandSeparator)
+	return append(joined, second...)
+}
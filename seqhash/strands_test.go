@@ -0,0 +1,52 @@
+package seqhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReverseComplement(t *testing.T) {
+	tests := []struct{ seq, want string }{
+		{"ACTG", "CAGT"},
+		{"AACG", "CGTT"},
+		{"acgu", "acgt"},
+		{"RYKMBVDHSWN-", "-NWSDHBVKMRY"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := string(reverseComplement([]byte(tt.seq))); got != tt.want {
+			t.Errorf("reverseComplement(%q) = %q, want %q", tt.seq, got, tt.want)
+		}
+	}
+}
+
+func TestBothStrands(t *testing.T) {
+	hashTypes := []string{"sha1", "xxhash", "blake3"}
+	hashes := func(input string, both bool) []string {
+		cfg := Config{HashTypes: hashTypes, NoFileName: true, HeadersOnly: true, BothStrands: both}
+		output := &bytes.Buffer{}
+		if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		return strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	}
+
+	forward := hashes(">s\nACTG\n", true)
+	reverse := hashes(">s\nCAGT\n", true)
+	forwardOnly := hashes(">s\nACTG\n", false)
+	if forward[0] != reverse[0] {
+		t.Errorf("Both-strands hashes differ between orientations:\n%s\n%s", forward[0], reverse[0])
+	}
+	if forward[0] == forwardOnly[0] {
+		t.Errorf("Both-strands hash equals the forward-only hash: %s", forward[0])
+	}
+	if want := getHashFunc("sha1")([]byte("ACTG|CAGT")); !strings.HasPrefix(forward[0], want+";") {
+		t.Errorf("Got %s, want the sha1 of ACTG|CAGT (%s)", forward[0], want)
+	}
+
+	// Lowercase input is normalized before the reverse complement
+	if lower := hashes(">s\ncagt\n", true); lower[0] != forward[0] {
+		t.Errorf("Lowercase reverse complement hashed differently: %s", lower[0])
+	}
+}
@@ -0,0 +1,61 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// isTerminal reports whether the file is an interactive terminal
+// (a variable, so that tests can simulate a TTY)
+var isTerminal = func(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// colorEnabled reports whether ANSI escape sequences may be written to w.
+// Colors are used only for terminals that are not "dumb" and when NO_COLOR is not set,
+// so redirected output and minimal containers always get plain text.
+func colorEnabled(w io.Writer) bool {
+	if _, set := os.LookupEnv("NO_COLOR"); set {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// palette colorizes text for a particular writer (or leaves it as is)
+type palette struct {
+	enabled bool
+}
+
+func newPalette(w io.Writer) palette {
+	return palette{enabled: colorEnabled(w)}
+}
+
+func (p palette) paint(attr color.Attribute, text string) string {
+	c := color.New(attr)
+	if p.enabled {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	return c.Sprint(text)
+}
+
+func (p palette) HiGreen(text string) string   { return p.paint(color.FgHiGreen, text) }
+func (p palette) HiCyan(text string) string    { return p.paint(color.FgHiCyan, text) }
+func (p palette) HiMagenta(text string) string { return p.paint(color.FgHiMagenta, text) }
+func (p palette) White(text string) string     { return p.paint(color.FgWhite, text) }
@@ -0,0 +1,77 @@
+package seqhash
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// usageThroughPipe runs printUsage (detailed help) writing to a pipe and returns the output
+func usageThroughPipe(t *testing.T) string {
+	t.Helper()
+
+	oldArgs := os.Args
+	os.Args = []string{"seqhasher", "--help"}
+	defer func() { os.Args = oldArgs }()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		r.Close()
+		done <- buf.String()
+	}()
+
+	printUsage(w)
+	w.Close()
+	return <-done
+}
+
+func TestUsageColorDegradation(t *testing.T) {
+	tests := []struct {
+		name       string
+		term       string
+		tty        bool
+		wantEscape bool
+	}{
+		{"Pipe", "xterm-256color", false, false},
+		{"Dumb terminal", "dumb", true, false},
+		{"Simulated TTY", "xterm-256color", true, true},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			oldIsTerminal := isTerminal
+			isTerminal = func(*os.File) bool { return tt.tty }
+			defer func() { isTerminal = oldIsTerminal }()
+
+			t.Setenv("TERM", tt.term)
+			t.Setenv("NO_COLOR", "") // restored after the test
+			os.Unsetenv("NO_COLOR")
+
+			output := usageThroughPipe(t)
+			if !strings.Contains(output, "DNA Sequence Hashing Tool") {
+				t.Fatalf("Unexpected usage output:\n%s", output)
+			}
+			if got := strings.Contains(output, "\x1b["); got != tt.wantEscape {
+				t.Errorf("Escape sequences present = %v, want %v\nGot:\n%q", got, tt.wantEscape, output)
+			}
+		})
+	}
+}
+
+func TestColorEnabledNonFileWriter(t *testing.T) {
+	oldIsTerminal := isTerminal
+	isTerminal = func(*os.File) bool { return true }
+	defer func() { isTerminal = oldIsTerminal }()
+
+	if colorEnabled(&bytes.Buffer{}) {
+		t.Error("Expected colors to be disabled for a non-file writer")
+	}
+}
@@ -0,0 +1,148 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"io"
+	"sync"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// Records of at least this many bases are hashed in the big-record lane (--threads)
+const defaultBigRecordThreshold = 1 << 20
+
+// Records that may be hashed ahead of the oldest unfinished one, per thread
+const reorderWindowPerThread = 64
+
+// prepareQueued prepares the records of the pool (a variable, so that tests can hold records back)
+var prepareQueued = prepareRecord
+
+// Record being hashed by the pool
+type pendingRecord struct {
+	record   *fastx.Record
+	isFastq  bool
+	prepared preparedRecord
+	done     chan struct{} // Closed when the record is prepared
+}
+
+// recordPool hashes records concurrently (--threads) and returns them in input order.
+//
+// Records are read by a single goroutine and queued in input order into a bounded
+// reorder buffer, which the caller drains. Normal records are prepared by a pool of
+// workers; records of at least --big-record-threshold bases go to a separate lane,
+// whose workers also spread the hash types of a record over the CPUs (see hashFanout).
+// Both lanes can hold the whole reorder buffer, so the reader only ever waits for
+// the caller, never for a busy lane: a few huge records occupy the big-record lane only,
+// while the pool keeps hashing the records that follow them (up to the size of the reorder buffer).
+// The digests of a record are always computed by the regular algorithms,
+// so the output is byte-identical to a single-threaded run.
+type recordPool struct {
+	ordered  chan *pendingRecord
+	readErr  error // Set before ordered is closed
+	stop     chan struct{}
+	stopped  sync.Once
+	reading  sync.WaitGroup
+	watchdog *watchdogReader // Restarted after each record (--record-timeout)
+}
+
+func newRecordPool(reader *fastx.Reader, cfg Config) *recordPool {
+	window := reorderWindowPerThread * cfg.Threads
+	p := &recordPool{
+		ordered:  make(chan *pendingRecord, window),
+		stop:     make(chan struct{}),
+		watchdog: cfg.watchdog,
+	}
+	// A queued record is in the reorder buffer, or is the one the caller waits for
+	small := make(chan *pendingRecord, window+1)
+	big := make(chan *pendingRecord, window+1)
+
+	for i := 0; i < cfg.Threads; i++ {
+		go p.work(small, cfg, nil)
+	}
+
+	var bigWorkers sync.WaitGroup
+	fanout := newHashFanout(cfg, 1)
+	for i := 0; i < max(1, cfg.Threads/4); i++ {
+		bigWorkers.Add(1)
+		go func() {
+			defer bigWorkers.Done()
+			p.work(big, cfg, fanout)
+		}()
+	}
+	go func() {
+		bigWorkers.Wait()
+		fanout.Close()
+	}()
+
+	p.reading.Add(1)
+	go p.read(reader, cfg.BigRecordThreshold, small, big)
+	return p
+}
+
+// read queues the records in input order and dispatches them to the workers
+func (p *recordPool) read(reader *fastx.Reader, threshold int, small, big chan *pendingRecord) {
+	defer p.reading.Done()
+	defer close(p.ordered)
+	defer close(big)
+	defer close(small)
+
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			p.readErr = err
+			return
+		}
+		p.watchdog.recordRead()
+		// The reader reuses its record
+		r := &pendingRecord{record: record.Clone(), isFastq: reader.IsFastq, done: make(chan struct{})}
+
+		lane := small
+		if threshold > 0 && len(r.record.Seq.Seq) >= threshold {
+			lane = big
+		}
+		select {
+		case p.ordered <- r:
+		case <-p.stop:
+			return
+		}
+		select {
+		case lane <- r:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *recordPool) work(jobs chan *pendingRecord, cfg Config, fanout *hashFanout) {
+	for r := range jobs {
+		select {
+		case <-p.stop: // Nobody waits for the record anymore
+		default:
+			r.prepared = prepareQueued(r.record, r.isFastq, cfg, fanout)
+		}
+		close(r.done)
+	}
+}
+
+// next returns the next record in input order, or the error that stopped reading (io.EOF at the end)
+func (p *recordPool) next() (*fastx.Record, preparedRecord, error) {
+	r, ok := <-p.ordered
+	if !ok {
+		if p.readErr == nil {
+			return nil, preparedRecord{}, io.EOF
+		}
+		return nil, preparedRecord{}, p.readErr
+	}
+	<-r.done
+	return r.record, r.prepared, nil
+}
+
+// Close stops the pool and waits until the input is no longer read
+func (p *recordPool) Close() {
+	p.stopped.Do(func() { close(p.stop) })
+	p.reading.Wait()
+}
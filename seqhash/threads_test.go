@@ -0,0 +1,225 @@
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// skewedRecords returns many short reads interleaved with a few huge records
+func skewedRecords(reads, readLength, contigs, contigLength int) string {
+	var b strings.Builder
+	short := strings.Split(randomRecords(reads, readLength), "\n")
+	long := strings.Split(randomRecords(contigs, contigLength), "\n")
+	for i := 0; i < reads; i++ {
+		if contigs > 0 && i%(reads/contigs) == 0 && i/(reads/contigs) < contigs {
+			j := i / (reads / contigs)
+			b.WriteString(">contig\n" + long[2*j+1] + "\n")
+		}
+		b.WriteString(">read\n" + short[2*i+1] + "\n")
+	}
+	return b.String()
+}
+
+func TestThreadsMatchSequential(t *testing.T) {
+	fasta := skewedRecords(500, 150, 3, 20000) + ">empty\n\n>last\nNNacgtNN\n"
+	fastq := "@r1 1:N:0:ACGT\nNNACGT\n+\nIIIIII\n@r2\nacgt\n+\nIIII\n@r3\nACGTACGTAC\n+\nIIIIIIIIII\n"
+
+	tests := []struct {
+		name  string
+		input string
+		cfg   Config
+	}{
+		{"FASTA", fasta, Config{}},
+		{"FASTA, several hash types", fasta, Config{HashTypes: fiveHashTypes}},
+		{"FASTA, trimmed and deduplicated", fasta, Config{TrimNs: true, EmitTrimmed: true, Dedup: true}},
+		{"FASTQ", fastq, Config{TrimNs: true, EmitTrimmed: true}},
+		{"JSON", fasta, Config{OutFormat: "json", JsonSummary: true}},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			if cfg.HashTypes == nil {
+				cfg.HashTypes = []string{"sha1"}
+			}
+			cfg.InputFileName = "test.fasta"
+			cfg.Threads = 1
+			expected := &bytes.Buffer{}
+			if err := processSequences(strings.NewReader(tt.input), expected, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, threshold := range []int{0, 1000, 1} {
+				cfg.Threads = 4
+				cfg.BigRecordThreshold = threshold
+				cfg.FanoutThreshold = 100
+				output := &bytes.Buffer{}
+				if err := processSequences(strings.NewReader(tt.input), output, cfg); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(output.Bytes(), expected.Bytes()) {
+					t.Errorf("Output with 4 threads (big record threshold %d) differs from the single-threaded output", threshold)
+				}
+			}
+		})
+	}
+}
+
+// interleavedDuplicates returns records with unique IDs, whose sequences are drawn
+// from a few distinct ones (including big records), so that duplicates are spread across the input
+func interleavedDuplicates(n int) (string, []string) {
+	pool := strings.Split(randomRecords(5, 40)+randomRecords(2, 5000), "\n")
+	pool = append(pool, ">seq", "NNACGTNN", ">seq", "ACGTRYACGT")
+	rng := rand.New(rand.NewSource(7))
+	var b strings.Builder
+	var representatives []string // IDs of the first record of each sequence
+	seen := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		seq := pool[2*rng.Intn(len(pool)/2)+1]
+		id := fmt.Sprintf("r%d", i)
+		if !seen[seq] {
+			seen[seq] = true
+			representatives = append(representatives, id)
+		}
+		b.WriteString(">" + id + "\n" + seq + "\n")
+	}
+	return b.String(), representatives
+}
+
+func TestDedupRepresentativesAcrossThreads(t *testing.T) {
+	input, representatives := interleavedDuplicates(2000)
+
+	for _, cfg := range []Config{
+		{Dedup: true},
+		{Dedup: true, SizeOut: true},
+		{NWildcardDedup: true, TrimNs: true},
+	} {
+		cfg.HashTypes = []string{"sha1", "xxhash"}
+		cfg.NoFileName = true
+		cfg.FanoutThreshold = 100
+		var expected []byte
+		for _, threads := range []int{1, 4, 16} {
+			for _, threshold := range []int{0, 1000} {
+				cfg.Threads = threads
+				cfg.BigRecordThreshold = threshold
+				output := &bytes.Buffer{}
+				if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+					t.Fatal(err)
+				}
+				if expected == nil {
+					expected = output.Bytes()
+					continue
+				}
+				if !bytes.Equal(output.Bytes(), expected) {
+					t.Errorf("Output with %d threads (big record threshold %d) differs from the single-threaded output", threads, threshold)
+				}
+			}
+		}
+
+		// Representatives are the first records of their sequences in the input
+		var ids []string
+		for _, line := range strings.Split(string(expected), "\n") {
+			if strings.HasPrefix(line, ">") {
+				fields := strings.Split(line, ";")
+				ids = append(ids, fields[2])
+			}
+		}
+		if strings.Join(ids, ",") != strings.Join(representatives, ",") {
+			t.Errorf("Got representatives %v, want %v", ids, representatives)
+		}
+	}
+}
+
+func TestThreadsReadError(t *testing.T) {
+	input := "@r1\nACTG\n+\nIIII\n@r2\nACTG\n+\nIIII\n@broken\nACTG\n+\nII\n"
+	cfg := Config{HashTypes: []string{"sha1"}, Threads: 1}
+	sequentialErr := processSequences(strings.NewReader(input), &bytes.Buffer{}, cfg)
+
+	cfg.Threads = 3
+	err := processSequences(strings.NewReader(input), &bytes.Buffer{}, cfg)
+	if sequentialErr == nil || err == nil || err.Error() != sequentialErr.Error() {
+		t.Errorf("Expected the same error as a single-threaded run (%v), got %v", sequentialErr, err)
+	}
+}
+
+func TestThreadsBigRecordsDoNotBlockPool(t *testing.T) {
+	// With 4 threads, a single worker hashes the big records; while it is held back,
+	// the records queued behind it must not keep the pool from the short reads that follow
+	release := make(chan struct{})
+	var short atomic.Int64
+	prepareQueued = func(record *fastx.Record, isFastq bool, cfg Config, fanout *hashFanout) preparedRecord {
+		if len(record.Seq.Seq) >= cfg.BigRecordThreshold {
+			<-release
+		} else {
+			short.Add(1)
+		}
+		return prepareRecord(record, isFastq, cfg, fanout)
+	}
+	defer func() { prepareQueued = prepareRecord }()
+
+	const reads = 100
+	input := skewedRecords(reads, 50, 0, 0)
+	input = strings.Repeat(">contig\n"+strings.Repeat("ACGT", 250)+"\n", 3) + input
+
+	cfg := Config{HashTypes: []string{"sha1"}, Threads: 4, BigRecordThreshold: 1000}
+	done := make(chan error, 1)
+	go func() { done <- processSequences(strings.NewReader(input), &bytes.Buffer{}, cfg) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for short.Load() < reads && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := short.Load(); got != reads {
+		t.Errorf("Hashed %d of %d short reads while the big-record lane was busy", got, reads)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThreadsInterrupted(t *testing.T) {
+	interrupted.Store(true)
+	defer interrupted.Store(false)
+
+	cfg := Config{HashTypes: []string{"sha1"}, Threads: 4}
+	err := processSequences(strings.NewReader(skewedRecords(1000, 100, 0, 0)), &bytes.Buffer{}, cfg)
+	if err != errInterrupted {
+		t.Errorf("Expected an interruption, got %v", err)
+	}
+}
+
+// Short reads mixed with a few huge contigs, hashed with the plain pool
+// (every record in the same pool) and with a separate lane for big records
+func BenchmarkSkewedRecords(b *testing.B) {
+	input := skewedRecords(20000, 300, 4, 4<<20)
+	b.SetBytes(int64(len(input)))
+
+	for _, bench := range []struct {
+		name      string
+		threads   int
+		threshold int
+	}{
+		{"Sequential", 1, 0},
+		{"Pool", 4, 0},
+		{"PoolWithBigRecordLane", 4, defaultBigRecordThreshold},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			cfg := Config{HashTypes: []string{"sha1", "blake3"}, HeadersOnly: true,
+				Threads: bench.threads, BigRecordThreshold: bench.threshold, FanoutThreshold: defaultFanoutThreshold}
+			for i := 0; i < b.N; i++ {
+				if err := processSequences(strings.NewReader(input), io.Discard, cfg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
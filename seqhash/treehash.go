@@ -0,0 +1,91 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Hash types of --tree-hash (cryptographic, so that chunk digests are evidence of the chunk contents)
+var treeHashTypes = []string{"blake3", "sha1", "sha3", "md5"}
+
+// treeChunk is a chunk of the hashed bytes (1-based, inclusive range) and its digest
+type treeChunk struct {
+	start, end int
+	digest     string
+}
+
+// treeHashes splits the data into chunks of --tree-hash bytes (the last chunk may be shorter)
+// and returns, for each hash type, the root digest: the digest of the concatenated hex digests
+// of the chunks. The chunks of the first hash type are returned for the sidecar.
+func treeHashes(data []byte, cfg Config) ([]string, []treeChunk) {
+	roots := make([]string, 0, len(cfg.HashTypes))
+	if len(data) == 0 {
+		for _, hashType := range cfg.HashTypes {
+			roots = append(roots, getHashFunc(hashType)(data))
+		}
+		return roots, nil
+	}
+
+	var chunks []treeChunk
+	for i, hashType := range cfg.HashTypes {
+		hashFunc := getHashFunc(hashType)
+		var digests strings.Builder
+		for start := 0; start < len(data); start += cfg.TreeChunk {
+			end := min(start+cfg.TreeChunk, len(data))
+			digest := hashFunc(data[start:end])
+			digests.WriteString(digest)
+			if i == 0 {
+				chunks = append(chunks, treeChunk{start: start + 1, end: end, digest: digest})
+			}
+		}
+		roots = append(roots, hashFunc([]byte(digests.String())))
+	}
+	return roots, chunks
+}
+
+// treeChunkWriter writes the --tree-hash-chunks sidecar: a TSV with the number of the hashed
+// record (1-based), its ID, and the number (1-based), byte range, and digest of each chunk
+type treeChunkWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func newTreeChunkWriter(fileName string) (*treeChunkWriter, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	tw := &treeChunkWriter{file: file, writer: bufio.NewWriter(file)}
+	_, err = fmt.Fprintln(tw.writer, "record\tid\tchunk\tstart\tend\tdigest")
+	return tw, err
+}
+
+// add writes the chunks of a record
+func (tw *treeChunkWriter) add(record int64, id []byte, chunks []treeChunk) error {
+	for i, c := range chunks {
+		if _, err := fmt.Fprintf(tw.writer, "%d\t%s\t%d\t%d\t%d\t%s\n", record, id, i+1, c.start, c.end, c.digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes the sidecar and closes the file (subsequent calls are no-ops)
+func (tw *treeChunkWriter) Close() error {
+	if tw.file == nil {
+		return nil
+	}
+	err := tw.writer.Flush()
+	if cerr := tw.file.Close(); err == nil {
+		err = cerr
+	}
+	tw.file = nil
+	return err
+}
@@ -0,0 +1,109 @@
+package seqhash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTreeHashes(t *testing.T) {
+	blake3 := getHashFunc("blake3")
+	md5 := getHashFunc("md5")
+	cfg := Config{HashTypes: []string{"blake3", "md5"}, TreeChunk: 4}
+
+	roots, chunks := treeHashes([]byte("ACGTACGTAC"), cfg)
+	expectedChunks := []treeChunk{
+		{1, 4, blake3([]byte("ACGT"))},
+		{5, 8, blake3([]byte("ACGT"))},
+		{9, 10, blake3([]byte("AC"))}, // The last chunk is hashed as-is
+	}
+	if len(chunks) != len(expectedChunks) {
+		t.Fatalf("Got chunks %v, want %v", chunks, expectedChunks)
+	}
+	for i, c := range chunks {
+		if c != expectedChunks[i] {
+			t.Errorf("Chunk %d = %v, want %v", i+1, c, expectedChunks[i])
+		}
+	}
+
+	expectedRoots := []string{
+		blake3([]byte(blake3([]byte("ACGT")) + blake3([]byte("ACGT")) + blake3([]byte("AC")))),
+		md5([]byte(md5([]byte("ACGT")) + md5([]byte("ACGT")) + md5([]byte("AC")))),
+	}
+	if strings.Join(roots, ",") != strings.Join(expectedRoots, ",") {
+		t.Errorf("Got roots %v, want %v", roots, expectedRoots)
+	}
+
+	// A single chunk is still hashed twice, so roots never equal plain digests
+	if roots, _ := treeHashes([]byte("ACG"), cfg); roots[0] != blake3([]byte(blake3([]byte("ACG")))) {
+		t.Errorf("Unexpected root of a single chunk: %s", roots[0])
+	}
+	if roots, chunks := treeHashes(nil, cfg); roots[0] != "" || chunks != nil {
+		t.Errorf("Expected no digests for an empty sequence, got %v, %v", roots, chunks)
+	}
+}
+
+// The sidecar of a record with one corrupted base pinpoints the chunk of that base
+func TestTreeHashLocalizesCorruption(t *testing.T) {
+	input := randomRecords(1, 100000)
+	position := 45678 // 1-based, in chunk 46 (45001-46000)
+	seqStart := strings.Index(input, "\n") + 1
+	base := input[seqStart+position-1]
+	replacement := byte('A')
+	if base == 'A' {
+		replacement = 'C'
+	}
+	corrupted := input[:seqStart+position-1] + string(replacement) + input[seqStart+position:]
+
+	run := func(input string) (string, []string) {
+		sidecar := filepath.Join(t.TempDir(), "chunks.tsv")
+		cfg := Config{HashTypes: []string{"blake3"}, NoFileName: true, HeadersOnly: true, TreeChunk: 1000, TreeChunksFile: sidecar}
+		output := &bytes.Buffer{}
+		if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		data, err := os.ReadFile(sidecar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output.String(), strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+
+	header, chunks := run(input)
+	corruptedHeader, corruptedChunks := run(corrupted)
+	if header == corruptedHeader {
+		t.Error("Expected the root digest to change with the corrupted base")
+	}
+	if len(chunks) != 101 || chunks[0] != "record\tid\tchunk\tstart\tend\tdigest" {
+		t.Fatalf("Expected a header line and 100 chunks, got %d lines starting with %q", len(chunks), chunks[0])
+	}
+
+	var differing []string
+	for i := range chunks {
+		if chunks[i] != corruptedChunks[i] {
+			differing = append(differing, corruptedChunks[i])
+		}
+	}
+	if len(differing) != 1 || !strings.HasPrefix(differing[0], "1\tseq\t46\t45001\t46000\t") {
+		t.Errorf("Expected only chunk 46 (45001-46000) to differ, got %q", differing)
+	}
+}
+
+func TestTreeHashConsistency(t *testing.T) {
+	input := randomRecords(5, 3000) + ">empty\n\n"
+	for _, cfg := range []Config{
+		{Threads: 3},
+		{Dedup: true},
+		{MinimalUniquePrefix: true},
+		{SpotCheck: 1},
+	} {
+		cfg.HashTypes = []string{"sha1"}
+		cfg.NoFileName = true
+		cfg.TreeChunk = 512
+		if err := processSequences(strings.NewReader(input), &bytes.Buffer{}, cfg); err != nil {
+			t.Errorf("processSequences(%+v) error = %v", cfg, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// uniquePrefixes returns the length of the shortest prefix of each primary digest
+// that is unique among the digests of the input (--minimal-unique-prefix).
+// The input is read in full first, so it is spooled to a temporary file (in --tmp-dir),
+// which is returned (rewound) for the second pass; the caller must close and remove it.
+func uniquePrefixes(input io.Reader, cfg Config) (map[string]int, *os.File, error) {
+	spool, err := os.CreateTemp(cfg.TmpDir, "seqhasher-spool-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error creating temporary file: %v", err)
+	}
+	// Removed on errors and panics; otherwise, the spool is handed over to the caller
+	handedOver := false
+	defer func() {
+		if !handedOver {
+			spool.Close()
+			os.Remove(spool.Name())
+		}
+	}()
+
+	digests, err := primaryDigests(io.TeeReader(input, spool), cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, fmt.Errorf("Error reading temporary file: %v", err)
+	}
+	handedOver = true
+	return prefixLengths(digests), spool, nil
+}
+
+// primaryDigests returns the distinct digests of the first hash type
+func primaryDigests(input io.Reader, cfg Config) ([]string, error) {
+	// Drain the input, so that the spool receives all of it
+	defer io.Copy(io.Discard, input)
+	if cfg.KeepComments {
+		input = newCommentFilter(input) // The comments are taken in the second pass
+	}
+
+	reader, err := newFastxReader(input)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create reader: %v", err)
+	}
+	if reader == nil {
+		return nil, nil
+	}
+	defer reader.Close()
+
+	first := cfg
+	first.HashTypes = cfg.HashTypes[:1]
+	normalize := cfg
+	normalize.HashTypes = nil // Empty sequences are reported in the second pass
+	seen := make(map[string]struct{})
+	var digests []string
+	for {
+		if interrupted.Load() {
+			return nil, errInterrupted
+		}
+		record, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("Error reading record: %v", err)
+		}
+		cfg.watchdog.recordRead()
+		for _, unit := range prepareRecord(record, reader.IsFastq, normalize, nil).units(record, cfg.Window > 0) {
+			if len(unit.seq) == 0 {
+				continue
+			}
+			// Hashed as in the second pass (e.g., with --length-prefix or --tree-hash)
+			digest := computeHashes(unit.seq, first, nil)[0]
+			if _, ok := seen[digest]; !ok && digest != "" {
+				seen[digest] = struct{}{}
+				digests = append(digests, digest)
+			}
+		}
+	}
+	return digests, nil
+}
+
+// prefixLengths assigns each digest the length of its shortest unique prefix:
+// one character more than the longest prefix shared with its neighbors in sorted order
+func prefixLengths(digests []string) map[string]int {
+	sort.Strings(digests)
+	lengths := make(map[string]int, len(digests))
+	for i, digest := range digests {
+		shared := 0
+		if i > 0 {
+			shared = commonPrefixLength(digest, digests[i-1])
+		}
+		if i < len(digests)-1 {
+			shared = max(shared, commonPrefixLength(digest, digests[i+1]))
+		}
+		lengths[digest] = min(shared+1, len(digest))
+	}
+	return lengths
+}
+
+func commonPrefixLength(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
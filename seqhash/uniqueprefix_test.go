@@ -0,0 +1,151 @@
+package seqhash
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMinimalUniquePrefix(t *testing.T) {
+	input := testSequences + randomRecords(300, 30) + ">empty\n\n"
+	cfg := Config{HashTypes: []string{"sha1", "md5"}, NoFileName: true, HeadersOnly: true, MinimalUniquePrefix: true}
+	output := &bytes.Buffer{}
+	if err := processSequences(strings.NewReader(input), output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+
+	// Distinct sequences and their full digests
+	sequences := make(map[string]string)
+	full := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(input), "\n") {
+		if !strings.HasPrefix(line, ">") {
+			seq := strings.ToUpper(line)
+			if seq != "" {
+				digest := getHashFunc("sha1")([]byte(seq))
+				sequences[seq] = digest
+				full[digest] = true
+			}
+		}
+	}
+
+	prefixOf := make(map[string]string) // Full digest -> assigned prefix
+	headers := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	for i, line := range strings.Split(strings.TrimSpace(input), "\n") {
+		if i%2 == 1 && line != "" {
+			header := headers[i/2]
+			prefix := strings.SplitN(header, ";", 2)[0]
+			digest := sequences[strings.ToUpper(line)]
+			if !strings.HasPrefix(digest, prefix) {
+				t.Fatalf("Prefix %q does not match the digest %s", prefix, digest)
+			}
+			if md5 := strings.Split(header, ";")[1]; md5 != getHashFunc("md5")([]byte(strings.ToUpper(line))) {
+				t.Errorf("Expected the second hash type to be complete, got %q", md5)
+			}
+			if p, ok := prefixOf[digest]; ok && p != prefix {
+				t.Errorf("Identical sequences got different prefixes: %q and %q", p, prefix)
+			}
+			prefixOf[digest] = prefix
+		}
+	}
+
+	assigned := make(map[string]bool)
+	for digest, prefix := range prefixOf {
+		if assigned[prefix] {
+			t.Errorf("Prefix %q is assigned to several sequences", prefix)
+		}
+		assigned[prefix] = true
+
+		// One character less must be shared with another digest
+		shorter := prefix[:len(prefix)-1]
+		shared := false
+		for other := range full {
+			if other != digest && strings.HasPrefix(other, shorter) {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			t.Errorf("Prefix %q of %s is not minimal", prefix, digest)
+		}
+	}
+	if len(prefixOf) != len(full) {
+		t.Errorf("Expected %d prefixes, got %d", len(full), len(prefixOf))
+	}
+}
+
+func TestPrefixLengths(t *testing.T) {
+	lengths := prefixLengths([]string{"abcd", "abef", "b123", "abcf"})
+	expected := map[string]int{"abcd": 4, "abcf": 4, "abef": 3, "b123": 1}
+	for digest, n := range expected {
+		if lengths[digest] != n {
+			t.Errorf("prefixLengths()[%q] = %d, want %d", digest, lengths[digest], n)
+		}
+	}
+}
+
+// The spool must be removed from --tmp-dir however the run ends
+func TestSpoolCleanup(t *testing.T) {
+	input := testSequences + randomRecords(50, 30)
+	checkEmpty := func(t *testing.T, dir string) {
+		t.Helper()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			t.Errorf("Temporary file left behind: %s", e.Name())
+		}
+	}
+	newConfig := func(t *testing.T) Config {
+		return parsedConfig(t, "--minimal-unique-prefix", "--tmp-dir", t.TempDir())
+	}
+
+	runTest(t, "Success", func(t *testing.T) {
+		cfg := newConfig(t)
+		if err := processSequences(strings.NewReader(input), io.Discard, cfg); err != nil {
+			t.Fatalf("processSequences() error = %v", err)
+		}
+		checkEmpty(t, cfg.TmpDir)
+	})
+
+	runTest(t, "Error in the second pass", func(t *testing.T) {
+		cfg := newConfig(t)
+		_, err := Process(context.Background(), strings.NewReader(input), &mockSink{failAt: 2}, cfg)
+		if err == nil {
+			t.Fatal("Expected a sink error")
+		}
+		checkEmpty(t, cfg.TmpDir)
+	})
+
+	runTest(t, "Interrupted", func(t *testing.T) {
+		interrupted.Store(true)
+		defer interrupted.Store(false)
+		cfg := newConfig(t)
+		if err := processSequences(strings.NewReader(input), io.Discard, cfg); err != errInterrupted {
+			t.Fatalf("Expected interruption error, got %v", err)
+		}
+		checkEmpty(t, cfg.TmpDir)
+	})
+
+	runTest(t, "Panic", func(t *testing.T) {
+		cfg := newConfig(t)
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			Process(context.Background(), strings.NewReader(input), panickingSink{}, cfg)
+		}()
+		checkEmpty(t, cfg.TmpDir)
+	})
+}
+
+type panickingSink struct{}
+
+func (panickingSink) WriteRecord(Record) error { panic("sink failure") }
+func (panickingSink) Flush() error             { return nil }
+func (panickingSink) Close() error             { return nil }
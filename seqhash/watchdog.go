@@ -0,0 +1,157 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Size of the chunks read from the input by the watchdog
+const watchdogChunkSize = 64 << 10
+
+// Chunk of the input, or the error that ended it
+type watchdogChunk struct {
+	data []byte
+	err  error
+}
+
+// watchdogReader fails the run if no new record arrives within the timeout (--record-timeout),
+// so that a stalled pipe ends the run instead of hanging it.
+// The timeout covers the time spent waiting for input since the last complete record
+// (reported by the record loop with recordRead), so a pipe that dribbles bytes
+// without completing a record fails as well; time spent processing records does not count.
+//
+// A blocked Read can't be interrupted, so the input is read by a separate goroutine
+// and handed over in chunks. After a timeout, that goroutine stays blocked until
+// the input delivers or closes; the FASTA/FASTQ reader above only sees the error,
+// and is closed as usual.
+// Read and recordRead are called by the goroutine that reads the records.
+type watchdogReader struct {
+	timeout time.Duration
+	left    time.Duration // Waiting time left for the current record
+	chunks  chan watchdogChunk
+	stop    chan struct{}
+	stopped sync.Once
+	pending []byte // Rest of the last chunk
+	err     error  // Sticky: the input is not read after an error or a timeout
+}
+
+func newWatchdogReader(input io.Reader, timeout time.Duration) *watchdogReader {
+	w := &watchdogReader{
+		timeout: timeout,
+		left:    timeout,
+		chunks:  make(chan watchdogChunk),
+		stop:    make(chan struct{}),
+	}
+	go w.pump(input)
+	return w
+}
+
+func (w *watchdogReader) pump(input io.Reader) {
+	for {
+		buf := make([]byte, watchdogChunkSize)
+		n, err := input.Read(buf)
+		if n == 0 && err == nil {
+			continue
+		}
+		select {
+		case w.chunks <- watchdogChunk{data: buf[:n], err: err}:
+		case <-w.stop:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (w *watchdogReader) Read(p []byte) (int, error) {
+	if len(w.pending) == 0 && w.err == nil {
+		select {
+		case chunk := <-w.chunks: // Input that is ready is taken even if the time is up
+			w.pending, w.err = chunk.data, chunk.err
+		default:
+			start := time.Now()
+			timer := time.NewTimer(max(w.left, 0))
+			select {
+			case chunk := <-w.chunks:
+				w.pending, w.err = chunk.data, chunk.err
+			case <-timer.C:
+				w.err = fmt.Errorf("no new record received within %v (--record-timeout)", w.timeout)
+			}
+			timer.Stop()
+			w.left -= time.Since(start)
+		}
+	}
+	if len(w.pending) > 0 {
+		n := copy(p, w.pending)
+		w.pending = w.pending[n:]
+		return n, nil
+	}
+	return 0, w.err
+}
+
+// recordRead restarts the timeout after a complete record; it does nothing without a watchdog
+func (w *watchdogReader) recordRead() {
+	if w != nil {
+		w.left = w.timeout
+	}
+}
+
+// Result of opening a file
+type openedFile struct {
+	file *os.File
+	err  error
+}
+
+// openWatched opens a file, failing after the timeout (if positive):
+// opening a named pipe (FIFO) blocks until its writer opens it as well
+func openWatched(fileName string, timeout time.Duration) (*os.File, error) {
+	if timeout <= 0 {
+		return os.Open(fileName)
+	}
+	opened := make(chan openedFile, 1)
+	go func() {
+		file, err := os.Open(fileName)
+		opened <- openedFile{file, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case o := <-opened:
+		return o.file, o.err
+	case <-timer.C:
+		// The file is closed if it is opened after all
+		go func() {
+			if o := <-opened; o.file != nil {
+				o.file.Close()
+			}
+		}()
+		return nil, fmt.Errorf("no input received within %v (--record-timeout)", timeout)
+	}
+}
+
+// watchedInput is an input file (or stdin) read through the watchdog
+type watchedInput struct {
+	*watchdogReader
+	src io.Closer
+}
+
+// Close releases the reading goroutine, and closes the input
+// (which also ends a read blocked on a pipe)
+func (w watchedInput) Close() error {
+	w.watchdogReader.Close()
+	return w.src.Close()
+}
+
+// Close releases the reading goroutine, unless it is blocked by the input
+func (w *watchdogReader) Close() {
+	w.stopped.Do(func() { close(w.stop) })
+}
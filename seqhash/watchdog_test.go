@@ -0,0 +1,176 @@
+package seqhash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stallingReader delivers its data, then blocks until released
+type stallingReader struct {
+	data    io.Reader
+	release chan struct{}
+}
+
+func (r *stallingReader) Read(p []byte) (int, error) {
+	if n, err := r.data.Read(p); err != io.EOF {
+		return n, err
+	}
+	<-r.release
+	return 0, io.EOF
+}
+
+func TestRecordTimeout(t *testing.T) {
+	for _, threads := range []int{1, 4} {
+		runTest(t, fmt.Sprintf("Threads %d", threads), func(t *testing.T) {
+			input := &stallingReader{data: strings.NewReader(">seq1\nACTG\n>seq2\nTG"), release: make(chan struct{})}
+			defer close(input.release)
+
+			cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, Threads: threads, RecordTimeout: 50 * time.Millisecond}
+			done := make(chan error, 1)
+			go func() { done <- processSequences(input, &bytes.Buffer{}, cfg) }()
+
+			select {
+			case err := <-done:
+				if err == nil || !strings.Contains(err.Error(), "no new record received within 50ms (--record-timeout)") {
+					t.Errorf("processSequences() error = %v, want a record timeout", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("processSequences() did not time out on stalled input")
+			}
+		})
+	}
+}
+
+func TestRecordTimeoutSlowInput(t *testing.T) {
+	// Input that keeps arriving, more slowly than the whole run takes, is not a stall
+	pr, pw := io.Pipe()
+	go func() {
+		for _, chunk := range []string{">seq1\nAC", "TG\n>seq2\n", "TGCA\n"} {
+			time.Sleep(20 * time.Millisecond)
+			pw.Write([]byte(chunk))
+		}
+		pw.Close()
+	}()
+
+	output := &bytes.Buffer{}
+	cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, RecordTimeout: time.Second}
+	if err := processSequences(pr, output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	if records := strings.Count(output.String(), ">"); records != 2 {
+		t.Errorf("Got %d records, want 2:\n%s", records, output.String())
+	}
+}
+
+func TestRecordTimeoutDribblingInput(t *testing.T) {
+	// Bytes that keep arriving without completing a record are a stall as well
+	for _, threads := range []int{1, 4} {
+		runTest(t, fmt.Sprintf("Threads %d", threads), func(t *testing.T) {
+			pr, pw := io.Pipe()
+			defer pw.Close()
+			go func() {
+				if _, err := pw.Write([]byte(">seq1\nACTG\n>seq2\n")); err != nil {
+					return
+				}
+				for {
+					time.Sleep(5 * time.Millisecond)
+					if _, err := pw.Write([]byte("A")); err != nil {
+						return
+					}
+				}
+			}()
+
+			cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, Threads: threads, RecordTimeout: 100 * time.Millisecond}
+			done := make(chan error, 1)
+			go func() { done <- processSequences(pr, &bytes.Buffer{}, cfg) }()
+
+			select {
+			case err := <-done:
+				if err == nil || !strings.Contains(err.Error(), "no new record received within 100ms (--record-timeout)") {
+					t.Errorf("processSequences() error = %v, want a record timeout", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("processSequences() did not time out on input that never completes a record")
+			}
+		})
+	}
+}
+
+func TestRecordTimeoutPerRecord(t *testing.T) {
+	// The timeout restarts with each record, so a run may take longer than the timeout
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < 6; i++ {
+			time.Sleep(30 * time.Millisecond)
+			fmt.Fprintf(pw, ">seq%d\nACTG\n", i)
+		}
+		pw.Close()
+	}()
+
+	output := &bytes.Buffer{}
+	cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, RecordTimeout: 100 * time.Millisecond}
+	if err := processSequences(pr, output, cfg); err != nil {
+		t.Fatalf("processSequences() error = %v", err)
+	}
+	if records := strings.Count(output.String(), ">"); records != 6 {
+		t.Errorf("Got %d records, want 6", records)
+	}
+}
+
+func TestRecordTimeoutBeforeFirstByte(t *testing.T) {
+	// A pipe that stalls before its first byte blocks the detection of the compression,
+	// so the input is watched from the moment it is opened
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pw.Close()
+	stdin := os.Stdin
+	os.Stdin = pr
+	defer func() { os.Stdin = stdin }()
+
+	cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, InputFileName: "-", RecordTimeout: 50 * time.Millisecond}
+	done := make(chan error, 1)
+	go func() {
+		_, err := processFile(&bytes.Buffer{}, cfg)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "no new record received within 50ms (--record-timeout)") {
+			t.Errorf("processFile() error = %v, want a record timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("processFile() did not time out on input that stalled before its first byte")
+	}
+}
+
+func TestRecordTimeoutCompressedInput(t *testing.T) {
+	// The watchdog reads the raw (compressed) input
+	expected, err := os.ReadFile("../test/test.fasta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range []string{"../test/test.fasta", "../test/test.fasta.gz", "../test/test.fasta.zst"} {
+		runTest(t, fileName, func(t *testing.T) {
+			input, _, err := openInput(fileName, time.Second, nil)
+			if err != nil {
+				t.Fatalf("openInput() error = %v", err)
+			}
+			defer input.Close()
+			data, err := io.ReadAll(input)
+			if err != nil {
+				t.Fatalf("Error reading input: %v", err)
+			}
+			if !bytes.Equal(data, expected) {
+				t.Errorf("Got %d bytes of input, want the %d bytes of test/test.fasta", len(data), len(expected))
+			}
+		})
+	}
+}
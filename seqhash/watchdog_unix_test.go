@@ -0,0 +1,43 @@
+//go:build unix
+
+package seqhash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRecordTimeoutNamedPipe(t *testing.T) {
+	// Opening a FIFO blocks until its writer opens it, which here never happens
+	fifo := filepath.Join(t.TempDir(), "input.fasta")
+	if err := syscall.Mkfifo(fifo, 0o600); err != nil {
+		t.Skipf("Can't create a named pipe: %v", err)
+	}
+	// Afterwards, the open that was given up on is released
+	defer func() {
+		if w, err := os.OpenFile(fifo, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+			w.Close()
+		}
+	}()
+
+	cfg := Config{HashTypes: []string{"sha1"}, NoFileName: true, InputFileName: fifo, RecordTimeout: 50 * time.Millisecond}
+	done := make(chan error, 1)
+	go func() {
+		_, err := processFile(&bytes.Buffer{}, cfg)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "no input received within 50ms (--record-timeout)") {
+			t.Errorf("processFile() error = %v, want a record timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("processFile() did not time out on a named pipe without a writer")
+	}
+}
@@ -0,0 +1,77 @@
+// This file is part of SeqHasher program (by Vladimir Mikryukov)
+// and is licensed under GNU GPL-3.0-or-later.
+// See the LICENSE file in the root of the source tree
+// or <http://www.gnu.org/licenses/gpl-3.0.html>.
+
+package seqhash
+
+import (
+	"fmt"
+
+	"github.com/shenwei356/bio/seq"
+	"github.com/shenwei356/bio/seqio/fastx"
+)
+
+// Window of a sequence hashed as a separate record (--window)
+type seqWindow struct {
+	start, end int      // 0-based, end exclusive, in the output sequence
+	seq        []byte   // Hashed bytes
+	hashes     []string // Digests of the window
+}
+
+// hashWindows hashes the windows of --window bases, starting every --step bases.
+// Only complete windows are hashed, so the last bases of the sequence may not be covered.
+// offset is the position of seq in the output sequence (e.g., after --trim-ns).
+func hashWindows(seq []byte, offset int, cfg Config, fanout *hashFanout) []seqWindow {
+	step := cfg.Step
+	if step <= 0 {
+		step = cfg.Window // Tiling
+	}
+	var windows []seqWindow
+	for start := 0; start+cfg.Window <= len(seq); start += step {
+		hashed := seq[start : start+cfg.Window]
+		if cfg.BothStrands {
+			hashed = bothStrands(hashed)
+		}
+		windows = append(windows, seqWindow{
+			start:  offset + start,
+			end:    offset + start + cfg.Window,
+			seq:    hashed,
+			hashes: computeHashes(hashed, cfg, fanout),
+		})
+	}
+	return windows
+}
+
+// Output record with the bytes it was hashed from
+type hashedUnit struct {
+	record *fastx.Record
+	seq    []byte
+	hashes []string
+	bases  int      // Length of the normalized sequence (or of the window)
+	bands  []uint64 // With --clustered-hash, the band keys of the MinHash sketch (not with --window)
+
+	original *originalRecord // With --validate-roundtrip
+}
+
+// units returns the output records of a prepared record: the record itself,
+// or, with --window, a record for each window with ";win=<start>-<end>" (1-based, inclusive)
+// appended to the ID
+func (p preparedRecord) units(record *fastx.Record, windowed bool) []hashedUnit {
+	if !windowed {
+		return []hashedUnit{{record: record, seq: p.seq, hashes: p.hashes, bases: p.bases, bands: p.bands, original: p.original}}
+	}
+	units := make([]hashedUnit, len(p.windows))
+	tail := record.Name[len(record.ID):]
+	for i, w := range p.windows {
+		// The windows share the bytes of the record, which are not modified afterwards
+		s := seq.Seq{Alphabet: record.Seq.Alphabet, Seq: record.Seq.Seq[w.start:w.end]}
+		if len(record.Seq.Qual) == len(record.Seq.Seq) {
+			s.Qual = record.Seq.Qual[w.start:w.end]
+		}
+		id := fmt.Appendf(nil, "%s;win=%d-%d", record.ID, w.start+1, w.end)
+		r := &fastx.Record{ID: id, Name: append(append([]byte{}, id...), tail...), Seq: &s}
+		units[i] = hashedUnit{record: r, seq: w.seq, hashes: w.hashes, bases: w.end - w.start}
+	}
+	return units
+}
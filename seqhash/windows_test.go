@@ -0,0 +1,72 @@
+package seqhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWindows(t *testing.T) {
+	sha1 := getHashFunc("sha1")
+	tests := []struct {
+		name  string
+		cfg   Config
+		input string
+		want  string
+	}{
+		{
+			name:  "Tiling windows",
+			cfg:   Config{Window: 4, Step: 4},
+			input: ">seq1 sample A\nACGTTGCAAC\n",
+			want: ">" + sha1([]byte("ACGT")) + ";seq1;win=1-4 sample A\nACGT\n" +
+				">" + sha1([]byte("TGCA")) + ";seq1;win=5-8 sample A\nTGCA\n",
+		},
+		{
+			name:  "Sliding windows",
+			cfg:   Config{Window: 4, Step: 3},
+			input: ">seq1\nACGTTGCAAC\n",
+			want: ">" + sha1([]byte("ACGT")) + ";seq1;win=1-4\nACGT\n" +
+				">" + sha1([]byte("TTGC")) + ";seq1;win=4-7\nTTGC\n" +
+				">" + sha1([]byte("CAAC")) + ";seq1;win=7-10\nCAAC\n",
+		},
+		{
+			name:  "Step defaults to the window size",
+			cfg:   Config{Window: 5},
+			input: "@seq1\nacgttGCAAC\n+\nABCDEFGHIJ\n",
+			want: "@" + sha1([]byte("ACGTT")) + ";seq1;win=1-5\nACGTT\n+\nABCDE\n" +
+				"@" + sha1([]byte("GCAAC")) + ";seq1;win=6-10\nGCAAC\n+\nFGHIJ\n",
+		},
+		{
+			// Coordinates refer to the output sequence, which keeps the Ns
+			name:  "Trimmed Ns",
+			cfg:   Config{Window: 3, TrimNs: true},
+			input: ">seq1\nNNACGTTGNN\n",
+			want: ">" + sha1([]byte("ACG")) + ";seq1;win=3-5\nACG\n" +
+				">" + sha1([]byte("TTG")) + ";seq1;win=6-8\nTTG\n",
+		},
+		{
+			name:  "Shorter than the window",
+			cfg:   Config{Window: 8},
+			input: ">short\nACGT\n>long\nACGTACGT\n",
+			want:  ">" + sha1([]byte("ACGTACGT")) + ";long;win=1-8\nACGTACGT\n",
+		},
+	}
+
+	for _, tt := range tests {
+		runTest(t, tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.HashTypes = []string{"sha1"}
+			cfg.NoFileName = true
+			for _, threads := range []int{1, 2} {
+				cfg.Threads = threads
+				output := &bytes.Buffer{}
+				if err := processSequences(strings.NewReader(tt.input), output, cfg); err != nil {
+					t.Fatalf("processSequences() error = %v", err)
+				}
+				if output.String() != tt.want {
+					t.Errorf("threads=%d: got:\n%s\nWant:\n%s", threads, output.String(), tt.want)
+				}
+			}
+		})
+	}
+}